@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateAPIKeyHandler handles POST /api/v1/admin/api-keys
+func (a *App) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.OwnerUserID == 0 {
+		req.OwnerUserID = principal.UserID
+	}
+
+	key, err := a.apiKeyService.CreateAPIKey(r.Context(), principal, req.Name, req.OwnerUserID, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// ListAPIKeysHandler handles GET /api/v1/admin/api-keys
+func (a *App) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	includeRevoked := r.URL.Query().Get("include_revoked") == "true"
+
+	keys, err := a.apiKeyService.ListAPIKeys(r.Context(), includeRevoked)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKeyHandler handles DELETE /api/v1/admin/api-keys/{id}
+func (a *App) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.apiKeyService.RevokeAPIKey(r.Context(), id); err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAPIKeyError maps API key service errors to HTTP responses
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "api key not found", msg == "owner not found":
+		http.Error(w, msg, http.StatusNotFound)
+	case strings.HasPrefix(msg, "calling api key"):
+		http.Error(w, msg, http.StatusForbidden)
+	case msg == "api key name already exists":
+		http.Error(w, msg, http.StatusConflict)
+	case msg == "name is required", msg == "expires_at must be in the future", strings.Contains(msg, "must be at most"),
+		strings.HasPrefix(msg, "invalid scope"), strings.HasPrefix(msg, "owner role"):
+		http.Error(w, msg, http.StatusBadRequest)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestAPIKeysCannotCreateBroaderKeys(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	users := services.NewUserService(database, m, events.NewOutbox(m))
+	admin, err := users.CreateUser(ctx, "keys@example.com", "Keys", "us-east", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.SetRole(ctx, admin.ID, auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{apiKeyService: services.NewAPIKeyService(database, m)}
+
+	session := &auth.Principal{UserID: admin.ID, SessionID: 1, Role: auth.RoleAdmin}
+	limitedKey := &auth.Principal{UserID: admin.ID, Role: auth.RoleAdmin, APIKeyID: 1, Scopes: []auth.Permission{auth.PermAPIKeysManage}}
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		scopes    []string
+		want      int
+	}{
+		{"session, all scopes", session, []string{string(auth.PermAPIKeysManage), string(auth.PermRolesManage)}, http.StatusCreated},
+		{"limited key, broader scope", limitedKey, []string{string(auth.PermAPIKeysManage), string(auth.PermRolesManage)}, http.StatusForbidden},
+		{"limited key, another scope", limitedKey, []string{string(auth.PermInventoryManage)}, http.StatusForbidden},
+		{"limited key, its own scope", limitedKey, []string{string(auth.PermAPIKeysManage)}, http.StatusCreated},
+	}
+	for i, tt := range tests {
+		body, _ := json.Marshal(models.CreateAPIKeyRequest{Name: "key-" + string(rune('a'+i)), Scopes: tt.scopes})
+		r := httptest.NewRequest("POST", "/api/v1/admin/api-keys", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		app.CreateAPIKeyHandler(w, r.WithContext(auth.WithPrincipal(r.Context(), tt.principal)))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d %s, want %d", tt.name, w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+		}
+	}
+
+	// Only the two allowed keys exist
+	keys, err := app.apiKeyService.ListAPIKeys(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("got %d keys, want 2", len(keys))
+	}
+}
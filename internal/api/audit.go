@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ListAuditLogHandler handles GET /api/v1/admin/audit-log
+func (a *App) ListAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var actorUserID int64
+	if u := r.URL.Query().Get("actor_user_id"); u != "" {
+		parsed, err := strconv.ParseInt(u, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid actor_user_id", http.StatusBadRequest)
+			return
+		}
+		actorUserID = parsed
+	}
+
+	entries, err := a.auditService.ListAuditLog(r.Context(), actorUserID, r.URL.Query().Get("action"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// LoginHandler handles POST /api/v1/auth/login
+func (a *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.authService.Login(r.Context(), req.Email, req.Password, r.UserAgent(), a.rateLimiter.ClientIP(r))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// LogoutHandler handles POST /api/v1/auth/logout, ending the caller's session
+func (a *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	if principal.APIKeyID != 0 {
+		http.Error(w, "api keys have no session to end", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.authService.RevokeSession(r.Context(), principal.UserID, principal.SessionID); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordResetHandler handles POST /api/v1/auth/password-reset
+func (a *App) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.authService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// Same response whether or not the email is registered
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPasswordResetHandler handles POST /api/v1/auth/password-reset/confirm
+func (a *App) ConfirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.authService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentUserHandler handles GET /api/v1/users/me
+func (a *App) GetCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := a.userService.GetUser(r.Context(), principal.UserID)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ListSessionsHandler handles GET /api/v1/users/me/sessions
+func (a *App) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := a.authService.ListSessions(r.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSessionsHandler handles DELETE /api/v1/users/me/sessions, ending
+// every session except the caller's (all of them with ?include_current=true)
+func (a *App) RevokeSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	keep := principal.SessionID
+	if r.URL.Query().Get("include_current") == "true" {
+		keep = 0
+	}
+	revoked, err := a.authService.RevokeOtherSessions(r.Context(), principal.UserID, keep)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"revoked": revoked})
+}
+
+// RevokeSessionHandler handles DELETE /api/v1/users/me/sessions/{id}
+func (a *App) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.authService.RevokeSession(r.Context(), principal.UserID, id); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requirePrincipal returns the authenticated caller, answering 401 when the
+// request has no session
+func requirePrincipal(w http.ResponseWriter, r *http.Request) (*auth.Principal, bool) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+	return principal, true
+}
+
+// requireSession returns the caller signed in with a session, answering 403
+// to API keys: they act for their owner but must not manage the owner's
+// sessions
+func requireSession(w http.ResponseWriter, r *http.Request) (*auth.Principal, bool) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return nil, false
+	}
+	if principal.APIKeyID != 0 {
+		http.Error(w, "api keys cannot manage sessions", http.StatusForbidden)
+		return nil, false
+	}
+	return principal, true
+}
+
+// writeAuthError maps auth service errors to HTTP status codes
+func writeAuthError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "invalid email or password":
+		http.Error(w, msg, http.StatusUnauthorized)
+	case msg == "session not found":
+		http.Error(w, msg, http.StatusNotFound)
+	case msg == "invalid or expired token", strings.HasPrefix(msg, "password must be"):
+		http.Error(w, msg, http.StatusBadRequest)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/middleware"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/notify"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+// newAuthTestApp returns an app that logs users in behind the trusted
+// proxy 10.0.0.1, with one user whose password is "correct horse battery staple"
+func newAuthTestApp(t *testing.T) *App {
+	t.Helper()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	users := services.NewUserService(database, m, events.NewOutbox(m))
+	if _, err := users.CreateUser(context.Background(), "login@example.com", "Login", "us-east", "correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := middleware.NewRateLimiter(m, nil, []string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &App{
+		userService: users,
+		authService: services.NewAuthService(database, m, notify.NewLogNotifier(), time.Hour, time.Hour),
+		rateLimiter: limiter,
+	}
+}
+
+// login posts credentials from a client behind the proxy
+func login(app *App, email, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.LoginRequest{Email: email, Password: password})
+	r := httptest.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(string(body)))
+	r.RemoteAddr = "10.0.0.1:443"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	r.Header.Set("User-Agent", "test")
+	w := httptest.NewRecorder()
+	app.LoginHandler(w, r)
+	return w
+}
+
+func TestLoginRecordsClientBehindProxy(t *testing.T) {
+	app := newAuthTestApp(t)
+	w := login(app, "login@example.com", "correct horse battery staple")
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", w.Code, w.Body)
+	}
+	var resp models.LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Session.IPAddress != "198.51.100.7" {
+		t.Errorf("session ip_address = %q, want the client behind the proxy", resp.Session.IPAddress)
+	}
+}
+
+func TestLockedAccountsLookLikeWrongPasswords(t *testing.T) {
+	app := newAuthTestApp(t)
+	unknown := login(app, "nobody@example.com", "correct horse battery staple")
+	if unknown.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown email: %d, want 401", unknown.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		if w := login(app, "login@example.com", "wrong password"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: %d, want 401", i+1, w.Code)
+		}
+	}
+	// The account is locked now; even the right password gets the answer an
+	// unknown email gets
+	for _, password := range []string{"correct horse battery staple", "wrong password"} {
+		w := login(app, "login@example.com", password)
+		if w.Code != unknown.Code || w.Body.String() != unknown.Body.String() || w.Header().Get("Retry-After") != "" {
+			t.Errorf("locked account with %q: %d %q, want %d %q", password, w.Code, w.Body, unknown.Code, unknown.Body)
+		}
+	}
+}
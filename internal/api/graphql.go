@@ -0,0 +1,8 @@
+package api
+
+import "net/http"
+
+// GraphQLHandler handles POST /api/v1/graphql
+func (a *App) GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	a.graphql.ServeHTTP(w, r)
+}
@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
 	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/graphqlapi"
+	"github.com/SigNoz/ecommerce-go-app/internal/health"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
 	"github.com/SigNoz/ecommerce-go-app/internal/middleware"
 	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/openapi"
 	"github.com/SigNoz/ecommerce-go-app/internal/services"
 	"github.com/SigNoz/ecommerce-go-app/pkg/config"
 	"github.com/gorilla/mux"
@@ -16,13 +21,24 @@ import (
 
 // App holds application dependencies
 type App struct {
-	config         *config.Config
-	db             *db.DB
-	metrics        *metrics.AppMetrics
-	productService *services.ProductService
-	cartService    *services.CartService
-	orderService   *services.OrderService
-	userService    *services.UserService
+	config           *config.Config
+	db               *db.DB
+	metrics          *metrics.AppMetrics
+	productService   *services.ProductService
+	cartService      *services.CartService
+	orderService     *services.OrderService
+	userService      *services.UserService
+	webhookService   *services.WebhookService
+	inventoryService *services.InventoryService
+	warehouseService *services.WarehouseService
+	shipmentService  *services.ShipmentService
+	addressService   *services.AddressService
+	authService      *services.AuthService
+	auditService     *services.AuditService
+	apiKeyService    *services.APIKeyService
+	rateLimiter      *middleware.RateLimiter
+	graphql          *graphqlapi.Handler
+	health           *health.Checker
 }
 
 // NewApp creates a new application instance
@@ -34,25 +50,59 @@ func NewApp(
 	cs *services.CartService,
 	os *services.OrderService,
 	us *services.UserService,
+	ws *services.WebhookService,
+	is *services.InventoryService,
+	whs *services.WarehouseService,
+	ss *services.ShipmentService,
+	as *services.AddressService,
+	auths *services.AuthService,
+	audits *services.AuditService,
+	aks *services.APIKeyService,
+	rl *middleware.RateLimiter,
+	hc *health.Checker,
 ) *App {
 	return &App{
-		config:         cfg,
-		db:             database,
-		metrics:        m,
-		productService: ps,
-		cartService:    cs,
-		orderService:   os,
-		userService:    us,
+		config:           cfg,
+		db:               database,
+		metrics:          m,
+		productService:   ps,
+		cartService:      cs,
+		orderService:     os,
+		userService:      us,
+		webhookService:   ws,
+		inventoryService: is,
+		warehouseService: whs,
+		shipmentService:  ss,
+		addressService:   as,
+		authService:      auths,
+		auditService:     audits,
+		apiKeyService:    aks,
+		rateLimiter:      rl,
+		graphql:          graphqlapi.NewHandler(ps, cs, is, os, m, cfg.GraphQLMaxDepth, cfg.GraphQLMaxComplexity),
+		health:           hc,
 	}
 }
 
+// require wraps a handler so only principals with perm can call it
+func (a *App) require(perm auth.Permission, h http.HandlerFunc) http.Handler {
+	return middleware.RequirePermission(perm, a.auditService.Record)(h)
+}
+
 // SetupRoutes configures the HTTP routes
 func (a *App) SetupRoutes(r *mux.Router) {
 	// Middleware
 	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.TraceContextMiddleware)
 	r.Use(middleware.CORSMiddleware)
 	r.Use(middleware.ErrorHandlerMiddleware)
 	r.Use(middleware.MetricsMiddleware(a.metrics))
+	r.Use(middleware.ReadRoutingMiddleware)
+	r.Use(a.rateLimiter.AuthFailureMiddleware)
+	r.Use(middleware.Authenticate(a.authService.Authenticate, a.apiKeyService.Authenticate))
+	r.Use(a.rateLimiter.Middleware)
+	if a.config.ValidateRequests {
+		r.Use(openapi.ValidateRequests(openapi.MustLoad()))
+	}
 
 	// API Routes
 	api := r.PathPrefix("/api/v1").Subrouter()
@@ -61,6 +111,7 @@ func (a *App) SetupRoutes(r *mux.Router) {
 	api.HandleFunc("/products", a.ListProductsHandler).Methods("GET")
 	api.HandleFunc("/products/{id}", a.GetProductHandler).Methods("GET")
 	api.HandleFunc("/products/{id}/inventory", a.GetProductInventoryHandler).Methods("GET")
+	api.HandleFunc("/products/{id}/stock", a.GetProductStockHandler).Methods("GET")
 
 	// Cart
 	api.HandleFunc("/cart", a.GetCartHandler).Methods("GET")
@@ -71,20 +122,79 @@ func (a *App) SetupRoutes(r *mux.Router) {
 	api.HandleFunc("/orders", a.CreateOrderHandler).Methods("POST")
 	api.HandleFunc("/orders", a.ListOrdersHandler).Methods("GET")
 	api.HandleFunc("/orders/{id}", a.GetOrderHandler).Methods("GET")
-	api.HandleFunc("/orders/{id}/status", a.UpdateOrderStatusHandler).Methods("PUT")
-
-	// Users
+	api.Handle("/orders/{id}/status", a.require(auth.PermOrdersUpdateStatus, a.UpdateOrderStatusHandler)).Methods("PUT")
+	api.Handle("/orders/{id}/shipments", a.require(auth.PermShipmentsManage, a.CreateShipmentHandler)).Methods("POST")
+	api.HandleFunc("/orders/{id}/shipments", a.ListShipmentsHandler).Methods("GET")
+
+	// Carrier tracking updates
+	api.HandleFunc("/carriers/{carrier}/updates", a.CarrierUpdateHandler).Methods("POST")
+
+	// Storefront GraphQL queries
+	api.HandleFunc("/graphql", a.GraphQLHandler).Methods("POST")
+
+	// Authentication
+	api.HandleFunc("/auth/login", a.LoginHandler).Methods("POST")
+	api.HandleFunc("/auth/logout", a.LogoutHandler).Methods("POST")
+	api.HandleFunc("/auth/password-reset", a.RequestPasswordResetHandler).Methods("POST")
+	api.HandleFunc("/auth/password-reset/confirm", a.ConfirmPasswordResetHandler).Methods("POST")
+
+	// Users (the /users/me routes go first so "me" is not taken as an ID)
+	api.HandleFunc("/users/me", a.GetCurrentUserHandler).Methods("GET")
+	api.HandleFunc("/users/me/sessions", a.ListSessionsHandler).Methods("GET")
+	api.HandleFunc("/users/me/sessions", a.RevokeSessionsHandler).Methods("DELETE")
+	api.HandleFunc("/users/me/sessions/{id:[0-9]+}", a.RevokeSessionHandler).Methods("DELETE")
 	api.HandleFunc("/users", a.CreateUserHandler).Methods("POST")
 	api.HandleFunc("/users/{id}", a.GetUserHandler).Methods("GET")
-
-	// Health
-	r.HandleFunc("/health", a.HealthHandler).Methods("GET")
-}
-
-// HealthHandler handles health check requests
-func (a *App) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+	api.HandleFunc("/users/{id}", a.UpdateUserHandler).Methods("PATCH")
+	api.HandleFunc("/users/{id}", a.DeleteUserHandler).Methods("DELETE")
+	api.HandleFunc("/users/{id}/addresses", a.ListAddressesHandler).Methods("GET")
+	api.HandleFunc("/users/{id}/addresses", a.CreateAddressHandler).Methods("POST")
+	api.HandleFunc("/users/{id}/addresses/{addressId:[0-9]+}", a.GetAddressHandler).Methods("GET")
+	api.HandleFunc("/users/{id}/addresses/{addressId:[0-9]+}", a.UpdateAddressHandler).Methods("PATCH")
+	api.HandleFunc("/users/{id}/addresses/{addressId:[0-9]+}", a.DeleteAddressHandler).Methods("DELETE")
+
+	// Admin routes; every route checks its own permission
+	admin := api.PathPrefix("/admin").Subrouter()
+
+	// Admin: webhooks
+	admin.Handle("/webhooks", a.require(auth.PermWebhooksManage, a.CreateWebhookHandler)).Methods("POST")
+	admin.Handle("/webhooks", a.require(auth.PermWebhooksManage, a.ListWebhooksHandler)).Methods("GET")
+	admin.Handle("/webhooks/{id:[0-9]+}", a.require(auth.PermWebhooksManage, a.GetWebhookHandler)).Methods("GET")
+	admin.Handle("/webhooks/{id:[0-9]+}", a.require(auth.PermWebhooksManage, a.DeleteWebhookHandler)).Methods("DELETE")
+	admin.Handle("/webhooks/{id:[0-9]+}/deliveries", a.require(auth.PermWebhooksManage, a.ListWebhookDeliveriesHandler)).Methods("GET")
+	admin.Handle("/webhooks/deliveries/{id:[0-9]+}/replay", a.require(auth.PermWebhooksManage, a.ReplayWebhookDeliveryHandler)).Methods("POST")
+
+	// Admin: warehouses
+	admin.Handle("/warehouses", a.require(auth.PermWarehousesManage, a.CreateWarehouseHandler)).Methods("POST")
+	admin.Handle("/warehouses", a.require(auth.PermWarehousesRead, a.ListWarehousesHandler)).Methods("GET")
+	admin.Handle("/warehouses/{id:[0-9]+}", a.require(auth.PermWarehousesRead, a.GetWarehouseHandler)).Methods("GET")
+	admin.Handle("/warehouses/{id:[0-9]+}", a.require(auth.PermWarehousesManage, a.UpdateWarehouseHandler)).Methods("PUT")
+	admin.Handle("/warehouses/{id:[0-9]+}", a.require(auth.PermWarehousesManage, a.DeleteWarehouseHandler)).Methods("DELETE")
+
+	// Admin: inventory
+	admin.Handle("/inventory/low-stock", a.require(auth.PermInventoryRead, a.GetLowStockHandler)).Methods("GET")
+	admin.Handle("/inventory/adjustments", a.require(auth.PermInventoryManage, a.AdjustStockHandler)).Methods("POST")
+	admin.Handle("/inventory/transfers", a.require(auth.PermInventoryManage, a.TransferStockHandler)).Methods("POST")
+	admin.Handle("/inventory/movements", a.require(auth.PermInventoryRead, a.ListInventoryMovementsHandler)).Methods("GET")
+
+	// Admin: users and audit log
+	admin.Handle("/users/{id:[0-9]+}/role", a.require(auth.PermRolesManage, a.UpdateUserRoleHandler)).Methods("PUT")
+	admin.Handle("/audit-log", a.require(auth.PermAuditRead, a.ListAuditLogHandler)).Methods("GET")
+
+	// Admin: API keys
+	admin.Handle("/api-keys", a.require(auth.PermAPIKeysManage, a.CreateAPIKeyHandler)).Methods("POST")
+	admin.Handle("/api-keys", a.require(auth.PermAPIKeysManage, a.ListAPIKeysHandler)).Methods("GET")
+	admin.Handle("/api-keys/{id:[0-9]+}", a.require(auth.PermAPIKeysManage, a.RevokeAPIKeyHandler)).Methods("DELETE")
+
+	// Health probes; /health is kept for existing clients
+	r.HandleFunc("/livez", a.health.LiveHandler).Methods("GET")
+	r.HandleFunc("/readyz", a.health.ReadyHandler).Methods("GET")
+	r.HandleFunc("/healthz", a.health.HealthHandler).Methods("GET")
+	r.HandleFunc("/health", a.health.HealthHandler).Methods("GET")
+
+	// API description
+	r.HandleFunc("/openapi.json", openapi.SpecHandler).Methods("GET")
+	r.HandleFunc("/docs", openapi.DocsHandler).Methods("GET")
 }
 
 // ListProductsHandler handles GET /api/v1/products
@@ -141,9 +251,11 @@ func (a *App) GetProductInventoryHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Stock across all warehouses is served by /products/{id}/stock
 	warehouseID := r.URL.Query().Get("warehouse_id")
 	if warehouseID == "" {
-		warehouseID = "WH-001" // Default warehouse
+		http.Error(w, "warehouse_id is required", http.StatusBadRequest)
+		return
 	}
 
 	inventory, err := a.productService.GetProductInventory(r.Context(), id, warehouseID)
@@ -239,7 +351,7 @@ func (a *App) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Currency == "" {
-		req.Currency = "USD"
+		req.Currency = a.config.BaseCurrency
 	}
 	if req.PaymentMethod == "" {
 		req.PaymentMethod = "credit_card"
@@ -252,12 +364,17 @@ func (a *App) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	order, err := a.orderService.CreateOrder(r.Context(), userID, req.PaymentMethod, req.Currency)
+	order, err := a.orderService.CreateOrder(r.Context(), userID, req.PaymentMethod, req.Currency, req.Region, req.ShippingAddressID)
 	if err != nil {
-		if err.Error() == "cart is empty" {
+		if err.Error() == "cart is empty" || err.Error() == "shipping address not found" ||
+			strings.HasPrefix(err.Error(), "unsupported currency") || strings.HasSuffix(err.Error(), "is not a shipping address") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if strings.HasPrefix(err.Error(), "insufficient stock") {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -313,24 +430,10 @@ func (a *App) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := a.userService.CreateUser(r.Context(), req.ID, req.Email, req.Name)
+	user, err := a.userService.CreateUser(r.Context(), req.Email, req.Name, req.Region, req.Password)
 	if err != nil {
-		if err.Error() == "user already exists" {
-			// Find the existing user to return their ID
-			// For now, we'll just return the error message,
-			// but the traffic script needs the ID.
-			// I'll add a GetUserByEmail method to UserService.
-			existingUser, lookupErr := a.userService.GetUserByEmail(r.Context(), req.Email)
-			if lookupErr == nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(existingUser)
-				return
-			}
-			http.Error(w, err.Error(), http.StatusConflict)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		// The conflict response does not reveal the existing account
+		writeUserError(w, err)
 		return
 	}
 
@@ -347,6 +450,9 @@ func (a *App) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
+	if !a.authorizeUser(w, r, id) {
+		return
+	}
 
 	user, err := a.userService.GetUser(r.Context(), id)
 	if err != nil {
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// GetProductStockHandler handles GET /api/v1/products/{id}/stock
+func (a *App) GetProductStockHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	stock, err := a.inventoryService.GetProductStock(r.Context(), id)
+	if err != nil {
+		if err.Error() == "product not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stock)
+}
+
+// AdjustStockHandler handles POST /api/v1/admin/inventory/adjustments
+func (a *App) AdjustStockHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.StockAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Actor = actorOf(r)
+
+	movement, err := a.inventoryService.AdjustStock(r.Context(), req)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// TransferStockHandler handles POST /api/v1/admin/inventory/transfers
+func (a *App) TransferStockHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.StockTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Actor = actorOf(r)
+
+	transfer, err := a.inventoryService.TransferStock(r.Context(), req)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// ListInventoryMovementsHandler handles GET /api/v1/admin/inventory/movements
+func (a *App) ListInventoryMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var productID int64
+	if p := r.URL.Query().Get("product_id"); p != "" {
+		parsed, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid product ID", http.StatusBadRequest)
+			return
+		}
+		productID = parsed
+	}
+
+	movements, err := a.inventoryService.ListMovements(r.Context(), productID, r.URL.Query().Get("warehouse_id"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+// actorOf names the authenticated user in the inventory ledger
+func actorOf(r *http.Request) string {
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		return fmt.Sprintf("user:%d", principal.UserID)
+	}
+	return ""
+}
+
+// writeInventoryError maps inventory service errors to HTTP status codes
+func writeInventoryError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "product not found", "inventory not found", "warehouse not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "insufficient stock":
+		http.Error(w, err.Error(), http.StatusConflict)
+	case "warehouse_id is required", "from_warehouse_id and to_warehouse_id are required",
+		"cannot transfer to the same warehouse", "invalid adjustment type",
+		"quantity must be positive", "quantity must not be negative":
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetLowStockHandler handles GET /api/v1/admin/inventory/low-stock
+func (a *App) GetLowStockHandler(w http.ResponseWriter, r *http.Request) {
+	velocityDays := services.DefaultVelocityDays
+	coverDays := services.DefaultCoverDays
+
+	if d := r.URL.Query().Get("velocity_days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			http.Error(w, "Invalid velocity_days", http.StatusBadRequest)
+			return
+		}
+		velocityDays = parsed
+	}
+	if d := r.URL.Query().Get("cover_days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			http.Error(w, "Invalid cover_days", http.StatusBadRequest)
+			return
+		}
+		coverDays = parsed
+	}
+
+	items, err := a.inventoryService.GetLowStock(r.Context(), velocityDays, coverDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/openapi"
+	"github.com/SigNoz/ecommerce-go-app/pkg/config"
+	"github.com/gorilla/mux"
+)
+
+// TestOpenAPICoversRoutes fails when a route is added to SetupRoutes without
+// being described in openapi.json, or the document describes a route that
+// no longer exists
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Registering routes does not touch the services
+	router := mux.NewRouter()
+	(&App{config: &config.Config{}}).SetupRoutes(router)
+
+	routed := make(map[string]bool)
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Path prefixes of subrouters
+			return nil
+		}
+		for _, method := range methods {
+			routed[strings.ToLower(method)+" "+openapi.NormalizePath(pathTemplate)] = true
+			if doc.Operation(method, pathTemplate) == nil {
+				t.Errorf("%s %s is not described in openapi.json", method, pathTemplate)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !routed[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s, which is not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// TestOpenAPIRequestValidation checks the validator against a few bodies
+func TestOpenAPIRequestValidation(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operation("POST", "/api/v1/cart/add")
+	if op == nil || op.RequestBody == nil {
+		t.Fatal("POST /api/v1/cart/add has no request body")
+	}
+	schema := op.RequestBody.Content["application/json"].Schema
+
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"product_id": 1, "quantity": 2}`, true},
+		{`{"product_id": 1, "quantity": 2, "note": "ignored"}`, true},
+		{`{"product_id": 1}`, false},
+		{`{"product_id": "1", "quantity": 2}`, false},
+		{`{"product_id": 1, "quantity": 0}`, false},
+		{`{"product_id": 1.5, "quantity": 2}`, false},
+		{`[]`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		err := doc.ValidateBody(schema, []byte(tt.body), op.RequestBody.Required)
+		if (err == nil) != tt.valid {
+			t.Errorf("body %q: valid = %v, want %v (err: %v)", tt.body, err == nil, tt.valid, err)
+		}
+	}
+}
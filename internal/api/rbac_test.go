@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestRequirePermissionAudits(t *testing.T) {
+	database := testutil.NewDB(t)
+	audit := services.NewAuditService(database, testutil.NewMetrics(t))
+	app := &App{auditService: audit}
+	h := app.require(auth.PermInventoryManage, func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      int
+		decision  string
+	}{
+		{"anonymous", nil, http.StatusUnauthorized, auth.DecisionDenied},
+		{"customer", &auth.Principal{UserID: 1, Role: auth.RoleCustomer}, http.StatusForbidden, auth.DecisionDenied},
+		{"support", &auth.Principal{UserID: 2, Role: auth.RoleSupport}, http.StatusForbidden, auth.DecisionDenied},
+		{"warehouse", &auth.Principal{UserID: 3, Role: auth.RoleWarehouse}, http.StatusOK, auth.DecisionAllowed},
+		{"admin key without the scope", &auth.Principal{UserID: 4, Role: auth.RoleAdmin, APIKeyID: 1}, http.StatusForbidden, auth.DecisionDenied},
+		{"admin key with the scope", &auth.Principal{UserID: 4, Role: auth.RoleAdmin, APIKeyID: 1, Scopes: []auth.Permission{auth.PermInventoryManage}}, http.StatusOK, auth.DecisionAllowed},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/v1/admin/inventory/adjustments", nil)
+		ctx := context.WithValue(r.Context(), "request_id", "req-"+tt.name)
+		if tt.principal != nil {
+			ctx = auth.WithPrincipal(ctx, tt.principal)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(ctx))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	// Every decision is in the audit log, newest first
+	entries, err := audit.ListAuditLog(context.Background(), 0, string(auth.PermInventoryManage), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("got %d audit entries, want %d", len(entries), len(tests))
+	}
+	for i, tt := range tests {
+		entry := entries[len(entries)-1-i]
+		if entry.Decision != tt.decision || entry.Target != "POST /api/v1/admin/inventory/adjustments" || entry.RequestID != "req-"+tt.name {
+			t.Errorf("%s: audit entry %+v, want %s", tt.name, entry, tt.decision)
+		}
+		switch {
+		case tt.principal == nil && entry.ActorUserID != nil:
+			t.Errorf("%s: actor %d, want none", tt.name, *entry.ActorUserID)
+		case tt.principal != nil && (entry.ActorUserID == nil || *entry.ActorUserID != tt.principal.UserID || entry.ActorRole != tt.principal.Role):
+			t.Errorf("%s: actor %v/%s, want %d/%s", tt.name, entry.ActorUserID, entry.ActorRole, tt.principal.UserID, tt.principal.Role)
+		}
+	}
+
+	// Filtering by actor
+	entries, err = audit.ListAuditLog(context.Background(), 4, "", 100)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("entries of user 4: %d, %v; want 2", len(entries), err)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// carrierUpdateMaxBody caps the size of carrier tracking updates
+	carrierUpdateMaxBody = 1 << 20
+	// carrierSignatureTolerance is how far a carrier update's timestamp may drift
+	carrierSignatureTolerance = 5 * time.Minute
+)
+
+// CreateShipmentHandler handles POST /api/v1/orders/{id}/shipments
+func (a *App) CreateShipmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := a.shipmentService.CreateShipment(r.Context(), orderID, req)
+	if err != nil {
+		writeShipmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// ListShipmentsHandler handles GET /api/v1/orders/{id}/shipments
+func (a *App) ListShipmentsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	shipments, err := a.shipmentService.ListOrderShipments(r.Context(), orderID)
+	if err != nil {
+		writeShipmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipments)
+}
+
+// CarrierUpdateHandler handles POST /api/v1/carriers/{carrier}/updates.
+// Carriers sign the body like our outgoing webhooks: X-Webhook-Timestamp and
+// X-Webhook-Signature computed with CARRIER_WEBHOOK_SECRET.
+func (a *App) CarrierUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if a.config.CarrierWebhookSecret == "" {
+		http.Error(w, "carrier updates are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, carrierUpdateMaxBody))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	signature := r.Header.Get(services.WebhookHeaderSignature)
+	timestamp := r.Header.Get(services.WebhookHeaderTimestamp)
+	if !services.VerifyWebhookSignature(a.config.CarrierWebhookSecret, signature, timestamp, body, carrierSignatureTolerance) {
+		log.Printf("[SHIPMENT] Rejected carrier update with invalid signature: carrier=%s", mux.Vars(r)["carrier"])
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var update models.CarrierUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := a.shipmentService.ApplyCarrierUpdate(r.Context(), mux.Vars(r)["carrier"], update)
+	if err != nil {
+		writeShipmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// writeShipmentError maps shipment service errors to HTTP status codes
+func writeShipmentError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "order not found" || msg == "shipment not found":
+		http.Error(w, msg, http.StatusNotFound)
+	case msg == "shipment already exists" || msg == "order items already shipped" || msg == "order cannot be shipped":
+		http.Error(w, msg, http.StatusConflict)
+	case msg == "carrier and tracking_number are required" || msg == "warehouse_id is required" ||
+		msg == "no items to ship" || msg == "invalid shipment status" || strings.HasPrefix(msg, "order item "):
+		http.Error(w, msg, http.StatusBadRequest)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"github.com/SigNoz/ecommerce-go-app/pkg/config"
+	"github.com/gorilla/mux"
+)
+
+func TestCarrierUpdateSignature(t *testing.T) {
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	const secret = "carrier-secret"
+	app := &App{
+		config:          &config.Config{CarrierWebhookSecret: secret},
+		shipmentService: services.NewShipmentService(database, m, events.NewOutbox(m)),
+	}
+
+	body := `{"tracking_number": "1Z999", "status": "delivered"}`
+	now := time.Now().Unix()
+	do := func(app *App, body, timestamp, signature string) int {
+		r := httptest.NewRequest("POST", "/api/v1/carriers/ups/updates", strings.NewReader(body))
+		r = mux.SetURLVars(r, map[string]string{"carrier": "ups"})
+		if timestamp != "" {
+			r.Header.Set(services.WebhookHeaderTimestamp, timestamp)
+		}
+		if signature != "" {
+			r.Header.Set(services.WebhookHeaderSignature, signature)
+		}
+		w := httptest.NewRecorder()
+		app.CarrierUpdateHandler(w, r)
+		return w.Code
+	}
+	ts := strconv.FormatInt(now, 10)
+	stale := now - int64((10 * time.Minute).Seconds())
+
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		signature string
+		want      int
+	}{
+		{"unsigned", body, "", "", http.StatusUnauthorized},
+		{"no timestamp", body, "", services.SignWebhookPayload(secret, now, []byte(body)), http.StatusUnauthorized},
+		{"wrong secret", body, ts, services.SignWebhookPayload("guess", now, []byte(body)), http.StatusUnauthorized},
+		{"tampered body", strings.Replace(body, "1Z999", "1Z998", 1), ts, services.SignWebhookPayload(secret, now, []byte(body)), http.StatusUnauthorized},
+		{"other timestamp", body, strconv.FormatInt(now+1, 10), services.SignWebhookPayload(secret, now, []byte(body)), http.StatusUnauthorized},
+		{"stale", body, strconv.FormatInt(stale, 10), services.SignWebhookPayload(secret, stale, []byte(body)), http.StatusUnauthorized},
+		// A valid signature reaches the service, which does not know the parcel
+		{"signed", body, ts, services.SignWebhookPayload(secret, now, []byte(body)), http.StatusNotFound},
+		{"signed invalid status", `{"tracking_number": "1Z999", "status": "lost"}`, ts, services.SignWebhookPayload(secret, now, []byte(`{"tracking_number": "1Z999", "status": "lost"}`)), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := do(app, tt.body, tt.timestamp, tt.signature); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Without a secret every update is refused
+	unconfigured := &App{config: &config.Config{}, shipmentService: app.shipmentService}
+	if got := do(unconfigured, body, ts, services.SignWebhookPayload("", now, []byte(body))); got != http.StatusServiceUnavailable {
+		t.Errorf("no secret configured: status %d, want 503", got)
+	}
+}
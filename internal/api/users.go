@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// UpdateUserHandler handles PATCH /api/v1/users/{id}
+func (a *App) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if !a.authorizeUser(w, r, id) {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := a.userService.UpdateUser(r.Context(), id, req)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUserHandler handles DELETE /api/v1/users/{id}
+func (a *App) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if !a.authorizeUser(w, r, id) {
+		return
+	}
+
+	if err := a.userService.DeleteUser(r.Context(), id); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAddressesHandler handles GET /api/v1/users/{id}/addresses
+func (a *App) ListAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if !a.authorizeUser(w, r, userID) {
+		return
+	}
+
+	addresses, err := a.addressService.ListAddresses(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(addresses)
+}
+
+// CreateAddressHandler handles POST /api/v1/users/{id}/addresses
+func (a *App) CreateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if !a.authorizeUser(w, r, userID) {
+		return
+	}
+
+	var req models.AddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := a.addressService.CreateAddress(r.Context(), userID, req)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(address)
+}
+
+// GetAddressHandler handles GET /api/v1/users/{id}/addresses/{addressId}
+func (a *App) GetAddressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, addressID, ok := addressVars(w, r)
+	if !ok || !a.authorizeUser(w, r, userID) {
+		return
+	}
+
+	address, err := a.addressService.GetAddress(r.Context(), userID, addressID)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(address)
+}
+
+// UpdateAddressHandler handles PATCH /api/v1/users/{id}/addresses/{addressId}
+func (a *App) UpdateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, addressID, ok := addressVars(w, r)
+	if !ok || !a.authorizeUser(w, r, userID) {
+		return
+	}
+
+	var req models.AddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := a.addressService.UpdateAddress(r.Context(), userID, addressID, req)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(address)
+}
+
+// DeleteAddressHandler handles DELETE /api/v1/users/{id}/addresses/{addressId}
+func (a *App) DeleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, addressID, ok := addressVars(w, r)
+	if !ok || !a.authorizeUser(w, r, userID) {
+		return
+	}
+
+	if err := a.addressService.DeleteAddress(r.Context(), userID, addressID); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateUserRoleHandler handles PUT /api/v1/admin/users/{id}/role
+func (a *App) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := a.userService.SetRole(r.Context(), id, req.Role)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// authorizeUser lets a user act on their own account, and staff with
+// users:manage on anyone's. Acting on another account is audited. API keys
+// do not act on their owner's account as its user; they need users:manage
+// among their scopes for every account.
+func (a *App) authorizeUser(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return false
+	}
+	if principal.UserID == userID && principal.APIKeyID == 0 {
+		return true
+	}
+
+	allowed := principal.Can(auth.PermUsersManage)
+	entry := models.AuditEntry{
+		ActorUserID: &principal.UserID,
+		ActorRole:   principal.Role,
+		Action:      string(auth.PermUsersManage),
+		Target:      r.Method + " " + r.URL.Path,
+		Decision:    auth.DecisionDenied,
+	}
+	if allowed {
+		entry.Decision = auth.DecisionAllowed
+	}
+	if requestID, ok := r.Context().Value("request_id").(string); ok {
+		entry.RequestID = requestID
+	}
+	a.auditService.Record(r.Context(), entry)
+
+	if !allowed {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	}
+	return allowed
+}
+
+// addressVars parses the user and address IDs of an address route
+func addressVars(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	addressID, err := strconv.ParseInt(vars["addressId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid address ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return userID, addressID, true
+}
+
+// writeUserError maps user and address service errors to HTTP status codes
+func writeUserError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "user not found", msg == "address not found":
+		http.Error(w, msg, http.StatusNotFound)
+	case msg == "user already exists", msg == "email already in use":
+		http.Error(w, msg, http.StatusConflict)
+	case msg == "invalid email", msg == "name is required", strings.Contains(msg, "must be at most"), strings.Contains(msg, "must be at least"),
+		msg == "recipient_name, line1, city, postal_code and country are required",
+		msg == "country must be a two-letter code",
+		strings.HasPrefix(msg, "invalid address kind"), strings.HasPrefix(msg, "invalid role"):
+		http.Error(w, msg, http.StatusBadRequest)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"github.com/gorilla/mux"
+)
+
+// newUsersTestApp returns an app with the user and audit services on a
+// migrated SQLite database, and the ID of a customer
+func newUsersTestApp(t *testing.T) (*App, int64) {
+	t.Helper()
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	users := services.NewUserService(database, m, events.NewOutbox(m))
+	user, err := users.CreateUser(ctx, "owner@example.com", "Owner", "us-east", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &App{userService: users, auditService: services.NewAuditService(database, m)}, user.ID
+}
+
+func TestUserRoutesAreAuthorized(t *testing.T) {
+	app, userID := newUsersTestApp(t)
+	session := &auth.Principal{UserID: userID, SessionID: 1, Role: auth.RoleCustomer}
+	key := &auth.Principal{UserID: userID, Role: auth.RoleCustomer, APIKeyID: 9}
+	adminKey := &auth.Principal{UserID: userID + 1, Role: auth.RoleAdmin, APIKeyID: 10, Scopes: []auth.Permission{auth.PermUsersManage}}
+
+	do := func(handler http.HandlerFunc, method, path string, vars map[string]string, principal *auth.Principal) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{"name": "Renamed"}`))
+		r = mux.SetURLVars(r, vars)
+		if principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	id := strconv.FormatInt(userID, 10)
+	userPath := "/api/v1/users/" + id
+	otherUser, err := app.userService.CreateUser(context.Background(), "other@example.com", "Other", "us-west", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := strconv.FormatInt(otherUser.ID, 10)
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		method    string
+		path      string
+		vars      map[string]string
+		principal *auth.Principal
+		want      int
+	}{
+		{"anonymous reads an account", app.GetUserHandler, "GET", userPath, map[string]string{"id": id}, nil, http.StatusUnauthorized},
+		{"session reads own account", app.GetUserHandler, "GET", userPath, map[string]string{"id": id}, session, http.StatusOK},
+		{"session reads another account", app.GetUserHandler, "GET", "/api/v1/users/" + other, map[string]string{"id": other}, session, http.StatusForbidden},
+		{"key reads owner's account", app.GetUserHandler, "GET", userPath, map[string]string{"id": id}, key, http.StatusForbidden},
+		{"key scoped to read any account", app.GetUserHandler, "GET", "/api/v1/users/" + other, map[string]string{"id": other}, adminKey, http.StatusOK},
+		{"session updates own account", app.UpdateUserHandler, "PATCH", userPath, map[string]string{"id": id}, session, http.StatusOK},
+		{"key updates owner's account", app.UpdateUserHandler, "PATCH", userPath, map[string]string{"id": id}, key, http.StatusForbidden},
+		{"key deletes owner's account", app.DeleteUserHandler, "DELETE", userPath, map[string]string{"id": id}, key, http.StatusForbidden},
+		{"key lists owner's addresses", app.ListAddressesHandler, "GET", userPath + "/addresses", map[string]string{"id": id}, key, http.StatusForbidden},
+		{"key scoped for users:manage", app.UpdateUserHandler, "PATCH", userPath, map[string]string{"id": id}, adminKey, http.StatusOK},
+		{"key lists sessions", app.ListSessionsHandler, "GET", "/api/v1/users/me/sessions", nil, key, http.StatusForbidden},
+		{"key revokes sessions", app.RevokeSessionsHandler, "DELETE", "/api/v1/users/me/sessions", nil, key, http.StatusForbidden},
+		{"key revokes a session", app.RevokeSessionHandler, "DELETE", "/api/v1/users/me/sessions/1", map[string]string{"id": "1"}, key, http.StatusForbidden},
+		{"key logs out", app.LogoutHandler, "POST", "/api/v1/auth/logout", nil, key, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := do(tt.handler, tt.method, tt.path, tt.vars, tt.principal); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
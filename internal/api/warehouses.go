@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateWarehouseHandler handles POST /api/v1/admin/warehouses
+func (a *App) CreateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.WarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	wh, err := a.warehouseService.CreateWarehouse(r.Context(), req)
+	if err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(wh)
+}
+
+// ListWarehousesHandler handles GET /api/v1/admin/warehouses
+func (a *App) ListWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := a.warehouseService.ListWarehouses(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(warehouses)
+}
+
+// GetWarehouseHandler handles GET /api/v1/admin/warehouses/{id}
+func (a *App) GetWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	wh, err := a.warehouseService.GetWarehouse(r.Context(), id)
+	if err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wh)
+}
+
+// UpdateWarehouseHandler handles PUT /api/v1/admin/warehouses/{id}
+func (a *App) UpdateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.WarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	wh, err := a.warehouseService.UpdateWarehouse(r.Context(), id, req)
+	if err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wh)
+}
+
+// DeleteWarehouseHandler handles DELETE /api/v1/admin/warehouses/{id}
+func (a *App) DeleteWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.warehouseService.DeleteWarehouse(r.Context(), id); err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeWarehouseError maps warehouse service errors to HTTP status codes
+func writeWarehouseError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "warehouse not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "warehouse already exists", "warehouse has stock":
+		http.Error(w, err.Error(), http.StatusConflict)
+	case "code, name and region are required", "warehouse code cannot be changed":
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
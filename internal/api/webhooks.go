@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateWebhookHandler handles POST /api/v1/admin/webhooks
+func (a *App) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := a.webhookService.CreateSubscription(r.Context(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		if err.Error() == "invalid webhook url" || err.Error() == "at least one event type is required" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// ListWebhooksHandler handles GET /api/v1/admin/webhooks
+func (a *App) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := a.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// GetWebhookHandler handles GET /api/v1/admin/webhooks/{id}
+func (a *App) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := a.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		if err.Error() == "webhook subscription not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// DeleteWebhookHandler handles DELETE /api/v1/admin/webhooks/{id}
+func (a *App) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		if err.Error() == "webhook subscription not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveriesHandler handles GET /api/v1/admin/webhooks/{id}/deliveries
+func (a *App) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	deliveries, err := a.webhookService.ListDeliveries(r.Context(), id, r.URL.Query().Get("status"), limit)
+	if err != nil {
+		if err.Error() == "webhook subscription not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// ReplayWebhookDeliveryHandler handles POST /api/v1/admin/webhooks/deliveries/{id}/replay
+func (a *App) ReplayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.webhookService.ReplayDelivery(r.Context(), id); err != nil {
+		if err.Error() == "webhook delivery not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "scheduled"})
+}
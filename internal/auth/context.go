@@ -0,0 +1,28 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller of a request: a user's session, or
+// an API key acting for its owner
+type Principal struct {
+	UserID    int64
+	SessionID int64 // Zero for API keys
+	Role      string
+
+	APIKeyID   int64        // Zero for sessions
+	APIKeyName string       // Empty for sessions
+	Scopes     []Permission // Permissions an API key is limited to
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the authenticated principal, or nil for anonymous requests
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,112 @@
+package auth
+
+// Roles a user can have. Every user starts as a customer.
+const (
+	RoleCustomer  = "customer"
+	RoleSupport   = "support"
+	RoleWarehouse = "warehouse"
+	RoleAdmin     = "admin"
+)
+
+// Permission names an operation that needs a role other than customer
+type Permission string
+
+// Permissions checked by the API
+const (
+	PermOrdersUpdateStatus Permission = "orders:update_status"
+	PermShipmentsManage    Permission = "shipments:manage"
+	PermInventoryRead      Permission = "inventory:read"
+	PermInventoryManage    Permission = "inventory:manage"
+	PermWarehousesRead     Permission = "warehouses:read"
+	PermWarehousesManage   Permission = "warehouses:manage"
+	PermWebhooksManage     Permission = "webhooks:manage"
+	PermUsersManage        Permission = "users:manage"
+	PermRolesManage        Permission = "roles:manage"
+	PermAuditRead          Permission = "audit:read"
+	PermAPIKeysManage      Permission = "api_keys:manage"
+)
+
+// allPermissions lists every permission, for validating API key scopes
+var allPermissions = []Permission{
+	PermOrdersUpdateStatus,
+	PermShipmentsManage,
+	PermInventoryRead,
+	PermInventoryManage,
+	PermWarehousesRead,
+	PermWarehousesManage,
+	PermWebhooksManage,
+	PermUsersManage,
+	PermRolesManage,
+	PermAuditRead,
+	PermAPIKeysManage,
+}
+
+// Access decisions recorded in the audit log
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+)
+
+// rolePermissions lists what each role may do. Admins may do everything.
+var rolePermissions = map[string][]Permission{
+	RoleCustomer: nil,
+	RoleSupport: {
+		PermOrdersUpdateStatus,
+		PermUsersManage,
+		PermInventoryRead,
+		PermWarehousesRead,
+	},
+	RoleWarehouse: {
+		PermOrdersUpdateStatus,
+		PermShipmentsManage,
+		PermInventoryRead,
+		PermInventoryManage,
+		PermWarehousesRead,
+	},
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok || role == RoleAdmin
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role string, perm Permission) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPermission reports whether perm is a known permission
+func ValidPermission(perm Permission) bool {
+	for _, p := range allPermissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal's role grants perm. API keys also need
+// perm among their scopes.
+func (p *Principal) Can(perm Permission) bool {
+	if p == nil || !HasPermission(p.Role, perm) {
+		return false
+	}
+	if p.APIKeyID == 0 {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if scope == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "testing"
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		perm      Permission
+		want      bool
+	}{
+		{"anonymous", nil, PermInventoryRead, false},
+		{"customer", &Principal{Role: RoleCustomer}, PermInventoryRead, false},
+		{"unknown role", &Principal{Role: "superuser"}, PermInventoryRead, false},
+		{"support reads inventory", &Principal{Role: RoleSupport}, PermInventoryRead, true},
+		{"support manages users", &Principal{Role: RoleSupport}, PermUsersManage, true},
+		{"support changes stock", &Principal{Role: RoleSupport}, PermInventoryManage, false},
+		{"warehouse changes stock", &Principal{Role: RoleWarehouse}, PermInventoryManage, true},
+		{"warehouse ships", &Principal{Role: RoleWarehouse}, PermShipmentsManage, true},
+		{"warehouse manages users", &Principal{Role: RoleWarehouse}, PermUsersManage, false},
+		{"warehouse reads the audit log", &Principal{Role: RoleWarehouse}, PermAuditRead, false},
+		{"admin", &Principal{Role: RoleAdmin}, PermRolesManage, true},
+		{"admin key without the scope", &Principal{Role: RoleAdmin, APIKeyID: 1, Scopes: []Permission{PermInventoryRead}}, PermRolesManage, false},
+		{"admin key with the scope", &Principal{Role: RoleAdmin, APIKeyID: 1, Scopes: []Permission{PermRolesManage}}, PermRolesManage, true},
+		{"key scoped beyond its owner's role", &Principal{Role: RoleSupport, APIKeyID: 1, Scopes: []Permission{PermInventoryManage}}, PermInventoryManage, false},
+		{"key without scopes", &Principal{Role: RoleWarehouse, APIKeyID: 1}, PermInventoryRead, false},
+	}
+	for _, tt := range tests {
+		if got := tt.principal.Can(tt.perm); got != tt.want {
+			t.Errorf("%s: Can(%s) = %v, want %v", tt.name, tt.perm, got, tt.want)
+		}
+	}
+
+	for role, want := range map[string]bool{RoleCustomer: true, RoleSupport: true, RoleWarehouse: true, RoleAdmin: true, "root": false, "": false} {
+		if got := ValidRole(role); got != want {
+			t.Errorf("ValidRole(%q) = %v, want %v", role, got, want)
+		}
+	}
+	for _, perm := range allPermissions {
+		if !HasPermission(RoleAdmin, perm) || !ValidPermission(perm) {
+			t.Errorf("%s is not granted to admins or not valid", perm)
+		}
+	}
+	if ValidPermission("products:delete") {
+		t.Error("an unknown permission is valid")
+	}
+}
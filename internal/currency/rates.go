@@ -0,0 +1,151 @@
+package currency
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RateProvider supplies exchange rates relative to a base currency
+type RateProvider interface {
+	// BaseCurrency returns the currency product prices are stored in
+	BaseCurrency() string
+	// Rate returns how many units of the given currency equal one unit of the base currency
+	Rate(ctx context.Context, currency string) (float64, error)
+}
+
+// StaticRateProvider serves a fixed set of exchange rates
+type StaticRateProvider struct {
+	mu    sync.RWMutex
+	base  string
+	rates map[string]float64
+}
+
+// NewStaticRateProvider creates a rate provider from an in-memory rate table.
+// The base currency always has a rate of 1.
+func NewStaticRateProvider(base string, rates map[string]float64) *StaticRateProvider {
+	p := &StaticRateProvider{}
+	p.set(base, rates)
+	return p
+}
+
+// BaseCurrency returns the base currency
+func (p *StaticRateProvider) BaseCurrency() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.base
+}
+
+// Rate returns the exchange rate from the base currency to the given currency
+func (p *StaticRateProvider) Rate(ctx context.Context, currency string) (float64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rate, ok := p.rates[Normalize(currency)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency: %s", currency)
+	}
+	return rate, nil
+}
+
+func (p *StaticRateProvider) set(base string, rates map[string]float64) {
+	normalized := make(map[string]float64, len(rates)+1)
+	for code, rate := range rates {
+		normalized[Normalize(code)] = rate
+	}
+	base = Normalize(base)
+	normalized[base] = 1
+
+	p.mu.Lock()
+	p.base = base
+	p.rates = normalized
+	p.mu.Unlock()
+}
+
+// rateFile is the on-disk format read by FileRateProvider
+type rateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// defaultRates is a fixed table of rates from USD, used when no rates file
+// is configured so that checkout in common currencies works out of the box
+//
+//go:embed default_rates.json
+var defaultRates []byte
+
+// NewDefaultRateProvider serves the built-in rates, rebased to base. The
+// rates are a snapshot and never change; set a rates file for real ones.
+func NewDefaultRateProvider(base string) (*StaticRateProvider, error) {
+	var f rateFile
+	if err := json.Unmarshal(defaultRates, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse default exchange rates: %w", err)
+	}
+	f.Rates[Normalize(f.Base)] = 1
+
+	// Rebase: units of code per base = units of code per USD / units of base per USD
+	baseRate, ok := f.Rates[Normalize(base)]
+	if !ok {
+		return nil, fmt.Errorf("no default exchange rates for base currency %s; set a rates file", base)
+	}
+	rates := make(map[string]float64, len(f.Rates))
+	for code, rate := range f.Rates {
+		rates[code] = rate / baseRate
+	}
+	return NewStaticRateProvider(base, rates), nil
+}
+
+// FileRateProvider serves exchange rates loaded from a JSON file
+type FileRateProvider struct {
+	*StaticRateProvider
+	path string
+}
+
+// NewFileRateProvider loads exchange rates from a JSON file of the form
+// {"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.79}}.
+// The file's base currency, if set, must match base.
+func NewFileRateProvider(path, base string) (*FileRateProvider, error) {
+	p := &FileRateProvider{
+		StaticRateProvider: NewStaticRateProvider(base, nil),
+		path:               path,
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload re-reads the rate file, keeping the current rates if it is invalid
+func (p *FileRateProvider) Reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+
+	var f rateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("failed to parse exchange rates file: %w", err)
+	}
+
+	for code, rate := range f.Rates {
+		if rate <= 0 {
+			return fmt.Errorf("invalid exchange rate for %s: %v", code, rate)
+		}
+	}
+
+	base := p.BaseCurrency()
+	if f.Base != "" && Normalize(f.Base) != base {
+		return fmt.Errorf("exchange rates file base currency %s does not match %s", f.Base, base)
+	}
+	p.set(base, f.Rates)
+	return nil
+}
+
+// Normalize returns the canonical (upper-case, trimmed) form of a currency code
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
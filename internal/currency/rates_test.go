@@ -0,0 +1,101 @@
+package currency
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	p := NewStaticRateProvider(" usd", map[string]float64{"eur": 0.9})
+
+	if got := p.BaseCurrency(); got != "USD" {
+		t.Errorf("BaseCurrency() = %q, want USD", got)
+	}
+	for code, want := range map[string]float64{"USD": 1, "EUR": 0.9, " Eur ": 0.9} {
+		got, err := p.Rate(context.Background(), code)
+		if err != nil || got != want {
+			t.Errorf("Rate(%q) = %v, %v; want %v", code, got, err, want)
+		}
+	}
+	if _, err := p.Rate(context.Background(), "GBP"); err == nil {
+		t.Error("Rate(GBP) succeeded without a rate")
+	}
+}
+
+func TestDefaultRateProvider(t *testing.T) {
+	usd, err := NewDefaultRateProvider("USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"USD", "EUR", "GBP", "JPY"} {
+		if _, err := usd.Rate(context.Background(), code); err != nil {
+			t.Errorf("Rate(%s): %v", code, err)
+		}
+	}
+
+	// Rebased to EUR, EUR is 1 and USD is the inverse of USD->EUR
+	eur, err := NewDefaultRateProvider("eur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usdToEUR, _ := usd.Rate(context.Background(), "EUR")
+	for code, want := range map[string]float64{"EUR": 1, "USD": 1 / usdToEUR} {
+		got, err := eur.Rate(context.Background(), code)
+		if err != nil || math.Abs(got-want) > 1e-9 {
+			t.Errorf("EUR-based Rate(%s) = %v, %v; want %v", code, got, err, want)
+		}
+	}
+
+	if _, err := NewDefaultRateProvider("XXX"); err == nil {
+		t.Error("NewDefaultRateProvider(XXX) succeeded")
+	}
+}
+
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"base": "usd", "rates": {"EUR": 0.92}}`)
+	p, err := NewFileRateProvider(path, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate, err := p.Rate(context.Background(), "EUR"); err != nil || rate != 0.92 {
+		t.Errorf("Rate(EUR) = %v, %v; want 0.92", rate, err)
+	}
+
+	// An invalid file keeps the current rates
+	for _, content := range []string{
+		`{"rates": {"EUR": 0}}`,
+		`{"base": "GBP", "rates": {"EUR": 1.1}}`,
+		`not json`,
+	} {
+		write(content)
+		if err := p.Reload(); err == nil {
+			t.Errorf("Reload(%s) succeeded", content)
+		}
+	}
+	if rate, _ := p.Rate(context.Background(), "EUR"); rate != 0.92 {
+		t.Errorf("Rate(EUR) after failed reloads = %v, want 0.92", rate)
+	}
+
+	write(`{"rates": {"EUR": 0.95, "GBP": 0.8}}`)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if rate, _ := p.Rate(context.Background(), "GBP"); rate != 0.8 {
+		t.Errorf("Rate(GBP) after reload = %v, want 0.8", rate)
+	}
+
+	if _, err := NewFileRateProvider(filepath.Join(t.TempDir(), "missing.json"), "USD"); err == nil {
+		t.Error("NewFileRateProvider succeeded without a file")
+	}
+}
@@ -5,56 +5,106 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/XSAM/otelsql"
-	_ "github.com/go-sql-driver/mysql"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
-// DB wraps the database connection with metrics
+// DB wraps the database connection with metrics. Reads made through
+// QueryContext and QueryRowContext may be routed to replicas; see
+// WithRouting.
 type DB struct {
 	*sql.DB
+	dialect          dialect
 	meter            metric.Meter
-	connectionActive metric.Int64Gauge
-	connectionIdle   metric.Int64Gauge
+	connectionActive metric.Int64ObservableGauge
+	connectionIdle   metric.Int64ObservableGauge
+	retries          metric.Int64Counter
 	serviceName      string
+
+	// queryTimeout bounds ExecContext when the caller's context has no deadline
+	queryTimeout time.Duration
+	maxRetries   int
+
+	// primary is the db.instance name of the primary
+	primary string
+	// replicas take reads in turn; next is the round-robin position
+	replicas []*replica
+	next     atomic.Uint64
+
+	// schemaTables are the tables created by the migrations
+	schemaTables []string
+	// lastWaitCount is the pool's wait count at Run's last pool check, and
+	// poolErr what that check found
+	lastWaitCount atomic.Int64
+	poolErr       atomic.Pointer[error]
 }
 
-// NewDB creates a new database connection with OpenTelemetry instrumentation
-func NewDB(dsn string, meter metric.Meter, serviceName string) (*DB, error) {
-	// Register otelsql wrapper for MySQL driver
-	driverName, err := otelsql.Register("mysql",
-		otelsql.WithAttributes(
-			attribute.String("db.system", "mysql"),
-		),
-	)
+// Options configures the backend, connection pool, startup and retries
+type Options struct {
+	// Driver is the backend: mysql (the default), postgres or sqlite
+	Driver string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout is how long NewDB keeps retrying the first connection
+	ConnectTimeout time.Duration
+	// QueryTimeout bounds each statement: on MySQL SELECTs through
+	// max_execution_time and lock waits through innodb_lock_wait_timeout, on
+	// PostgreSQL through statement_timeout and lock_timeout, on SQLite lock
+	// waits through busy_timeout, and ExecContext through its context.
+	// Zero disables it.
+	QueryTimeout time.Duration
+	// MaxRetries is how many times Retry and reads retry a transient error
+	MaxRetries int
+
+	// ReplicaDSNs are read replicas of the primary, each with its own pool
+	// of the same size. SQLite has none.
+	ReplicaDSNs []string
+}
+
+// Connection retry backoff at startup
+const (
+	connectBackoffMin = 500 * time.Millisecond
+	connectBackoffMax = 10 * time.Second
+)
+
+// NewDB creates a new database connection with OpenTelemetry instrumentation.
+// dsn is in the form of the driver: a MySQL DSN, a postgres:// URL or the
+// path of a SQLite file. It retries the first connection with backoff for
+// opts.ConnectTimeout, so the app can start before the database is up, and
+// gives up early when ctx is cancelled. Replicas are not waited for: one
+// that does not answer starts out ejected until Run finds it healthy.
+func NewDB(ctx context.Context, dsn string, opts Options, meter metric.Meter, serviceName string) (*DB, error) {
+	d, err := newDialect(opts.Driver)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register otelsql: %w", err)
+		return nil, err
+	}
+	if len(opts.ReplicaDSNs) > 0 && d.name() == "sqlite" {
+		return nil, fmt.Errorf("read replicas are not supported with sqlite")
 	}
 
-	// Open database connection
-	db, err := sql.Open(driverName, dsn)
+	db, primary, err := openPool(d, dsn, opts, serviceName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
 	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if err := connect(ctx, db, opts.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	// Create metrics for connection pool
-	connectionActive, err := meter.Int64Gauge(
+	connectionActive, err := meter.Int64ObservableGauge(
 		"db.client.connections.active",
 		metric.WithDescription("Number of active database connections"),
 		metric.WithUnit("1"),
@@ -63,7 +113,7 @@ func NewDB(dsn string, meter metric.Meter, serviceName string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create connection active gauge: %w", err)
 	}
 
-	connectionIdle, err := meter.Int64Gauge(
+	connectionIdle, err := meter.Int64ObservableGauge(
 		"db.client.connections.idle",
 		metric.WithDescription("Number of idle database connections"),
 		metric.WithUnit("1"),
@@ -72,58 +122,224 @@ func NewDB(dsn string, meter metric.Meter, serviceName string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create connection idle gauge: %w", err)
 	}
 
+	retries, err := meter.Int64Counter(
+		"db.client.retries",
+		metric.WithDescription("Number of retries of transient database errors"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retries counter: %w", err)
+	}
+
 	dbWrapper := &DB{
 		DB:               db,
+		dialect:          d,
 		meter:            meter,
 		connectionActive: connectionActive,
 		connectionIdle:   connectionIdle,
+		retries:          retries,
 		serviceName:      serviceName,
+		queryTimeout:     opts.QueryTimeout,
+		maxRetries:       opts.MaxRetries,
+		primary:          primary,
+	}
+
+	for _, replicaDSN := range opts.ReplicaDSNs {
+		r, err := openReplica(ctx, d, replicaDSN, opts, serviceName)
+		if err != nil {
+			dbWrapper.Close()
+			return nil, err
+		}
+		dbWrapper.replicas = append(dbWrapper.replicas, r)
+	}
+
+	if _, err := meter.RegisterCallback(dbWrapper.observePool, connectionActive, connectionIdle); err != nil {
+		return nil, fmt.Errorf("failed to register connection pool gauge callback: %w", err)
+	}
+
+	return dbWrapper, nil
+}
+
+// openPool opens a connection pool to one server, returning it with the
+// server's host:port, which tags its spans and metrics as db.instance
+func openPool(d dialect, dsn string, opts Options, serviceName string) (*sql.DB, string, error) {
+	dsn, instance, err := d.prepareDSN(dsn, opts.QueryTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Register otelsql wrapper for the driver
+	driverName, err := otelsql.Register(d.driverName(),
+		otelsql.WithAttributes(
+			attribute.String("db.system", d.system()),
+			attribute.String("db.instance", instance),
+		),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to register otelsql: %w", err)
+	}
+
+	// Open database connection
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open database %s: %w", instance, err)
 	}
 
+	// Configure connection pool
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
 	// Register otelsql's built-in stats reporting
 	if err := otelsql.RegisterDBStatsMetrics(db, otelsql.WithAttributes(
-		attribute.String("db.system", "mysql"),
+		attribute.String("db.system", d.system()),
+		attribute.String("db.instance", instance),
 		attribute.String("service.name", serviceName),
 	)); err != nil {
 		log.Printf("Warning: failed to register otelsql stats metrics: %v", err)
 	}
 
-	return dbWrapper, nil
+	return db, instance, nil
 }
 
-// Close closes the database connection
+// connect pings the database until it answers, backing off between
+// attempts, for up to timeout. A zero timeout tries once.
+func connect(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := connectBackoffMin
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		err := db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to ping database after %d attempt(s): %w", attempt, err)
+		default:
+		}
+		log.Printf("[DB] Database not ready (attempt %d): %v; retrying in %s", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to ping database after %d attempt(s): %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, connectBackoffMax)
+	}
+}
+
+// observePool reports the connections in use and idle in each pool
+func (db *DB) observePool(ctx context.Context, o metric.Observer) error {
+	db.observeStats(o, db.primary, db.Stats())
+	for _, r := range db.replicas {
+		db.observeStats(o, r.name, r.db.Stats())
+	}
+	return nil
+}
+
+func (db *DB) observeStats(o metric.Observer, instance string, stats sql.DBStats) {
+	attrs := metric.WithAttributes(
+		attribute.String("db.system", db.dialect.system()),
+		attribute.String("db.instance", instance),
+		attribute.String("service.name", db.serviceName),
+	)
+	o.ObserveInt64(db.connectionActive, int64(stats.InUse), attrs)
+	o.ObserveInt64(db.connectionIdle, int64(stats.Idle), attrs)
+}
+
+// System returns the db.system name of the backend, such as mysql or
+// postgresql
+func (db *DB) System() string {
+	return db.dialect.system()
+}
+
+// Close closes the database connections
 func (db *DB) Close() error {
+	for _, r := range db.replicas {
+		r.db.Close()
+	}
 	return db.DB.Close()
 }
 
-// InitSchema initializes the database schema
-// It splits the SQL into individual statements and executes them one by one
-func (db *DB) InitSchema(ctx context.Context, schemaSQL string) error {
-	// Split SQL into individual statements
-	// Remove comments and empty lines, then split by semicolon
-	statements := splitSQLStatements(schemaSQL)
+// checkPool records whether the connection pool is saturated: every
+// connection is in use and callers have had to wait for one since the last
+// check. Only Run calls it, so that probes do not reset the wait count.
+func (db *DB) checkPool() {
+	stats := db.Stats()
+	waits := stats.WaitCount - db.lastWaitCount.Swap(stats.WaitCount)
+	var err error
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections && waits > 0 {
+		err = fmt.Errorf("connection pool saturated: %d/%d connections in use, %d waits since last check",
+			stats.InUse, stats.MaxOpenConnections, waits)
+	}
+	db.poolErr.Store(&err)
+}
 
-	for i, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
-		}
+// CheckPool fails when Run last found the connection pool saturated
+func (db *DB) CheckPool(ctx context.Context) error {
+	if err := db.poolErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
 
-		// Skip comments
-		if strings.HasPrefix(stmt, "--") {
-			continue
-		}
+// CheckSchema fails when a table created by the migrations is missing,
+// e.g. because Migrate failed against an older database. It passes when
+// Migrate was not called.
+func (db *DB) CheckSchema(ctx context.Context) error {
+	if len(db.schemaTables) == 0 {
+		return nil
+	}
 
-		_, err := db.ExecContext(ctx, stmt)
-		if err != nil {
-			return fmt.Errorf("failed to execute statement %d: %w\nStatement: %s", i+1, err, stmt)
+	rows, err := db.QueryContext(ctx, db.dialect.tablesQuery())
+	if err != nil {
+		return fmt.Errorf("failed to check schema: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return fmt.Errorf("failed to check schema: %w", err)
 		}
+		found[strings.ToLower(table)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check schema: %w", err)
 	}
 
-	log.Println("Database schema initialized successfully")
+	var missing []string
+	for _, table := range db.schemaTables {
+		if !found[table] {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("schema is not up to date: missing tables %s", strings.Join(missing, ", "))
+	}
 	return nil
 }
 
+// createTablePattern matches the table name of a CREATE TABLE statement
+var createTablePattern = regexp.MustCompile(`(?i)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`?" + `(\w+)`)
+
+// createdTables returns the tables the statements create, in lower case
+func createdTables(statements []string) []string {
+	var tables []string
+	for _, stmt := range statements {
+		if m := createTablePattern.FindStringSubmatch(strings.TrimSpace(stmt)); m != nil {
+			tables = append(tables, strings.ToLower(m[1]))
+		}
+	}
+	return tables
+}
+
 // splitSQLStatements splits a SQL string into individual statements
 func splitSQLStatements(sql string) []string {
 	// Remove comments (lines starting with --)
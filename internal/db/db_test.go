@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCheckPool(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+	database.SetMaxOpenConns(1)
+
+	// Hold the only connection while a query waits for it
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := database.DB.ExecContext(ctx, "SELECT 1")
+		done <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); database.Stats().WaitCount == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the query never waited for a connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Probes report what the periodic check found, however often they run
+	if err := database.CheckPool(ctx); err != nil {
+		t.Errorf("before the first check: %v", err)
+	}
+	database.checkPool()
+	for i := 0; i < 3; i++ {
+		if err := database.CheckPool(ctx); err == nil {
+			t.Errorf("probe %d passed on a saturated pool", i+1)
+		}
+	}
+
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// No one has waited since
+	database.checkPool()
+	if err := database.CheckPool(ctx); err != nil {
+		t.Errorf("after the pool drained: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	_ "modernc.org/sqlite"             // registers the "sqlite" driver
+)
+
+// Drivers are the supported values of Options.Driver
+var Drivers = []string{"mysql", "postgres", "sqlite"}
+
+// dialect is the SQL flavour of a database backend. Services write MySQL
+// flavoured SQL with ? placeholders, and the dialect rewrites what its
+// backend does not understand.
+type dialect interface {
+	// name is the Options.Driver value and the migrations directory
+	name() string
+	// system is the db.system attribute of spans and metrics
+	system() string
+	// driverName is the database/sql driver
+	driverName() string
+	// prepareDSN applies the query timeout to dsn and returns it with the
+	// db.instance name of the server
+	prepareDSN(dsn string, queryTimeout time.Duration) (string, string, error)
+	// rewrite translates a query for the backend
+	rewrite(query string) string
+	// returningID reports whether inserted ids have to be read with
+	// RETURNING id because the driver has no LastInsertId
+	returningID() bool
+	// tablesQuery lists the tables in the database
+	tablesQuery() string
+	// transactionalDDL reports whether a migration can run in a transaction
+	// as one multi-statement Exec
+	transactionalDDL() bool
+}
+
+func newDialect(driver string) (dialect, error) {
+	switch driver {
+	case "", "mysql":
+		return mysqlDialect{}, nil
+	case "postgres":
+		return postgresDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q (want one of %s)", driver, strings.Join(Drivers, ", "))
+}
+
+// mysqlDialect is MySQL 8, which the services' SQL is written for
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string       { return "mysql" }
+func (mysqlDialect) system() string     { return "mysql" }
+func (mysqlDialect) driverName() string { return "mysql" }
+
+func (mysqlDialect) prepareDSN(dsn string, queryTimeout time.Duration) (string, string, error) {
+	dsn, err := withQueryTimeout(dsn, queryTimeout)
+	if err != nil {
+		return "", "", err
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	return dsn, cfg.Addr, nil
+}
+
+func (mysqlDialect) rewrite(query string) string { return query }
+func (mysqlDialect) returningID() bool           { return false }
+func (mysqlDialect) transactionalDDL() bool      { return false }
+
+func (mysqlDialect) tablesQuery() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()"
+}
+
+// withQueryTimeout sets the session variables that make MySQL enforce the
+// query timeout on the server: max_execution_time for SELECTs and
+// innodb_lock_wait_timeout, in whole seconds, for lock waits
+func withQueryTimeout(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return dsn, nil
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	cfg.Params["max_execution_time"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	cfg.Params["innodb_lock_wait_timeout"] = strconv.FormatInt(int64(max(time.Second, timeout.Round(time.Second))/time.Second), 10)
+	return cfg.FormatDSN(), nil
+}
+
+// postgresDialect is PostgreSQL through pgx
+type postgresDialect struct{}
+
+func (postgresDialect) name() string       { return "postgres" }
+func (postgresDialect) system() string     { return "postgresql" }
+func (postgresDialect) driverName() string { return "pgx" }
+
+// prepareDSN takes a postgres:// URL. The query timeout becomes the
+// statement_timeout and lock_timeout of each connection.
+func (postgresDialect) prepareDSN(dsn string, queryTimeout time.Duration) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	if queryTimeout > 0 {
+		params := u.Query()
+		params.Set("statement_timeout", strconv.FormatInt(queryTimeout.Milliseconds(), 10))
+		params.Set("lock_timeout", strconv.FormatInt(queryTimeout.Milliseconds(), 10))
+		u.RawQuery = params.Encode()
+	}
+	return u.String(), u.Host, nil
+}
+
+func (postgresDialect) returningID() bool      { return true }
+func (postgresDialect) transactionalDDL() bool { return true }
+
+func (postgresDialect) tablesQuery() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()"
+}
+
+var (
+	// onDuplicateIgnorePattern matches the no-op ON DUPLICATE KEY UPDATE
+	// id = id that services use to skip rows that already exist
+	onDuplicateIgnorePattern = regexp.MustCompile(`(?i)\bON\s+DUPLICATE\s+KEY\s+UPDATE\s+\w+\s*=\s*\w+\s*$`)
+	jsonObjectPattern        = regexp.MustCompile(`(?i)\bJSON_OBJECT\s*\(`)
+	// jsonPathPattern matches ->>'$.key'
+	jsonPathPattern = regexp.MustCompile(`->>\s*'\$\.(\w+)'`)
+)
+
+func (postgresDialect) rewrite(query string) string {
+	query = onDuplicateIgnorePattern.ReplaceAllString(query, "ON CONFLICT DO NOTHING")
+	query = jsonObjectPattern.ReplaceAllString(query, "jsonb_build_object(")
+	query = jsonPathPattern.ReplaceAllString(query, "->>'$1'")
+	return numberPlaceholders(query)
+}
+
+// numberPlaceholders replaces the ? placeholders outside quotes with $1, $2, ...
+func numberPlaceholders(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// sqliteDialect is SQLite through modernc.org/sqlite, a single file with
+// no server. Writes take the database lock, so transactions start with
+// BEGIN IMMEDIATE and row locks are left out.
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string       { return "sqlite" }
+func (sqliteDialect) system() string     { return "sqlite" }
+func (sqliteDialect) driverName() string { return "sqlite" }
+
+// prepareDSN takes the path of the database file. Lock waits are bounded
+// by the query timeout through busy_timeout.
+func (sqliteDialect) prepareDSN(dsn string, queryTimeout time.Duration) (string, string, error) {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" || path == ":memory:" {
+		return "", "", fmt.Errorf("sqlite needs a database file, got %q", dsn)
+	}
+	busyTimeout := queryTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = time.Minute
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Set("_txlock", "immediate")
+	params.Set("_time_format", "sqlite")
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + params.Encode(), path, nil
+}
+
+// lockingClausePattern matches FOR UPDATE and FOR SHARE with their OF,
+// NOWAIT and SKIP LOCKED options, and LOCK IN SHARE MODE
+var lockingClausePattern = regexp.MustCompile(`(?i)\s+(FOR\s+(UPDATE|SHARE)(\s+OF\s+\w+(\s*,\s*\w+)*)?(\s+NOWAIT|\s+SKIP\s+LOCKED)?|LOCK\s+IN\s+SHARE\s+MODE)\b`)
+
+func (sqliteDialect) rewrite(query string) string {
+	query = onDuplicateIgnorePattern.ReplaceAllString(query, "ON CONFLICT DO NOTHING")
+	return lockingClausePattern.ReplaceAllString(query, "")
+}
+
+func (sqliteDialect) returningID() bool      { return false }
+func (sqliteDialect) transactionalDDL() bool { return true }
+
+func (sqliteDialect) tablesQuery() string {
+	return "SELECT name FROM sqlite_master WHERE type = 'table'"
+}
@@ -0,0 +1,76 @@
+package db
+
+import "testing"
+
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM users WHERE id = ?", "SELECT * FROM users WHERE id = $1"},
+		{"UPDATE users SET name = ?, region = ? WHERE id = ?", "UPDATE users SET name = $1, region = $2 WHERE id = $3"},
+		{"SELECT * FROM products WHERE name = 'why?' AND id = ?", "SELECT * FROM products WHERE name = 'why?' AND id = $1"},
+		{`SELECT "what?" FROM t WHERE a = ? AND b = '?'`, `SELECT "what?" FROM t WHERE a = $1 AND b = '?'`},
+		{"SELECT * FROM t WHERE a = 'it''s?' AND b = ?", "SELECT * FROM t WHERE a = 'it''s?' AND b = $1"},
+		{"INSERT INTO t (a, b) VALUES (?,?)", "INSERT INTO t (a, b) VALUES ($1,$2)"},
+	}
+	for _, tt := range tests {
+		if got := numberPlaceholders(tt.in); got != tt.want {
+			t.Errorf("numberPlaceholders(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresRewrite(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{
+			"INSERT INTO warehouses (code, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = id",
+			"INSERT INTO warehouses (code, name) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		},
+		{
+			"INSERT INTO inventory (product_id, quantity) VALUES (?, ?) ON DUPLICATE KEY UPDATE quantity = VALUES(quantity)",
+			"INSERT INTO inventory (product_id, quantity) VALUES ($1, $2) ON DUPLICATE KEY UPDATE quantity = VALUES(quantity)",
+		},
+		{
+			"SELECT JSON_OBJECT('id', id) FROM orders WHERE shipping_address->>'$.country' = ?",
+			"SELECT jsonb_build_object('id', id) FROM orders WHERE shipping_address->>'country' = $1",
+		},
+		{
+			"SELECT id FROM outbox WHERE published_at IS NULL FOR UPDATE OF outbox SKIP LOCKED",
+			"SELECT id FROM outbox WHERE published_at IS NULL FOR UPDATE OF outbox SKIP LOCKED",
+		},
+	}
+	for _, tt := range tests {
+		if got := (postgresDialect{}).rewrite(tt.in); got != tt.want {
+			t.Errorf("rewrite(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteRewrite(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SELECT quantity FROM inventory WHERE product_id = ? FOR UPDATE", "SELECT quantity FROM inventory WHERE product_id = ?"},
+		{"SELECT id FROM outbox WHERE published_at IS NULL LIMIT ? FOR UPDATE SKIP LOCKED", "SELECT id FROM outbox WHERE published_at IS NULL LIMIT ?"},
+		{"SELECT i.id FROM inventory i JOIN products p ON p.id = i.product_id FOR UPDATE OF i, p NOWAIT", "SELECT i.id FROM inventory i JOIN products p ON p.id = i.product_id"},
+		{"SELECT * FROM users WHERE id = ? FOR SHARE", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT * FROM users WHERE id = ? LOCK IN SHARE MODE", "SELECT * FROM users WHERE id = ?"},
+		{"INSERT INTO warehouses (code) VALUES (?) ON DUPLICATE KEY UPDATE id = id", "INSERT INTO warehouses (code) VALUES (?) ON CONFLICT DO NOTHING"},
+		{"SELECT 'for update' FROM t WHERE a = ?", "SELECT 'for update' FROM t WHERE a = ?"},
+	}
+	for _, tt := range tests {
+		if got := (sqliteDialect{}).rewrite(tt.in); got != tt.want {
+			t.Errorf("rewrite(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMySQLRewrite(t *testing.T) {
+	query := "SELECT id FROM outbox WHERE aggregate_id = ? FOR UPDATE SKIP LOCKED"
+	if got := (mysqlDialect{}).rewrite(query); got != query {
+		t.Errorf("rewrite(%q) = %q, want it unchanged", query, got)
+	}
+}
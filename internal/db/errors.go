@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// MySQL server error numbers
+const (
+	errDupFieldName    = 1060 // ER_DUP_FIELDNAME
+	errDupKeyName      = 1061 // ER_DUP_KEYNAME
+	errDupEntry        = 1062 // ER_DUP_ENTRY
+	errLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	errDeadlock        = 1213 // ER_LOCK_DEADLOCK
+	errFKDupName       = 1826 // ER_FK_DUP_NAME
+)
+
+// PostgreSQL SQLSTATE codes
+const (
+	pgUniqueViolation      = "23505" // unique_violation
+	pgDeadlockDetected     = "40P01" // deadlock_detected
+	pgLockNotAvailable     = "55P03" // lock_not_available, raised by lock_timeout
+	pgSerializationFailure = "40001" // serialization_failure
+)
+
+// IsDuplicateEntry reports whether err is a unique key violation
+func IsDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDupEntry
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// isAlreadyApplied reports whether err is MySQL refusing to add a column,
+// index or foreign key that already exists. MySQL has no ADD COLUMN IF NOT
+// EXISTS, so migrations rely on this to be safe to run again.
+func isAlreadyApplied(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case errDupFieldName, errDupKeyName, errFKDupName:
+			return true
+		}
+	}
+	return false
+}
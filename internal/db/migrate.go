@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// migrations holds the schema of each backend in migrations/<driver>, as
+// numbered files applied in name order. Each backend gets the same
+// changes, written in its own SQL.
+//
+//go:embed migrations
+var migrations embed.FS
+
+const createMigrationsTable = "CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(255) PRIMARY KEY, applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)"
+
+// Migrate applies the migrations of the backend that have not been applied
+// yet, recording each in schema_migrations. On PostgreSQL and SQLite a
+// migration runs in one transaction; MySQL commits DDL as it goes, so
+// there its statements run one by one and must be safe to run again; adding
+// a column, index or foreign key that exists counts as done.
+func (db *DB) Migrate(ctx context.Context) error {
+	files, err := fs.Glob(migrations, path.Join("migrations", db.dialect.name(), "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(files)
+
+	if _, err := db.DB.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	for _, file := range files {
+		data, err := migrations.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		statements := splitSQLStatements(string(data))
+		tables = append(tables, createdTables(statements)...)
+		// Set as we go so that CheckSchema reports what a failed migration left out
+		db.schemaTables = tables
+
+		version := strings.TrimSuffix(path.Base(file), ".sql")
+		if applied[version] {
+			continue
+		}
+		if err := db.applyMigration(ctx, version, string(data), statements); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+		log.Printf("[DB] Applied migration %s", version)
+	}
+	return nil
+}
+
+func (db *DB) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := db.DB.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func (db *DB) applyMigration(ctx context.Context, version, script string, statements []string) error {
+	record := db.dialect.rewrite("INSERT INTO schema_migrations (version) VALUES (?)")
+
+	if !db.dialect.transactionalDDL() {
+		for i, stmt := range statements {
+			if _, err := db.DB.ExecContext(ctx, stmt); err != nil && !isAlreadyApplied(err) {
+				return fmt.Errorf("statement %d: %w\nStatement: %s", i+1, err, stmt)
+			}
+		}
+		// Another instance may have applied it at the same time
+		if _, err := db.DB.ExecContext(ctx, record, version); err != nil && !IsDuplicateEntry(err) {
+			return err
+		}
+		return nil
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if len(statements) > 0 {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// TestMigrateAndCRUD migrates each backend and runs the kinds of statements
+// services write against it. MySQL and PostgreSQL need a server, given by
+// TEST_MYSQL_DSN (a MySQL DSN with parseTime=true) and TEST_POSTGRES_DSN (a
+// postgres:// URL); they are skipped without one.
+func TestMigrateAndCRUD(t *testing.T) {
+	backends := []struct {
+		driver string
+		dsn    string
+	}{
+		{"sqlite", filepath.Join(t.TempDir(), "test.db")},
+		{"mysql", os.Getenv("TEST_MYSQL_DSN")},
+		{"postgres", os.Getenv("TEST_POSTGRES_DSN")},
+	}
+	for _, b := range backends {
+		t.Run(b.driver, func(t *testing.T) {
+			if b.dsn == "" {
+				t.Skipf("set TEST_%s_DSN to test %s", strings.ToUpper(b.driver), b.driver)
+			}
+			ctx := context.Background()
+			database, err := NewDB(ctx, b.dsn, Options{
+				Driver:         b.driver,
+				MaxRetries:     3,
+				ConnectTimeout: 10 * time.Second,
+			}, noop.NewMeterProvider().Meter("test"), "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer database.Close()
+
+			// Migrating again finds nothing to do
+			for i := 0; i < 2; i++ {
+				if err := database.Migrate(ctx); err != nil {
+					t.Fatalf("migration %d: %v", i+1, err)
+				}
+			}
+			if err := database.CheckSchema(ctx); err != nil {
+				t.Fatal(err)
+			}
+			testCRUD(t, ctx, database)
+		})
+	}
+}
+
+func testCRUD(t *testing.T, ctx context.Context, database *DB) {
+	sku := "SMOKE-" + time.Now().Format("150405.000000000")
+	defer database.ExecContext(ctx, "DELETE FROM products WHERE sku = ?", sku)
+
+	res, err := database.ExecContext(ctx,
+		"INSERT INTO products (name, description, price, category, sku) VALUES (?, ?, ?, ?, ?)",
+		"Smoke test", "what's this?", 9.99, "test", sku)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil || id == 0 {
+		t.Fatalf("LastInsertId = %d, %v", id, err)
+	}
+
+	// A duplicate is skipped
+	if _, err := database.ExecContext(ctx,
+		"INSERT INTO products (name, price, sku) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE id = id",
+		"Duplicate", 1.00, sku); err != nil {
+		t.Fatalf("insert ignoring duplicates: %v", err)
+	}
+	// Without ON DUPLICATE KEY it is reported as one
+	if _, err := database.ExecContext(ctx,
+		"INSERT INTO products (name, price, sku) VALUES (?, ?, ?)", "Duplicate", 1.00, sku); !IsDuplicateEntry(err) {
+		t.Errorf("duplicate insert: %v, want a duplicate entry", err)
+	}
+
+	// Row locks and updates inside a transaction
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	var name, description string
+	if err := tx.QueryRowContext(ctx,
+		"SELECT name, description FROM products WHERE id = ? AND description = 'what''s this?' FOR UPDATE", id).Scan(&name, &description); err != nil {
+		t.Fatalf("select for update: %v", err)
+	}
+	if name != "Smoke test" {
+		t.Errorf("name = %q, want the first insert's", name)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET price = ?, reorder_point = ? WHERE id = ?", 19.99, 3, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var price float64
+	var reorderPoint int
+	if err := database.QueryRowContext(ctx, "SELECT price, reorder_point FROM products WHERE sku = ?", sku).Scan(&price, &reorderPoint); err != nil {
+		t.Fatal(err)
+	}
+	if price != 19.99 || reorderPoint != 3 {
+		t.Errorf("price=%v reorder_point=%d after update, want 19.99 and 3", price, reorderPoint)
+	}
+
+	if _, err := database.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	err = database.QueryRowContext(ctx, "SELECT name FROM products WHERE id = ?", id).Scan(&name)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("select after delete: %v, want no rows", err)
+	}
+}
+
+// TestUpgradeBaselineStatements checks that the MySQL upgrade splits into
+// one statement per change, and that only "already exists" errors are skipped
+func TestUpgradeBaselineStatements(t *testing.T) {
+	data, err := migrations.ReadFile("migrations/mysql/0002_upgrade_baseline.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := splitSQLStatements(string(data))
+	if len(statements) != 18 {
+		t.Fatalf("got %d statements, want 18", len(statements))
+	}
+	for _, stmt := range statements {
+		if strings.Contains(stmt, "--") {
+			t.Errorf("statement kept a comment: %q", stmt)
+		}
+	}
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: errDupFieldName}, true},
+		{&mysql.MySQLError{Number: errDupKeyName}, true},
+		{&mysql.MySQLError{Number: errFKDupName}, true},
+		{&mysql.MySQLError{Number: errDupEntry}, false},
+		{&mysql.MySQLError{Number: 1146}, false}, // ER_NO_SUCH_TABLE
+		{errors.New("Duplicate column name"), false},
+	}
+	for _, tt := range tests {
+		if got := isAlreadyApplied(tt.err); got != tt.want {
+			t.Errorf("isAlreadyApplied(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+-- Upgrades a database created from the original schema.sql, whose tables
+-- 0001_init leaves as they are. On a database created by 0001_init every
+-- change below is already there; statements that fail because their column
+-- or constraint exists are skipped.
+
+-- Products: reorder points for low-stock alerts
+ALTER TABLE products ADD COLUMN reorder_point INT NOT NULL DEFAULT 10 AFTER sku;
+
+-- Users: regions, roles, passwords and soft deletion
+ALTER TABLE users ADD COLUMN region VARCHAR(50) NOT NULL DEFAULT '' AFTER name;
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer' AFTER region;
+ALTER TABLE users ADD COLUMN password_hash VARCHAR(255) NULL AFTER role;
+ALTER TABLE users ADD COLUMN failed_logins INT NOT NULL DEFAULT 0 AFTER password_hash;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP NULL AFTER failed_logins;
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL AFTER updated_at;
+
+-- Orders: base-currency amounts and the shipping address. Orders placed
+-- before currency conversion were charged in their own currency, which
+-- becomes their base amount.
+ALTER TABLE orders ADD COLUMN base_amount DECIMAL(10, 2) NULL AFTER currency;
+ALTER TABLE orders ADD COLUMN base_currency VARCHAR(10) NOT NULL DEFAULT 'USD' AFTER base_amount;
+ALTER TABLE orders ADD COLUMN exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1 AFTER base_currency;
+ALTER TABLE orders ADD COLUMN shipping_address JSON NULL AFTER exchange_rate;
+UPDATE orders SET base_amount = total_amount, base_currency = COALESCE(currency, 'USD') WHERE base_amount IS NULL;
+ALTER TABLE orders MODIFY COLUMN base_amount DECIMAL(10, 2) NOT NULL;
+
+-- Order items: the warehouse each line ships from
+ALTER TABLE order_items ADD COLUMN warehouse_id VARCHAR(100) AFTER price;
+
+-- Inventory: low-stock marks, and warehouse codes that must name a warehouse
+ALTER TABLE inventory ADD COLUMN low_stock_since TIMESTAMP NULL AFTER quantity;
+INSERT IGNORE INTO warehouses (code, name, region) SELECT DISTINCT warehouse_id, warehouse_id, '' FROM inventory;
+ALTER TABLE inventory ADD CONSTRAINT inventory_ibfk_2 FOREIGN KEY (warehouse_id) REFERENCES warehouses(code);
@@ -0,0 +1,351 @@
+-- E-Commerce Database Schema (PostgreSQL)
+-- Applied by the app at startup; keep the mysql and sqlite migrations in step
+
+-- Keeps updated_at current, like MySQL's ON UPDATE CURRENT_TIMESTAMP
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Products table (prices are in the base currency, BASE_CURRENCY)
+CREATE TABLE IF NOT EXISTS products (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price NUMERIC(10, 2) NOT NULL,
+    category VARCHAR(100),
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    reorder_point INT NOT NULL DEFAULT 10,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_products_category ON products (category);
+CREATE TRIGGER products_updated_at BEFORE UPDATE ON products FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Users table (deleted users are anonymized and keep deleted_at so their orders remain)
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    region VARCHAR(50) NOT NULL DEFAULT '',
+    role VARCHAR(20) NOT NULL DEFAULT 'customer',
+    password_hash VARCHAR(255) NULL,
+    failed_logins INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ NULL
+);
+CREATE TRIGGER users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Sessions table (only a SHA-256 hash of the bearer token is stored)
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- Audit log of access decisions on protected operations (no foreign key so
+-- entries outlive what they refer to)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    actor_user_id BIGINT NULL,
+    actor_role VARCHAR(20) NOT NULL DEFAULT '',
+    action VARCHAR(100) NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    decision VARCHAR(10) NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+
+-- Password reset tokens table (single use; only a SHA-256 hash is stored)
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+-- API keys of machine clients, acting for their owner within their scopes
+-- (only a SHA-256 hash of the key is stored; key_prefix identifies it in listings)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    owner_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key_prefix CHAR(12) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner_user_id ON api_keys (owner_user_id);
+
+-- Addresses table (kind is shipping or billing; one default per user and kind)
+CREATE TABLE IF NOT EXISTS addresses (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'shipping',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    recipient_name VARCHAR(255) NOT NULL,
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL,
+    state VARCHAR(100) NOT NULL DEFAULT '',
+    postal_code VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL,
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_user_kind ON addresses (user_id, kind);
+CREATE TRIGGER addresses_updated_at BEFORE UPDATE ON addresses FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Carts table
+CREATE TABLE IF NOT EXISTS carts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_carts_user_id ON carts (user_id);
+CREATE TRIGGER carts_updated_at BEFORE UPDATE ON carts FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Cart items table
+CREATE TABLE IF NOT EXISTS cart_items (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    cart_id BIGINT NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_cart_items_cart_id ON cart_items (cart_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_product_id ON cart_items (product_id);
+CREATE TRIGGER cart_items_updated_at BEFORE UPDATE ON cart_items FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Orders table
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    payment_method VARCHAR(50),
+    total_amount NUMERIC(10, 2) NOT NULL,
+    currency VARCHAR(10) DEFAULT 'USD',
+    base_amount NUMERIC(10, 2) NOT NULL,
+    base_currency VARCHAR(10) NOT NULL DEFAULT 'USD',
+    exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1,
+    shipping_address JSONB NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders (created_at);
+CREATE TRIGGER orders_updated_at BEFORE UPDATE ON orders FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Order items table
+CREATE TABLE IF NOT EXISTS order_items (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INT NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    warehouse_id VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items (product_id);
+
+-- Shipments table (status is in_transit or delivered)
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    warehouse_id VARCHAR(100) NOT NULL,
+    carrier VARCHAR(100) NOT NULL,
+    tracking_number VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'in_transit',
+    shipped_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (carrier, tracking_number)
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments (order_id);
+CREATE TRIGGER shipments_updated_at BEFORE UPDATE ON shipments FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Shipment items table (the order lines packed in each shipment)
+CREATE TABLE IF NOT EXISTS shipment_items (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL UNIQUE REFERENCES order_items(id) ON DELETE CASCADE
+);
+
+-- Warehouses table (code is the warehouse_id used by inventory; lower priority ships first)
+CREATE TABLE IF NOT EXISTS warehouses (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    code VARCHAR(100) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    region VARCHAR(50) NOT NULL,
+    priority INT NOT NULL DEFAULT 100,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_warehouses_region ON warehouses (region);
+CREATE TRIGGER warehouses_updated_at BEFORE UPDATE ON warehouses FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Inventory table (low_stock_since is set while quantity is at or below the product's reorder point)
+CREATE TABLE IF NOT EXISTS inventory (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id VARCHAR(100) NOT NULL REFERENCES warehouses(code),
+    quantity INT NOT NULL DEFAULT 0,
+    low_stock_since TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, warehouse_id)
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_warehouse_id ON inventory (warehouse_id);
+CREATE TRIGGER inventory_updated_at BEFORE UPDATE ON inventory FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Inventory movements table (append-only ledger of every stock change)
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id VARCHAR(100) NOT NULL,
+    movement_type VARCHAR(50) NOT NULL,
+    quantity_change INT NOT NULL,
+    quantity_after INT NOT NULL,
+    reason VARCHAR(255),
+    actor VARCHAR(255) NOT NULL,
+    reference VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_product_warehouse ON inventory_movements (product_id, warehouse_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_reference ON inventory_movements (reference);
+
+-- Outbox table (domain events written in the same transaction as the state change)
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    trace_context JSONB NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (published_at, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON outbox (aggregate_type, aggregate_id);
+
+-- Webhook subscriptions table
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    event_types JSONB NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TRIGGER webhook_subscriptions_updated_at BEFORE UPDATE ON webhook_subscriptions FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Webhook deliveries table (delivery log; status is pending, retrying, succeeded or dead)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT NULL,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE TRIGGER webhook_deliveries_updated_at BEFORE UPDATE ON webhook_deliveries FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Insert diverse sample data with multiple categories
+INSERT INTO products (name, description, price, category, sku) VALUES
+-- Electronics (7 products)
+('Laptop', 'High-performance laptop', 999.99, 'Electronics', 'LAP-001'),
+('Mouse', 'Wireless mouse', 29.99, 'Electronics', 'MOU-001'),
+('Keyboard', 'Mechanical keyboard', 79.99, 'Electronics', 'KEY-001'),
+('Monitor', '27-inch 4K monitor', 399.99, 'Electronics', 'MON-001'),
+('Headphones', 'Noise-cancelling headphones', 199.99, 'Electronics', 'HEA-001'),
+('Tablet', '10-inch tablet', 299.99, 'Electronics', 'TAB-001'),
+('Smartphone', 'Latest smartphone', 699.99, 'Electronics', 'PHN-001'),
+-- Clothing (5 products)
+('T-Shirt', 'Cotton t-shirt', 19.99, 'Clothing', 'TSH-001'),
+('Jeans', 'Classic blue jeans', 49.99, 'Clothing', 'JEA-001'),
+('Sneakers', 'Running sneakers', 79.99, 'Clothing', 'SNK-001'),
+('Jacket', 'Winter jacket', 89.99, 'Clothing', 'JCK-001'),
+('Hat', 'Baseball cap', 14.99, 'Clothing', 'HAT-001'),
+-- Books (3 products)
+('Programming Book', 'Learn Go programming', 39.99, 'Books', 'BOK-001'),
+('Novel', 'Bestselling novel', 12.99, 'Books', 'BOK-002'),
+('Cookbook', 'Italian recipes', 24.99, 'Books', 'BOK-003'),
+-- Home & Garden (3 products)
+('Coffee Maker', 'Drip coffee maker', 59.99, 'Home & Garden', 'HOM-001'),
+('Lamp', 'Desk lamp', 34.99, 'Home & Garden', 'HOM-002'),
+('Plant Pot', 'Ceramic plant pot', 19.99, 'Home & Garden', 'HOM-003'),
+-- Sports (4 products)
+('Basketball', 'Official size basketball', 24.99, 'Sports', 'SPT-001'),
+('Yoga Mat', 'Premium yoga mat', 29.99, 'Sports', 'SPT-002'),
+('Dumbbells', '10lb dumbbells set', 49.99, 'Sports', 'SPT-003'),
+('Tennis Racket', 'Professional tennis racket', 89.99, 'Sports', 'SPT-004')
+ON CONFLICT DO NOTHING;
+
+-- Insert warehouses
+INSERT INTO warehouses (code, name, region, priority) VALUES
+('WH-001', 'East Coast Fulfilment Center', 'us-east', 10),
+('WH-002', 'West Coast Fulfilment Center', 'us-west', 20),
+('WH-003', 'Central Europe Fulfilment Center', 'eu-central', 30)
+ON CONFLICT DO NOTHING;
+
+-- Insert inventory for all products across multiple warehouses
+INSERT INTO inventory (product_id, warehouse_id, quantity) VALUES
+-- WH-001 (22 products)
+(1, 'WH-001', 50), (2, 'WH-001', 200), (3, 'WH-001', 100), (4, 'WH-001', 30), (5, 'WH-001', 75),
+(6, 'WH-001', 40), (7, 'WH-001', 60), (8, 'WH-001', 150), (9, 'WH-001', 80), (10, 'WH-001', 90),
+(11, 'WH-001', 45), (12, 'WH-001', 25), (13, 'WH-001', 200), (14, 'WH-001', 180), (15, 'WH-001', 120),
+(16, 'WH-001', 70), (17, 'WH-001', 55), (18, 'WH-001', 35), (19, 'WH-001', 100), (20, 'WH-001', 65),
+(21, 'WH-001', 85), (22, 'WH-001', 95),
+-- WH-002 (22 products)
+(1, 'WH-002', 30), (2, 'WH-002', 150), (3, 'WH-002', 60), (4, 'WH-002', 20), (5, 'WH-002', 50),
+(6, 'WH-002', 25), (7, 'WH-002', 40), (8, 'WH-002', 100), (9, 'WH-002', 50), (10, 'WH-002', 60),
+(11, 'WH-002', 30), (12, 'WH-002', 15), (13, 'WH-002', 150), (14, 'WH-002', 120), (15, 'WH-002', 80),
+(16, 'WH-002', 45), (17, 'WH-002', 35), (18, 'WH-002', 25), (19, 'WH-002', 70), (20, 'WH-002', 40),
+(21, 'WH-002', 55), (22, 'WH-002', 65),
+-- WH-003 (22 products)
+(1, 'WH-003', 20), (2, 'WH-003', 100), (3, 'WH-003', 40), (4, 'WH-003', 15), (5, 'WH-003', 35),
+(6, 'WH-003', 20), (7, 'WH-003', 30), (8, 'WH-003', 80), (9, 'WH-003', 40), (10, 'WH-003', 50),
+(11, 'WH-003', 25), (12, 'WH-003', 10), (13, 'WH-003', 100), (14, 'WH-003', 90), (15, 'WH-003', 60),
+(16, 'WH-003', 30), (17, 'WH-003', 25), (18, 'WH-003', 20), (19, 'WH-003', 50), (20, 'WH-003', 30),
+(21, 'WH-003', 40), (22, 'WH-003', 45)
+ON CONFLICT DO NOTHING;
+
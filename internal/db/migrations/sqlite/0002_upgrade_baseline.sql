@@ -0,0 +1,3 @@
+-- Upgrades a database created from the original schema.sql. That schema
+-- only ever existed for MySQL, so there is nothing to do here; the file
+-- keeps the migration numbers of the backends in step.
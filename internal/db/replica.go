@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often Run checks the pool and pings the replicas, and how long a
+// ping may take
+const (
+	checkInterval       = 5 * time.Second
+	replicaCheckTimeout = 2 * time.Second
+)
+
+// replica is a read replica. It is ejected, and takes no reads, from a
+// lost connection until Run finds it answering pings again.
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+func openReplica(ctx context.Context, d dialect, dsn string, opts Options, serviceName string) (*replica, error) {
+	db, name, err := openPool(d, dsn, opts, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	r := &replica{name: name, db: db}
+
+	pingCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		log.Printf("[DB] Replica %s not ready, ejected: %v", name, err)
+		return r, nil
+	}
+	r.healthy.Store(true)
+	return r, nil
+}
+
+// eject stops routing reads to the replica until Run readmits it
+func (r *replica) eject(err error) {
+	if r.healthy.Swap(false) {
+		log.Printf("[DB] Replica %s ejected: %v", r.name, err)
+	}
+}
+
+// routing is the read routing state of one request
+type routing struct {
+	mu      sync.Mutex
+	replica *replica
+	wrote   bool
+}
+
+type contextKey int
+
+const routingKey contextKey = 0
+
+// WithRouting returns a context for one request whose reads go to a
+// replica. The request sticks to the replica it first reads from, so its
+// reads do not go back in time, and once it writes, through ExecContext,
+// BeginTx or a locking read, its reads go to the primary so that it sees
+// its own writes. Reads on a context without routing go to the primary.
+func WithRouting(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routingKey, &routing{})
+}
+
+// UsePrimary returns a context that leaves the request's routing out:
+// its reads go to the primary and its writes do not make the request's
+// other reads stick to the primary. It is for bookkeeping that must see
+// writes made by earlier requests, such as looking up a session that was
+// just created.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routingKey, (*routing)(nil))
+}
+
+func routingFrom(ctx context.Context) *routing {
+	r, _ := ctx.Value(routingKey).(*routing)
+	return r
+}
+
+// markWrite sends the rest of the request's reads to the primary
+func markWrite(ctx context.Context) {
+	if r := routingFrom(ctx); r != nil {
+		r.mu.Lock()
+		r.wrote = true
+		r.mu.Unlock()
+	}
+}
+
+// lockingReadPattern matches SELECT ... FOR UPDATE and FOR SHARE
+var lockingReadPattern = regexp.MustCompile(`(?i)\bFOR\s+(UPDATE|SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+
+// reader returns the pool for a SELECT on ctx and the replica it belongs
+// to, nil for the primary
+func (db *DB) reader(ctx context.Context, query string) (*sql.DB, *replica) {
+	if len(db.replicas) == 0 {
+		return db.DB, nil
+	}
+	if lockingReadPattern.MatchString(query) {
+		markWrite(ctx)
+		return db.DB, nil
+	}
+	state := routingFrom(ctx)
+	if state == nil {
+		return db.DB, nil
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.wrote {
+		return db.DB, nil
+	}
+	if state.replica == nil || !state.replica.healthy.Load() {
+		state.replica = db.nextReplica()
+	}
+	if state.replica == nil {
+		return db.DB, nil
+	}
+	return state.replica.db, state.replica
+}
+
+// nextReplica returns the next healthy replica in turn, or nil when every
+// replica is ejected
+func (db *DB) nextReplica() *replica {
+	start := db.next.Add(1)
+	for i := range uint64(len(db.replicas)) {
+		r := db.replicas[(start+i)%uint64(len(db.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Instance returns the host:port of the server that reads on ctx go to,
+// for the db.instance attribute of query metrics
+func (db *DB) Instance(ctx context.Context) string {
+	if state := routingFrom(ctx); state != nil && len(db.replicas) > 0 {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if !state.wrote && state.replica != nil {
+			return state.replica.name
+		}
+	}
+	return db.primary
+}
+
+// Run checks the connection pool and pings the replicas until ctx is
+// cancelled, readmitting the replicas that answer and ejecting the ones
+// that do not
+func (db *DB) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		db.checkPool()
+		for _, r := range db.replicas {
+			pingCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+			err := r.db.PingContext(pingCtx)
+			cancel()
+			if err != nil {
+				if ctx.Err() == nil {
+					r.eject(err)
+				}
+				continue
+			}
+			if !r.healthy.Swap(true) {
+				log.Printf("[DB] Replica %s readmitted", r.name)
+			}
+		}
+	}
+}
+
+// CheckReplicas fails when a replica is ejected
+func (db *DB) CheckReplicas(ctx context.Context) error {
+	var ejected []string
+	for _, r := range db.replicas {
+		if !r.healthy.Load() {
+			ejected = append(ejected, r.name)
+		}
+	}
+	if len(ejected) > 0 {
+		return fmt.Errorf("%d of %d replicas ejected: %s", len(ejected), len(db.replicas), strings.Join(ejected, ", "))
+	}
+	return nil
+}
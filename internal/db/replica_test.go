@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// downDriver opens no connections, like a replica that went away
+type downDriver struct{}
+
+func (downDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrBadConn }
+
+func init() {
+	sql.Register("down", downDriver{})
+}
+
+// withReplicas gives database a replica per name, each a SQLite database
+// whose servers table holds its name, as the primary's holds "primary".
+// Names starting with "down" never connect.
+func withReplicas(t *testing.T, database *DB, names ...string) {
+	t.Helper()
+	ctx := context.Background()
+	create := func(pool *sql.DB, name string) {
+		for _, stmt := range []string{"CREATE TABLE servers (name TEXT)", "INSERT INTO servers (name) VALUES ('" + name + "')"} {
+			if _, err := pool.ExecContext(ctx, stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	create(database.DB, "primary")
+
+	for _, name := range names {
+		driverName, dsn := "sqlite", filepath.Join(t.TempDir(), name+".db")
+		if strings.HasPrefix(name, "down") {
+			driverName = "down"
+		}
+		pool, err := sql.Open(driverName, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pool.Close() })
+		if driverName == "sqlite" {
+			create(pool, name)
+		}
+		r := &replica{name: name, db: pool}
+		r.healthy.Store(true)
+		database.replicas = append(database.replicas, r)
+	}
+}
+
+// server returns the server a read on ctx went to
+func server(t *testing.T, database *DB, ctx context.Context, query string) string {
+	t.Helper()
+	var name string
+	if err := database.QueryRowContext(ctx, query).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestReadRouting(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+	withReplicas(t, database, "replica-1", "replica-2")
+	const read = "SELECT name FROM servers"
+
+	if got := server(t, database, ctx, read); got != "primary" {
+		t.Errorf("read without routing went to %s", got)
+	}
+
+	// A request sticks to the replica it first reads from
+	first := WithRouting(ctx)
+	replica := server(t, database, first, read)
+	if replica == "primary" {
+		t.Fatal("read with routing went to the primary")
+	}
+	for i := 0; i < 5; i++ {
+		if got := server(t, database, first, read); got != replica {
+			t.Fatalf("read %d went to %s after %s", i+2, got, replica)
+		}
+	}
+	if got := database.Instance(first); got != replica {
+		t.Errorf("Instance = %s, want %s", got, replica)
+	}
+	// The next request gets the other replica
+	if got := server(t, database, WithRouting(ctx), read); got == replica || got == "primary" {
+		t.Errorf("next request read from %s, want the replica other than %s", got, replica)
+	}
+
+	// Once a request writes, it reads its writes from the primary
+	tests := []struct {
+		name  string
+		write func(ctx context.Context) error
+	}{
+		{"exec", func(ctx context.Context) error {
+			_, err := database.ExecContext(ctx, "UPDATE servers SET name = name")
+			return err
+		}},
+		{"transaction", func(ctx context.Context) error {
+			tx, err := database.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			return tx.Rollback()
+		}},
+		{"locking read", func(ctx context.Context) error {
+			return database.QueryRowContext(ctx, read+" FOR UPDATE").Scan(new(string))
+		}},
+	}
+	for _, tt := range tests {
+		ctx := WithRouting(ctx)
+		if got := server(t, database, ctx, read); got == "primary" {
+			t.Fatalf("%s: read before writing went to the primary", tt.name)
+		}
+		if err := tt.write(ctx); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := server(t, database, ctx, read); got != "primary" {
+			t.Errorf("%s: read after writing went to %s", tt.name, got)
+		}
+		if got := database.Instance(ctx); got != database.primary {
+			t.Errorf("%s: Instance = %s, want the primary", tt.name, got)
+		}
+	}
+
+	// Bookkeeping reads go to the primary and leave the request's routing be
+	routed := WithRouting(ctx)
+	if got := server(t, database, UsePrimary(routed), read); got != "primary" {
+		t.Errorf("UsePrimary read from %s", got)
+	}
+	if _, err := database.ExecContext(UsePrimary(routed), "UPDATE servers SET name = name"); err != nil {
+		t.Fatal(err)
+	}
+	if got := server(t, database, routed, read); got == "primary" {
+		t.Error("a write through UsePrimary sent the request's reads to the primary")
+	}
+}
+
+func TestReplicaEjection(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+	withReplicas(t, database, "down-1", "replica-1")
+	const read = "SELECT name FROM servers"
+
+	if err := database.CheckReplicas(ctx); err != nil {
+		t.Fatalf("before any read: %v", err)
+	}
+	// A read that hits the lost replica ejects it and is retried elsewhere
+	for i := 0; i < 4; i++ {
+		if got := server(t, database, WithRouting(ctx), read); got != "replica-1" {
+			t.Errorf("request %d read from %s, want replica-1", i+1, got)
+		}
+	}
+	err := database.CheckReplicas(ctx)
+	if err == nil || err.Error() != "1 of 2 replicas ejected: down-1" {
+		t.Errorf("CheckReplicas = %v, want down-1 ejected", err)
+	}
+
+	// A request stuck to a replica that is ejected moves on
+	stuck := WithRouting(ctx)
+	if got := server(t, database, stuck, read); got != "replica-1" {
+		t.Fatalf("read from %s", got)
+	}
+	database.replicas[1].eject(sql.ErrConnDone)
+	if got := server(t, database, stuck, read); got != "primary" {
+		t.Errorf("with every replica ejected, read from %s, want the primary", got)
+	}
+}
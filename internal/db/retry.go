@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Retry backoff between attempts, doubled each time with jitter
+const (
+	retryBackoffMin = 25 * time.Millisecond
+	retryBackoffMax = time.Second
+)
+
+// committedError is an error from after a transaction committed
+type committedError struct {
+	err error
+}
+
+func (e *committedError) Error() string { return e.err.Error() }
+func (e *committedError) Unwrap() error { return e.err }
+
+// AfterCommit marks err, returned by work done after a transaction
+// committed, so that Retry does not run the transaction again
+func AfterCommit(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &committedError{err: err}
+}
+
+// transientReason returns why err is worth retrying, or "" when it is not:
+// "deadlock" and "lock_wait_timeout" roll back the statement (and we then
+// roll back the transaction), and "connection" means the connection dropped,
+// which rolls back any open transaction on the server. PostgreSQL
+// serialization failures count as deadlocks, and SQLite's busy database
+// as a lock wait timeout.
+func transientReason(err error) string {
+	var committed *committedError
+	if errors.As(err, &committed) {
+		return ""
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case errDeadlock:
+			return "deadlock"
+		case errLockWaitTimeout:
+			return "lock_wait_timeout"
+		}
+		return ""
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgDeadlockDetected, pgSerializationFailure:
+			return "deadlock"
+		case pgLockNotAvailable:
+			return "lock_wait_timeout"
+		}
+		return ""
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return "lock_wait_timeout"
+		}
+		return ""
+	}
+	if errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return "connection"
+	}
+	return ""
+}
+
+// IsTransient reports whether err is a deadlock, lock wait timeout or lost
+// connection that is worth retrying
+func IsTransient(err error) bool {
+	return transientReason(err) != ""
+}
+
+// Retry runs fn, and runs it again after a backoff while it fails with a
+// transient error, up to the configured number of retries. fn must be
+// safe to repeat: a read, or a whole transaction from BeginTx to Commit, so
+// that a failed attempt leaves nothing behind. name identifies the
+// operation in the db.client.retries metric.
+func (db *DB) Retry(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	backoff := retryBackoffMin
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		reason := transientReason(err)
+		if reason == "" || attempt >= db.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		db.retries.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("db.system", db.dialect.system()),
+			attribute.String("db.operation", name),
+			attribute.String("reason", reason),
+			attribute.String("service.name", db.serviceName),
+		))
+		log.Printf("[DB] Retrying %s after %s (attempt %d): %v", name, reason, attempt+1, err)
+
+		// Full jitter keeps deadlocked transactions from colliding again
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rand.N(backoff) + time.Millisecond):
+		}
+		backoff = min(backoff*2, retryBackoffMax)
+	}
+}
+
+// ExecContext runs a statement on the primary, bounded by the query
+// timeout when ctx has no deadline. Statements are not retried since they
+// may not be idempotent; wrap them in Retry when they are.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	markWrite(ctx)
+	if _, ok := ctx.Deadline(); !ok && db.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.queryTimeout)
+		defer cancel()
+	}
+	return db.exec(ctx, db.DB, query, args)
+}
+
+// QueryContext runs a query. SELECTs are routed to a replica when ctx
+// allows it and retried when they fail with a transient error before
+// returning rows; a replica that loses its connection is ejected and the
+// retry goes elsewhere.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if !isSelect(query) {
+		markWrite(ctx)
+		return db.DB.QueryContext(ctx, db.dialect.rewrite(query), args...)
+	}
+
+	var rows *sql.Rows
+	err := db.Retry(ctx, "SELECT", func(ctx context.Context) error {
+		pool, r := db.reader(ctx, query)
+		var err error
+		rows, err = pool.QueryContext(ctx, db.dialect.rewrite(query), args...)
+		db.checkReplica(r, err)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext runs a query expected to return at most one row, routed
+// and retried like QueryContext
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if !isSelect(query) {
+		markWrite(ctx)
+		return db.DB.QueryRowContext(ctx, db.dialect.rewrite(query), args...)
+	}
+
+	var row *sql.Row
+	db.Retry(ctx, "SELECT", func(ctx context.Context) error {
+		pool, r := db.reader(ctx, query)
+		row = pool.QueryRowContext(ctx, db.dialect.rewrite(query), args...)
+		db.checkReplica(r, row.Err())
+		return row.Err()
+	})
+	return row
+}
+
+// checkReplica ejects r when err shows it lost its connection
+func (db *DB) checkReplica(r *replica, err error) {
+	if r != nil && transientReason(err) == "connection" {
+		r.eject(err)
+	}
+}
+
+func isSelect(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Tx is a transaction on the primary. Its queries are rewritten for the
+// database's dialect like those made through DB.
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+// BeginTx starts a transaction on the primary
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	markWrite(ctx)
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// ExecContext runs a statement in the transaction
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.db.exec(ctx, tx.Tx, query, args)
+}
+
+// Commit commits the transaction. A connection lost while committing leaves
+// it unknown whether the commit took effect, so the error is marked like
+// AfterCommit's: Retry must not run the transaction again and apply it twice.
+func (tx *Tx) Commit() error {
+	err := tx.Tx.Commit()
+	if transientReason(err) == "connection" {
+		return AfterCommit(err)
+	}
+	return err
+}
+
+// QueryContext runs a query in the transaction
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.db.dialect.rewrite(query), args...)
+}
+
+// QueryRowContext runs a query expected to return at most one row in the
+// transaction
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.db.dialect.rewrite(query), args...)
+}
+
+// execQuerier is implemented by *sql.DB and *sql.Tx
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// exec runs a statement rewritten for the dialect. Where the driver has no
+// LastInsertId, INSERTs read the ids they create with RETURNING id.
+func (db *DB) exec(ctx context.Context, q execQuerier, query string, args []interface{}) (sql.Result, error) {
+	query = db.dialect.rewrite(query)
+	if !db.dialect.returningID() || !isInsert(query) {
+		return q.ExecContext(ctx, query, args...)
+	}
+
+	rows, err := q.QueryContext(ctx, query+" RETURNING id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result insertResult
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read inserted id: %w", err)
+		}
+		if result.rows == 0 {
+			result.id = id
+		}
+		result.rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// insertResult is the result of an INSERT ... RETURNING id. Like MySQL's,
+// its LastInsertId is the first id inserted.
+type insertResult struct {
+	id   int64
+	rows int64
+}
+
+func (r insertResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r insertResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+func isInsert(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= 6 && strings.EqualFold(query[:6], "INSERT")
+}
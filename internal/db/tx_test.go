@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// lostCommitDriver opens connections whose commits fail with a lost
+// connection, as when the server goes away after receiving COMMIT
+type lostCommitDriver struct{}
+
+func (lostCommitDriver) Open(string) (driver.Conn, error) { return lostCommitConn{}, nil }
+
+type lostCommitConn struct{}
+
+func (lostCommitConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (lostCommitConn) Close() error                        { return nil }
+func (lostCommitConn) Begin() (driver.Tx, error)           { return lostCommitTx{}, nil }
+
+type lostCommitTx struct{}
+
+func (lostCommitTx) Commit() error   { return driver.ErrBadConn }
+func (lostCommitTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("lostcommit", lostCommitDriver{})
+}
+
+// newTestDB opens a migrated SQLite database in a temporary directory, as
+// testutil.NewDB does for the packages that can import it
+func newTestDB(tb testing.TB) *DB {
+	tb.Helper()
+	ctx := context.Background()
+	database, err := NewDB(ctx, filepath.Join(tb.TempDir(), "test.db"), Options{
+		Driver:     "sqlite",
+		MaxRetries: 3,
+	}, noop.NewMeterProvider().Meter("test"), "test")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { database.Close() })
+	if err := database.Migrate(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return database
+}
+
+func TestRetrySkipsLostCommits(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+	lost, err := sql.Open("lostcommit", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lost.Close()
+
+	// The commit may have gone through, so running the transaction again
+	// could apply it twice
+	attempts := 0
+	err = database.Retry(ctx, "test", func(ctx context.Context) error {
+		attempts++
+		sqlTx, err := lost.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		tx := &Tx{Tx: sqlTx, db: database}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	})
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("Retry = %v, want the lost connection", err)
+	}
+	if attempts != 1 {
+		t.Errorf("transaction ran %d times, want once", attempts)
+	}
+
+	// A connection lost before the commit rolls the transaction back, so it
+	// is retried
+	attempts = 0
+	err = database.Retry(ctx, "test", func(ctx context.Context) error {
+		attempts++
+		return fmt.Errorf("failed to update inventory: %w", driver.ErrBadConn)
+	})
+	if !errors.Is(err, driver.ErrBadConn) || attempts != 4 {
+		t.Errorf("Retry = %v after %d attempts, want the lost connection after 4", err, attempts)
+	}
+}
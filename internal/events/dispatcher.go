@@ -0,0 +1,312 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	dispatchInterval = 2 * time.Second
+	dispatchBatch    = 100
+	maxRetryBackoff  = 5 * time.Minute
+
+	// claimLease is how long other dispatchers leave a claimed event alone.
+	// An instance that dies mid-batch delays its events by this much.
+	claimLease = time.Minute
+)
+
+// Dispatcher delivers outbox events to sinks.
+//
+// Events are marked published only after every sink accepted them, so a
+// failure in any sink causes the whole event to be retried (at-least-once).
+// Failed events are retried with exponential backoff. An event is only
+// claimed once every earlier event of its aggregate is published, so events
+// of an aggregate are delivered in order, also across app instances.
+//
+// Claimed events are leased for claimLease through next_attempt_at and
+// delivered outside the claiming transaction, so no row locks are held
+// across the sinks' network calls.
+type Dispatcher struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+	sinks   []Sink
+}
+
+// NewDispatcher creates a new outbox dispatcher
+func NewDispatcher(db *db.DB, metrics *metrics.AppMetrics, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		db:      db,
+		metrics: metrics,
+		sinks:   sinks,
+	}
+}
+
+// AddSink registers another sink. It must be called before Run.
+func (d *Dispatcher) AddSink(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep draining while events get published: the next event of an
+		// aggregate only becomes claimable once the one before it is
+		for {
+			n, err := d.dispatchBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[EVENTS] Outbox dispatch failed: %v", err)
+				}
+				break
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+}
+
+// dispatchBatch claims a batch of due events, delivers them and records the
+// outcome. It returns how many events were published.
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	batch, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, p := range batch {
+		err := d.deliver(ctx, p.event)
+		if err == nil {
+			published++
+			lag := time.Since(p.event.CreatedAt).Milliseconds()
+			d.metrics.EventsPublishLag.Record(ctx, float64(lag), metric.WithAttributes(d.metrics.WithServiceName([]attribute.KeyValue{
+				attribute.String("event_type", p.event.Type),
+			})...))
+			continue
+		}
+		if ctx.Err() != nil {
+			// Shutting down; the lease runs out and another pass retries it
+			return published, ctx.Err()
+		}
+
+		backoff := retryBackoff(p.attempts + 1)
+		log.Printf("[EVENTS] Delivery failed: event_id=%d, type=%s, attempt=%d, retry_in=%s, error=%v",
+			p.event.ID, p.event.Type, p.attempts+1, backoff, err)
+
+		start := time.Now()
+		retryQuery := "UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?"
+		_, err = d.db.ExecContext(ctx, retryQuery, err.Error(), time.Now().UTC().Add(backoff), p.event.ID)
+		d.metrics.RecordDBQuery(ctx, "UPDATE", "outbox", retryQuery, start, err == nil)
+		if err != nil {
+			return published, fmt.Errorf("failed to schedule retry: %w", err)
+		}
+	}
+	return published, nil
+}
+
+// claimedEvent is an outbox event leased by this dispatcher
+type claimedEvent struct {
+	event    Event
+	attempts int
+}
+
+// claim leases the due events that have no unpublished event before them in
+// their aggregate
+func (d *Dispatcher) claim(ctx context.Context) ([]claimedEvent, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several app instances claim concurrently
+	now := time.Now().UTC()
+	start := time.Now()
+	query := `
+		SELECT id, event_type, aggregate_type, aggregate_id, payload, trace_context, attempts, created_at
+		FROM outbox
+		WHERE published_at IS NULL AND next_attempt_at <= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM outbox o2
+			WHERE o2.aggregate_type = outbox.aggregate_type AND o2.aggregate_id = outbox.aggregate_id
+			  AND o2.published_at IS NULL AND o2.id < outbox.id
+		  )
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, now, dispatchBatch)
+	d.metrics.RecordDBQuery(ctx, "SELECT", "outbox", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var batch []claimedEvent
+	for rows.Next() {
+		var c claimedEvent
+		var traceContext []byte
+		if err := rows.Scan(&c.event.ID, &c.event.Type, &c.event.AggregateType, &c.event.AggregateID,
+			&c.event.Payload, &traceContext, &c.attempts, &c.event.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		if len(traceContext) > 0 {
+			if err := json.Unmarshal(traceContext, &c.event.TraceContext); err != nil {
+				log.Printf("[EVENTS] Ignoring invalid trace context: event_id=%d, error=%v", c.event.ID, err)
+			}
+		}
+		batch = append(batch, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]interface{}, 0, len(batch)+1)
+	ids = append(ids, now.Add(claimLease))
+	placeholders := make([]string, len(batch))
+	for i, c := range batch {
+		placeholders[i] = "?"
+		ids = append(ids, c.event.ID)
+	}
+	start = time.Now()
+	leaseQuery := "UPDATE outbox SET next_attempt_at = ? WHERE id IN (" + strings.Join(placeholders, ", ") + ")"
+	_, err = tx.ExecContext(ctx, leaseQuery, ids...)
+	d.metrics.RecordDBQuery(ctx, "UPDATE", "outbox", "UPDATE outbox SET next_attempt_at = ? WHERE id IN (...)", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease outbox events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit outbox claim: %w", err)
+	}
+	return batch, nil
+}
+
+// deliver publishes an event to every sink and marks it published,
+// returning the first error. The event's trace context is restored into ctx
+// so sinks can propagate it. Sinks that write to the database (TxSink)
+// publish in the transaction that marks the event published; the others
+// publish before it, outside any transaction, and a failure in one of them
+// skips the TxSinks.
+func (d *Dispatcher) deliver(ctx context.Context, event Event) error {
+	if len(event.TraceContext) > 0 {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(event.TraceContext))
+	}
+
+	var txSinks []TxSink
+	var firstErr error
+	for _, sink := range d.sinks {
+		if txSink, ok := sink.(TxSink); ok {
+			txSinks = append(txSinks, txSink)
+			continue
+		}
+		err := sink.Publish(ctx, event)
+		d.recordPublish(ctx, event, sink, err)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("sink %s: %w", sink.Name(), err)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	err := d.db.Retry(ctx, "outbox", func(ctx context.Context) error {
+		tx, err := d.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for _, sink := range txSinks {
+			if err := sink.PublishTx(ctx, tx, event); err != nil {
+				return fmt.Errorf("sink %s: %w", sink.Name(), err)
+			}
+		}
+
+		start := time.Now()
+		publishedQuery := "UPDATE outbox SET attempts = attempts + 1, last_error = NULL, published_at = ? WHERE id = ?"
+		_, err = tx.ExecContext(ctx, publishedQuery, time.Now().UTC(), event.ID)
+		d.metrics.RecordDBQuery(ctx, "UPDATE", "outbox", publishedQuery, start, err == nil)
+		if err != nil {
+			return fmt.Errorf("failed to mark event published: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit event: %w", err)
+		}
+		return nil
+	})
+	for _, sink := range txSinks {
+		d.recordPublish(ctx, event, sink, err)
+	}
+	return err
+}
+
+// recordPublish counts a delivery of an event to a sink
+func (d *Dispatcher) recordPublish(ctx context.Context, event Event, sink Sink, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	d.metrics.EventsPublished.Add(ctx, 1, metric.WithAttributes(d.metrics.WithServiceName([]attribute.KeyValue{
+		attribute.String("event_type", event.Type),
+		attribute.String("sink", sink.Name()),
+		attribute.String("status", status),
+	})...))
+}
+
+// retryBackoff returns the delay before the given delivery attempt
+func retryBackoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return maxRetryBackoff
+	}
+	backoff := time.Second << uint(attempt-1)
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// LogSink writes every event to the application log
+type LogSink struct{}
+
+// NewLogSink creates a sink that logs events
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+// Name returns the sink name
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+// Publish logs the event
+func (s *LogSink) Publish(ctx context.Context, event Event) error {
+	log.Printf("[EVENT] id=%d type=%s %s=%s payload=%s",
+		event.ID, event.Type, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+// recordingSink records the events it accepts and fails the ones in fail
+type recordingSink struct {
+	fail      map[int64]bool
+	published []Event
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Publish(ctx context.Context, event Event) error {
+	if s.fail[event.ID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func TestDispatcherOrdersEventsPerAggregate(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	outbox := NewOutbox(m)
+
+	// Events 1 and 3 belong to order 1, event 2 to order 2
+	enqueue(t, ctx, database, outbox, TypeOrderCreated, AggregateOrder, 1, OrderCreated{OrderID: 1})
+	enqueue(t, ctx, database, outbox, TypeOrderCreated, AggregateOrder, 2, OrderCreated{OrderID: 2})
+	enqueue(t, ctx, database, outbox, TypeOrderStatusChanged, AggregateOrder, 1, OrderStatusChanged{OrderID: 1, OldStatus: "pending", NewStatus: "shipped"})
+
+	sink := &recordingSink{fail: map[int64]bool{1: true}}
+	d := NewDispatcher(database, m, sink)
+
+	// While the first event of order 1 fails, its later events wait and
+	// other aggregates are not held up
+	n, err := d.dispatchBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(sink.published) != 1 || sink.published[0].ID != 2 {
+		t.Fatalf("first batch published %d events %+v, want only event 2", n, sink.published)
+	}
+	makeDue(t, database)
+	if n, err := d.dispatchBatch(ctx); err != nil || n != 0 {
+		t.Fatalf("second batch published %d, %v; want nothing while event 1 fails", n, err)
+	}
+
+	var attempts int
+	var lastError string
+	if err := database.QueryRowContext(ctx, "SELECT attempts, last_error FROM outbox WHERE id = 1").Scan(&attempts, &lastError); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || lastError == "" {
+		t.Errorf("event 1 has attempts=%d last_error=%q, want 2 failed attempts", attempts, lastError)
+	}
+
+	// Once it succeeds, the rest of order 1 follows in order
+	sink.fail = nil
+	makeDue(t, database)
+	for {
+		n, err := d.dispatchBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	var ids []int64
+	for _, e := range sink.published {
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 3 || ids[1] != 1 || ids[2] != 3 {
+		t.Errorf("published events %v, want [2 1 3]", ids)
+	}
+
+	var pending int
+	if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox WHERE published_at IS NULL").Scan(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d events still pending", pending)
+	}
+}
+
+func TestDispatcherLeasesClaimedEvents(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	enqueue(t, ctx, database, NewOutbox(m), TypeUserDeleted, AggregateUser, 7, UserDeleted{UserID: 7})
+
+	d := NewDispatcher(database, m)
+	batch, err := d.claim(ctx)
+	if err != nil || len(batch) != 1 {
+		t.Fatalf("claim = %d events, %v; want 1", len(batch), err)
+	}
+
+	// Another dispatcher must not claim it again while the lease holds
+	if again, err := NewDispatcher(database, m).claim(ctx); err != nil || len(again) != 0 {
+		t.Errorf("second claim = %d events, %v; want none", len(again), err)
+	}
+}
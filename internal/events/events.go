@@ -0,0 +1,173 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Domain event types
+const (
+	TypeOrderCreated       = "order.created"
+	TypeOrderStatusChanged = "order.status_changed"
+	TypeCartItemAdded      = "cart.item_added"
+	TypeShipmentCreated    = "shipment.created"
+	TypeShipmentDelivered  = "shipment.delivered"
+	TypeUserDeleted        = "user.deleted"
+
+	// TypeInventoryLowStock keeps the inventory.low wire name that
+	// subscribers already match on
+	TypeInventoryLowStock = "inventory.low"
+
+	// TypeNotificationPasswordReset carries a password reset token for a
+	// mailer to deliver. Only subscribe trusted sinks to it.
+	TypeNotificationPasswordReset = "notification.password_reset"
+)
+
+// Aggregate types events are keyed by
+const (
+	AggregateOrder   = "order"
+	AggregateUser    = "user"
+	AggregateProduct = "product"
+)
+
+// Event is a domain event stored in the outbox
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+
+	// TraceContext is the propagated trace context of the request that
+	// produced the event (e.g. traceparent)
+	TraceContext map[string]string `json:"-"`
+}
+
+// Sink receives events delivered by the dispatcher.
+// Delivery is at-least-once, so sinks must tolerate duplicates (use Event.ID).
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// TxSink is a Sink that writes to the application database. The dispatcher
+// has it publish through the transaction that marks the event published, so
+// its writes commit together with it and do not wait on the dispatcher's own
+// locks (SQLite locks the whole database).
+type TxSink interface {
+	Sink
+	PublishTx(ctx context.Context, tx Execer, event Event) error
+}
+
+// OrderCreated is the payload of order.created
+type OrderCreated struct {
+	OrderID       int64            `json:"order_id"`
+	UserID        int64            `json:"user_id"`
+	Status        string           `json:"status"`
+	PaymentMethod string           `json:"payment_method"`
+	TotalAmount   models.Money     `json:"total_amount"`
+	Currency      string           `json:"currency"`
+	BaseAmount    models.Money     `json:"base_amount"`
+	BaseCurrency  string           `json:"base_currency"`
+	Items         []OrderLineEvent `json:"items"`
+}
+
+// OrderLineEvent is a line of an order in an event payload
+type OrderLineEvent struct {
+	ProductID   int64        `json:"product_id"`
+	Quantity    int          `json:"quantity"`
+	Price       models.Money `json:"price"`
+	WarehouseID string       `json:"warehouse_id,omitempty"`
+}
+
+// OrderStatusChanged is the payload of order.status_changed
+type OrderStatusChanged struct {
+	OrderID   int64  `json:"order_id"`
+	UserID    int64  `json:"user_id"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+}
+
+// CartItemAdded is the payload of cart.item_added
+type CartItemAdded struct {
+	UserID    int64 `json:"user_id"`
+	CartID    int64 `json:"cart_id"`
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+}
+
+// InventoryLowStock is the payload of inventory.low
+type InventoryLowStock struct {
+	ProductID    int64  `json:"product_id"`
+	WarehouseID  string `json:"warehouse_id"`
+	Quantity     int    `json:"quantity"`
+	ReorderPoint int    `json:"reorder_point"`
+}
+
+// ShipmentUpdated is the payload of shipment.created and shipment.delivered
+type ShipmentUpdated struct {
+	ShipmentID     int64  `json:"shipment_id"`
+	OrderID        int64  `json:"order_id"`
+	WarehouseID    string `json:"warehouse_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	Status         string `json:"status"`
+}
+
+// UserDeleted is the payload of user.deleted. Consumers holding personal
+// data of the user should erase it.
+type UserDeleted struct {
+	UserID int64 `json:"user_id"`
+}
+
+// Execer is implemented by *db.Tx (and *db.DB)
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Outbox writes events into the outbox table as part of a caller's transaction
+type Outbox struct {
+	metrics *metrics.AppMetrics
+}
+
+// NewOutbox creates a new outbox writer
+func NewOutbox(metrics *metrics.AppMetrics) *Outbox {
+	return &Outbox{metrics: metrics}
+}
+
+// Enqueue records an event in the outbox. Pass the transaction that makes the
+// state change so the event is committed (or rolled back) together with it.
+func (o *Outbox) Enqueue(ctx context.Context, tx Execer, eventType, aggregateType string, aggregateID int64, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	// Capture the trace context so the dispatcher can propagate it later
+	var traceContext interface{}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		if traceContext, err = json.Marshal(carrier); err != nil {
+			return fmt.Errorf("failed to encode trace context: %w", err)
+		}
+	}
+
+	start := time.Now()
+	query := "INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload, trace_context) VALUES (?, ?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, query, eventType, aggregateType, fmt.Sprint(aggregateID), data, traceContext)
+	o.metrics.RecordDBQuery(ctx, "INSERT", "outbox", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// enqueue writes an event to the outbox outside any transaction
+func enqueue(tb testing.TB, ctx context.Context, database *db.DB, outbox *Outbox, eventType, aggregateType string, aggregateID int64, payload interface{}) {
+	tb.Helper()
+	if err := outbox.Enqueue(ctx, database, eventType, aggregateType, aggregateID, payload); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// makeDue lets the dispatcher claim every pending event, skipping backoffs
+// and leases
+func makeDue(tb testing.TB, database *db.DB) {
+	tb.Helper()
+	if _, err := database.ExecContext(context.Background(), "UPDATE outbox SET next_attempt_at = ? WHERE published_at IS NULL", "2000-01-01 00:00:00"); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes messages to Kafka (or any Kafka-protocol broker)
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher for the given brokers.
+// Messages are partitioned by key hash and require acknowledgement from all
+// in-sync replicas.
+func NewKafkaPublisher(brokers []string) (*KafkaPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no kafka brokers configured")
+	}
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			BatchTimeout:           10 * time.Millisecond,
+		},
+	}, nil
+}
+
+// Publish writes one message synchronously
+func (p *KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   msg.Topic,
+		Key:     []byte(msg.Key),
+		Value:   msg.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to kafka topic %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+// Close flushes pending writes and closes the connection
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
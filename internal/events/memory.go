@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps published messages in memory. It is meant for tests
+// and local runs: wire it into a PublisherSink and inspect Messages to check
+// which events the services emitted.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryPublisher creates an empty in-memory publisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish records the message
+func (p *MemoryPublisher) Publish(ctx context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message published so far
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+// MessagesFor returns the messages published to a topic
+func (p *MemoryPublisher) MessagesFor(topic string) []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []Message
+	for _, m := range p.messages {
+		if m.Topic == topic {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Reset discards all recorded messages
+func (p *MemoryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
+
+// Close is a no-op
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
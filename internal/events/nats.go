@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderKey carries the message key on NATS, which has no native keys
+const HeaderKey = "event-key"
+
+// NATSPublisher publishes messages to NATS subjects
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the NATS server at url
+func NewNATSPublisher(url string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("ecommerce-go-app"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	return &NATSPublisher{conn: conn}, nil
+}
+
+// Publish sends the message to the subject named by its topic and waits for
+// the server to acknowledge the flush. Nats-Msg-Id is set to the event ID so
+// JetStream streams de-duplicate redeliveries.
+func (p *NATSPublisher) Publish(ctx context.Context, msg Message) error {
+	m := nats.NewMsg(msg.Topic)
+	m.Data = msg.Value
+	for k, v := range msg.Headers {
+		m.Header.Set(k, v)
+	}
+	m.Header.Set(HeaderKey, msg.Key)
+	if id, ok := msg.Headers[HeaderEventID]; ok {
+		m.Header.Set(nats.MsgIdHdr, id)
+	}
+
+	if err := p.conn.PublishMsg(m); err != nil {
+		return fmt.Errorf("failed to publish to nats subject %s: %w", msg.Topic, err)
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush nats connection: %w", err)
+	}
+	return nil
+}
+
+// Close drains and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
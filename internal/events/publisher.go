@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SigNoz/ecommerce-go-app/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Message headers set on every published event
+const (
+	HeaderEventID       = "event-id"
+	HeaderEventType     = "event-type"
+	HeaderAggregateType = "aggregate-type"
+)
+
+// Message is a keyed message for a message bus
+type Message struct {
+	Topic   string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// EventPublisher sends messages to a message bus
+type EventPublisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// NewEventPublisher creates the publisher selected by EVENT_PUBLISHER
+// (kafka, nats or memory). It returns nil when publishing is disabled.
+func NewEventPublisher(cfg *config.Config) (EventPublisher, error) {
+	switch strings.ToLower(cfg.EventPublisher) {
+	case "", "none":
+		return nil, nil
+	case "kafka":
+		return NewKafkaPublisher(splitList(cfg.KafkaBrokers))
+	case "nats":
+		return NewNATSPublisher(cfg.NATSURL)
+	case "memory":
+		return NewMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher: %s", cfg.EventPublisher)
+	}
+}
+
+// PublisherSink adapts an EventPublisher to the outbox dispatcher.
+// Each event type goes to its own topic (prefix + type), keyed by the
+// aggregate ID (order_id for order events, user_id for cart events) so
+// consumers see the events of one aggregate in order.
+type PublisherSink struct {
+	name        string
+	publisher   EventPublisher
+	topicPrefix string
+}
+
+// NewPublisherSink creates a sink that publishes events to a message bus
+func NewPublisherSink(name string, publisher EventPublisher, topicPrefix string) *PublisherSink {
+	return &PublisherSink{
+		name:        name,
+		publisher:   publisher,
+		topicPrefix: topicPrefix,
+	}
+}
+
+// Name returns the sink name
+func (s *PublisherSink) Name() string {
+	return s.name
+}
+
+// Publish converts the event into a message and publishes it.
+// Trace context in ctx is propagated in the message headers.
+func (s *PublisherSink) Publish(ctx context.Context, event Event) error {
+	headers := map[string]string{
+		HeaderEventID:       fmt.Sprint(event.ID),
+		HeaderEventType:     event.Type,
+		HeaderAggregateType: event.AggregateType,
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+
+	return s.publisher.Publish(ctx, Message{
+		Topic:   s.topicPrefix + event.Type,
+		Key:     event.AggregateID,
+		Value:   event.Payload,
+		Headers: headers,
+	})
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPublisherSinkThroughDispatcher(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	outbox := NewOutbox(m)
+
+	// The order is created inside a traced request
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	created := OrderCreated{OrderID: 42, UserID: 7}
+	enqueue(t, traced, database, outbox, TypeOrderCreated, AggregateOrder, 42, created)
+	// A background job has no trace to propagate
+	enqueue(t, context.Background(), database, outbox, TypeUserDeleted, AggregateUser, 7, UserDeleted{UserID: 7})
+
+	publisher := NewMemoryPublisher()
+	d := NewDispatcher(database, m, NewPublisherSink("memory", publisher, "ecommerce."))
+	if n, err := d.dispatchBatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("dispatchBatch = %d, %v; want 2 published", n, err)
+	}
+
+	if got := len(publisher.Messages()); got != 2 {
+		t.Fatalf("published %d messages, want 2", got)
+	}
+
+	orders := publisher.MessagesFor("ecommerce.order.created")
+	if len(orders) != 1 {
+		t.Fatalf("got %d messages on ecommerce.order.created, want 1", len(orders))
+	}
+	msg := orders[0]
+	if msg.Key != "42" {
+		t.Errorf("key = %q, want the order id 42", msg.Key)
+	}
+	var payload OrderCreated
+	if err := json.Unmarshal(msg.Value, &payload); err != nil || payload.OrderID != created.OrderID || payload.UserID != created.UserID {
+		t.Errorf("payload %s (%v), want %+v", msg.Value, err, created)
+	}
+	for header, want := range map[string]string{
+		HeaderEventID:       "1",
+		HeaderEventType:     TypeOrderCreated,
+		HeaderAggregateType: AggregateOrder,
+		"traceparent":       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if got := msg.Headers[header]; got != want {
+			t.Errorf("header %s = %q, want %q", header, got, want)
+		}
+	}
+
+	users := publisher.MessagesFor("ecommerce.user.deleted")
+	if len(users) != 1 {
+		t.Fatalf("got %d messages on ecommerce.user.deleted, want 1", len(users))
+	}
+	if users[0].Key != "7" || users[0].Headers[HeaderEventID] != "2" {
+		t.Errorf("user.deleted message %+v, want key 7 and event id 2", users[0])
+	}
+	if tp, ok := users[0].Headers["traceparent"]; ok {
+		t.Errorf("untraced event carries traceparent %q", tp)
+	}
+
+	// Published events are not delivered again
+	publisher.Reset()
+	makeDue(t, database)
+	if n, err := d.dispatchBatch(context.Background()); err != nil || n != 0 || len(publisher.Messages()) != 0 {
+		t.Errorf("second dispatch = %d, %v with %d messages; want nothing", n, err, len(publisher.Messages()))
+	}
+}
@@ -0,0 +1,106 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/ast"
+)
+
+// assumedListSize is how many elements a nested list, such as cart items
+// or a product's warehouses, is assumed to have when estimating cost
+const assumedListSize = 10
+
+// complexity limits how many fields a request may resolve. Every field
+// costs 1, multiplied by the size of the lists it is nested in: the limit
+// argument of the query, or assumedListSize below it. Root fields are
+// charged before they read anything.
+type complexity struct {
+	schema *ast.Schema
+	max    int
+}
+
+// budget is the cost charged to one request so far
+type budget struct {
+	mu    sync.Mutex
+	spent int
+}
+
+// charge adds the cost of the current root field and its selection to the
+// request's budget. size is the number of elements a list field returns at
+// most, and 1 otherwise.
+func (c *complexity) charge(ctx context.Context, field string, size int) error {
+	if c.max <= 0 {
+		return nil
+	}
+
+	cost := c.cost(field, size, graphql.SelectedFieldNames(ctx))
+	b := budgetFrom(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent += cost
+	if b.spent > c.max {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", b.spent, c.max)
+	}
+	return nil
+}
+
+// cost estimates the fields resolved by a root field. paths are its
+// selected fields as dot-separated paths, e.g. "items.product.name".
+func (c *complexity) cost(field string, size int, paths []string) int {
+	query, ok := c.schema.RootOperationTypes["query"].(*ast.ObjectTypeDefinition)
+	if !ok {
+		return 1
+	}
+	root := query.Fields.Get(field)
+	if root == nil {
+		return 1
+	}
+
+	cost := 1
+	for _, path := range paths {
+		typ := root.Type
+		n := size
+		for i, name := range strings.Split(path, ".") {
+			if i > 0 && isList(typ) {
+				n *= assumedListSize
+			}
+			obj, ok := namedType(typ).(*ast.ObjectTypeDefinition)
+			if !ok {
+				break
+			}
+			f := obj.Fields.Get(name)
+			if f == nil {
+				break
+			}
+			typ = f.Type
+		}
+		cost += n
+	}
+	return cost
+}
+
+func isList(t ast.Type) bool {
+	if nonNull, ok := t.(*ast.NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*ast.List)
+	return ok
+}
+
+// namedType strips list and non-null wrappers
+func namedType(t ast.Type) ast.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *ast.NonNull:
+			t = wrapped.OfType
+		case *ast.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Package graphqlapi serves the storefront GraphQL API (schema.graphql). It
+// reads through the same services as the REST handlers, batching product,
+// stock and order item lookups per request so that a cart or order list is
+// read in a few IN queries rather than one query per line.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxQueryLength caps the query document in bytes
+	maxQueryLength = 8 << 10
+	// maxRequestBody caps the request, query and variables together
+	maxRequestBody = 64 << 10
+)
+
+type contextKey int
+
+const (
+	loadersKey contextKey = iota
+	budgetKey
+)
+
+// Handler executes GraphQL queries
+type Handler struct {
+	schema    *graphql.Schema
+	products  *services.ProductService
+	inventory *services.InventoryService
+	orders    *services.OrderService
+}
+
+// NewHandler parses the schema and binds it to the services. Queries
+// nested deeper than maxDepth or costing more than maxComplexity fields are
+// rejected; zero disables either limit.
+func NewHandler(
+	ps *services.ProductService,
+	cs *services.CartService,
+	is *services.InventoryService,
+	os *services.OrderService,
+	m *metrics.AppMetrics,
+	maxDepth, maxComplexity int,
+) *Handler {
+	c := &complexity{max: maxComplexity}
+	schema := graphql.MustParseSchema(schemaSDL, &resolver{
+		products:   ps,
+		carts:      cs,
+		orders:     os,
+		complexity: c,
+	},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+		graphql.Tracer(metricsTracer{metrics: m}),
+	)
+	c.schema = schema.AST()
+
+	return &Handler{
+		schema:    schema,
+		products:  ps,
+		inventory: is,
+		orders:    os,
+	}
+}
+
+// ServeHTTP handles POST requests with a JSON {"query", "operationName",
+// "variables"} body. Query errors are reported in the response's "errors"
+// with status 200, as GraphQL clients expect.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&params); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if params.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loadersKey, newLoaders(h.products, h.inventory, h.orders))
+	ctx = context.WithValue(ctx, budgetKey, &budget{})
+	response := h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey).(*loaders)
+}
+
+func budgetFrom(ctx context.Context) *budget {
+	return ctx.Value(budgetKey).(*budget)
+}
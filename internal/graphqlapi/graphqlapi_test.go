@@ -0,0 +1,193 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// testHandler serves GraphQL over the seed data and counts the queries
+// the services run per table
+type testHandler struct {
+	*Handler
+	carts  *services.CartService
+	users  *services.UserService
+	reader *sdkmetric.ManualReader
+	seen   map[string]int64 // SELECTs per table at the last count
+}
+
+func newTestHandler(t *testing.T, maxDepth, maxComplexity int) *testHandler {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	m, err := metrics.NewAppMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"), "test", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := testutil.NewDB(t)
+	outbox := events.NewOutbox(m)
+	inventory, err := services.NewInventoryService(database, m, outbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carts := services.NewCartService(database, m, "USD", outbox)
+	h := NewHandler(services.NewProductService(database, m, "USD"), carts, inventory, nil, m, maxDepth, maxComplexity)
+	return &testHandler{
+		Handler: h,
+		carts:   carts,
+		users:   services.NewUserService(database, m, outbox),
+		reader:  reader,
+		seen:    make(map[string]int64),
+	}
+}
+
+// query runs query and returns its data and error messages
+func (h *testHandler) query(t *testing.T, query string) (json.RawMessage, []string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range resp.Errors {
+		errs = append(errs, e.Message)
+	}
+	return resp.Data, errs
+}
+
+// queries returns the SELECTs run on each table since the last call
+func (h *testHandler) queries(t *testing.T) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := h.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	totals := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			data, ok := metric.Data.(metricdata.Sum[int64])
+			if !ok || metric.Name != "db.client.queries.count" {
+				continue
+			}
+			for _, dp := range data.DataPoints {
+				op, _ := dp.Attributes.Value(attribute.Key("db.operation"))
+				table, _ := dp.Attributes.Value(attribute.Key("db.sql.table"))
+				if op.AsString() == "SELECT" {
+					totals[table.AsString()] += dp.Value
+				}
+			}
+		}
+	}
+	counts := make(map[string]int64)
+	for table, total := range totals {
+		if n := total - h.seen[table]; n > 0 {
+			counts[table] = n
+		}
+	}
+	h.seen = totals
+	return counts
+}
+
+func TestQueryLimits(t *testing.T) {
+	h := newTestHandler(t, 3, 300)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string // part of the error, if any
+		reads int64  // product lists read
+	}{
+		{"within the limits", `{ products(limit: 20) { name stock { totalQuantity } } }`, "", 1},
+		{"at the complexity limit", `{ products(limit: 100) { name } }`, "", 1},
+		{"too deep", `{ products(limit: 1) { stock { warehouses { quantity } } } }`, `Field "quantity" has depth 4 that exceeds max depth 3`, 0},
+		{"too deep through a fragment", `{ products(limit: 1) { ...p } } fragment p on Product { stock { warehouses { quantity } } }`, "exceeds max depth 3", 0},
+		{"too complex", `{ products(limit: 100) { name sku stock { totalQuantity } } }`, "query complexity 401 exceeds the limit of 300", 0},
+		// The budget is shared by root fields, so the first one fits in it
+		{"too complex together", `{ a: products(limit: 100) { name sku } b: products(limit: 100) { name sku } }`, "query complexity 402 exceeds the limit of 300", 1},
+	}
+	for _, tt := range tests {
+		h.queries(t)
+		_, errs := h.query(t, tt.query)
+		switch {
+		case tt.want == "" && len(errs) != 0:
+			t.Errorf("%s: %v", tt.name, errs)
+		case tt.want != "" && (len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), tt.want)):
+			t.Errorf("%s: errors %q, want %q", tt.name, errs, tt.want)
+		}
+		// Fields over the limits are rejected before they read anything
+		if queries := h.queries(t); queries["products"] != tt.reads {
+			t.Errorf("%s: ran %v, want %d product queries", tt.name, queries, tt.reads)
+		}
+	}
+
+	// Zero disables the limits
+	h = newTestHandler(t, 0, 0)
+	if _, errs := h.query(t, `{ products(limit: 100) { name sku stock { warehouses { quantity } } } }`); len(errs) != 0 {
+		t.Errorf("without limits: %v", errs)
+	}
+}
+
+func TestQueriesAreBatched(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, 0, 0)
+	user, err := h.users.CreateUser(ctx, "graphql@example.com", "GraphQL", "us-east", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if err := h.carts.AddToCart(ctx, user.ID, id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		results int
+	}{
+		{"products and their stock", `{ products(limit: 5) { id stock { totalQuantity warehouses { warehouseId } } } }`, 5},
+		{"cart items, their products and stock", `{ cart(userId: "` + strconv.FormatInt(user.ID, 10) + `") { items { product { name stock { totalQuantity } } } } }`, 3},
+	}
+	for _, tt := range tests {
+		h.queries(t)
+		data, errs := h.query(t, tt.query)
+		if len(errs) != 0 {
+			t.Fatalf("%s: %v", tt.name, errs)
+		}
+		if n := strings.Count(string(data), `"totalQuantity"`); n != tt.results {
+			t.Errorf("%s: %d results, want %d: %s", tt.name, n, tt.results, data)
+		}
+		// One query for the products and one for all of their stock,
+		// whatever the number of rows
+		queries := h.queries(t)
+		if queries["products"] != 1 || queries["inventory"] != 1 {
+			t.Errorf("%s: ran %v, want one query each on products and inventory", tt.name, queries)
+		}
+	}
+}
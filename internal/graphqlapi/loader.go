@@ -0,0 +1,123 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+)
+
+// loader batches lookups by ID within one request. Resolvers queue the IDs
+// they may need as they are created, e.g. the products of every cart item,
+// and the first Load fetches everything queued in one query. Later Loads
+// are answered from what it fetched.
+type loader[T any] struct {
+	fetch func(ctx context.Context, ids []int64) (map[int64]T, error)
+	// notFound is returned for IDs fetch has no entry for; when nil they
+	// get the zero value
+	notFound error
+
+	mu      sync.Mutex
+	queued  map[int64]bool
+	results map[int64]T
+	errs    map[int64]error
+}
+
+func newLoader[T any](fetch func(ctx context.Context, ids []int64) (map[int64]T, error), notFound error) *loader[T] {
+	return &loader[T]{
+		fetch:    fetch,
+		notFound: notFound,
+		queued:   make(map[int64]bool),
+		results:  make(map[int64]T),
+		errs:     make(map[int64]error),
+	}
+}
+
+// Queue adds ids to the next batch
+func (l *loader[T]) Queue(ids ...int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		if _, done := l.results[id]; !done && l.errs[id] == nil {
+			l.queued[id] = true
+		}
+	}
+}
+
+// Load returns the value for id, fetching it with everything queued so far.
+// Concurrent Loads wait for the batch in flight rather than start their own.
+func (l *loader[T]) Load(ctx context.Context, id int64) (T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if v, ok := l.results[id]; ok {
+		return v, nil
+	}
+	if err := l.errs[id]; err != nil {
+		var zero T
+		return zero, err
+	}
+
+	l.queued[id] = true
+	ids := make([]int64, 0, len(l.queued))
+	for queued := range l.queued {
+		ids = append(ids, queued)
+	}
+	l.queued = make(map[int64]bool)
+
+	results, err := l.fetch(ctx, ids)
+	for _, batched := range ids {
+		switch v, ok := results[batched]; {
+		case err != nil:
+			l.errs[batched] = err
+		case ok:
+			l.results[batched] = v
+		case l.notFound != nil:
+			l.errs[batched] = l.notFound
+		default:
+			// e.g. orders without items
+			var zero T
+			l.results[batched] = zero
+		}
+	}
+
+	if err := l.errs[id]; err != nil {
+		var zero T
+		return zero, err
+	}
+	return l.results[id], nil
+}
+
+// loaders are the batch loaders of one request
+type loaders struct {
+	products   *loader[models.Product]
+	stock      *loader[*models.ProductStock]
+	orderItems *loader[[]models.OrderItem]
+}
+
+// newLoaders creates the loaders of a request. What one loader fetches is
+// queued in the next: the products of order items, and the stock of
+// products, so each level of a query is read in one batch.
+func newLoaders(ps *services.ProductService, is *services.InventoryService, os *services.OrderService) *loaders {
+	l := &loaders{
+		stock: newLoader(is.GetProductsStock, nil),
+	}
+	l.products = newLoader(func(ctx context.Context, ids []int64) (map[int64]models.Product, error) {
+		products, err := ps.GetProductsByIDs(ctx, ids)
+		for id := range products {
+			l.stock.Queue(id)
+		}
+		return products, err
+	}, errProductNotFound)
+	l.orderItems = newLoader(func(ctx context.Context, ids []int64) (map[int64][]models.OrderItem, error) {
+		items, err := os.ListOrderItems(ctx, ids)
+		for _, lines := range items {
+			for _, item := range lines {
+				l.products.Queue(item.ProductID)
+			}
+		}
+		return items, err
+	}, nil)
+	return l
+}
@@ -0,0 +1,282 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// maxLimit caps the limit argument of list queries
+const maxLimit = 100
+
+var errProductNotFound = fmt.Errorf("product not found")
+
+// resolver is the Query type
+type resolver struct {
+	products   *services.ProductService
+	carts      *services.CartService
+	orders     *services.OrderService
+	complexity *complexity
+}
+
+func (r *resolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	if err := r.complexity.charge(ctx, "product", 1); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	product, err := l.products.Load(ctx, id)
+	if err == errProductNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{loaders: l, product: product}, nil
+}
+
+func (r *resolver) Products(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*productResolver, error) {
+	limit, err := listLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if args.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if err := r.complexity.charge(ctx, "products", limit); err != nil {
+		return nil, err
+	}
+
+	products, err := r.products.ListProducts(ctx, limit, int(args.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	resolvers := make([]*productResolver, len(products))
+	for i := range products {
+		l.stock.Queue(products[i].ID)
+		resolvers[i] = &productResolver{loaders: l, product: products[i]}
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) Cart(ctx context.Context, args struct{ UserID *graphql.ID }) (*cartResolver, error) {
+	if err := r.complexity.charge(ctx, "cart", 1); err != nil {
+		return nil, err
+	}
+	uid, err := userID(ctx, args.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.carts.GetCart(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	for _, item := range cart.Items {
+		l.products.Queue(item.ProductID)
+	}
+	return &cartResolver{loaders: l, cart: cart}, nil
+}
+
+func (r *resolver) Orders(ctx context.Context, args struct {
+	UserID *graphql.ID
+	Limit  int32
+}) ([]*orderResolver, error) {
+	limit, err := listLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.complexity.charge(ctx, "orders", limit); err != nil {
+		return nil, err
+	}
+	uid, err := userID(ctx, args.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := r.orders.ListUserOrders(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	l := loadersFrom(ctx)
+	resolvers := make([]*orderResolver, len(orders))
+	for i := range orders {
+		l.orderItems.Queue(orders[i].ID)
+		resolvers[i] = &orderResolver{loaders: l, order: orders[i]}
+	}
+	return resolvers, nil
+}
+
+type productResolver struct {
+	loaders *loaders
+	product models.Product
+}
+
+func (r *productResolver) ID() graphql.ID      { return formatID(r.product.ID) }
+func (r *productResolver) Name() string        { return r.product.Name }
+func (r *productResolver) Description() string { return r.product.Description }
+func (r *productResolver) Price() string       { return r.product.Price.String() }
+func (r *productResolver) Currency() string    { return r.product.Currency }
+func (r *productResolver) Category() string    { return r.product.Category }
+func (r *productResolver) SKU() string         { return r.product.SKU }
+
+func (r *productResolver) Stock(ctx context.Context) (*stockResolver, error) {
+	stock, err := r.loaders.stock.Load(ctx, r.product.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &stockResolver{stock: stock}, nil
+}
+
+type stockResolver struct {
+	stock *models.ProductStock
+}
+
+func (r *stockResolver) TotalQuantity() int32 { return int32(r.stock.TotalQuantity) }
+
+func (r *stockResolver) Warehouses() []*warehouseStockResolver {
+	resolvers := make([]*warehouseStockResolver, len(r.stock.Warehouses))
+	for i := range r.stock.Warehouses {
+		resolvers[i] = &warehouseStockResolver{inventory: r.stock.Warehouses[i]}
+	}
+	return resolvers
+}
+
+type warehouseStockResolver struct {
+	inventory models.Inventory
+}
+
+func (r *warehouseStockResolver) WarehouseID() string { return r.inventory.WarehouseID }
+func (r *warehouseStockResolver) Quantity() int32     { return int32(r.inventory.Quantity) }
+
+type cartResolver struct {
+	loaders *loaders
+	cart    *models.CartResponse
+}
+
+func (r *cartResolver) ID() graphql.ID   { return formatID(r.cart.Cart.ID) }
+func (r *cartResolver) Total() string    { return r.cart.Total.String() }
+func (r *cartResolver) Currency() string { return r.cart.Currency }
+
+func (r *cartResolver) Items() []*cartItemResolver {
+	resolvers := make([]*cartItemResolver, len(r.cart.Items))
+	for i := range r.cart.Items {
+		resolvers[i] = &cartItemResolver{loaders: r.loaders, item: r.cart.Items[i]}
+	}
+	return resolvers
+}
+
+type cartItemResolver struct {
+	loaders *loaders
+	item    models.CartItem
+}
+
+func (r *cartItemResolver) ID() graphql.ID        { return formatID(r.item.ID) }
+func (r *cartItemResolver) ProductID() graphql.ID { return formatID(r.item.ProductID) }
+func (r *cartItemResolver) Quantity() int32       { return int32(r.item.Quantity) }
+
+func (r *cartItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	return loadProduct(ctx, r.loaders, r.item.ProductID)
+}
+
+type orderResolver struct {
+	loaders *loaders
+	order   models.Order
+}
+
+func (r *orderResolver) ID() graphql.ID        { return formatID(r.order.ID) }
+func (r *orderResolver) Status() string        { return r.order.Status }
+func (r *orderResolver) PaymentMethod() string { return r.order.PaymentMethod }
+func (r *orderResolver) TotalAmount() string   { return r.order.TotalAmount.String() }
+func (r *orderResolver) Currency() string      { return r.order.Currency }
+func (r *orderResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.order.CreatedAt}
+}
+
+func (r *orderResolver) Items(ctx context.Context) ([]*orderItemResolver, error) {
+	items, err := r.loaders.orderItems.Load(ctx, r.order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*orderItemResolver, len(items))
+	for i := range items {
+		resolvers[i] = &orderItemResolver{loaders: r.loaders, item: items[i]}
+	}
+	return resolvers, nil
+}
+
+type orderItemResolver struct {
+	loaders *loaders
+	item    models.OrderItem
+}
+
+func (r *orderItemResolver) ID() graphql.ID        { return formatID(r.item.ID) }
+func (r *orderItemResolver) ProductID() graphql.ID { return formatID(r.item.ProductID) }
+func (r *orderItemResolver) Quantity() int32       { return int32(r.item.Quantity) }
+func (r *orderItemResolver) Price() string         { return r.item.Price.String() }
+func (r *orderItemResolver) WarehouseID() string   { return r.item.WarehouseID }
+
+func (r *orderItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	return loadProduct(ctx, r.loaders, r.item.ProductID)
+}
+
+func loadProduct(ctx context.Context, l *loaders, id int64) (*productResolver, error) {
+	product, err := l.products.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{loaders: l, product: product}, nil
+}
+
+// userID is the user a cart or orders query acts for: the caller when
+// authenticated, else the userId argument
+func userID(ctx context.Context, requested *graphql.ID) (int64, error) {
+	if principal := auth.FromContext(ctx); principal != nil {
+		return principal.UserID, nil
+	}
+	if requested == nil {
+		return 0, fmt.Errorf("userId is required")
+	}
+	return parseID(*requested)
+}
+
+// listLimit checks a limit argument; the schema supplies its default
+func listLimit(limit int32) (int, error) {
+	if limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return int(limit), nil
+}
+
+func parseID(id graphql.ID) (int64, error) {
+	parsed, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid id %q", id)
+	}
+	return parsed, nil
+}
+
+func formatID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
@@ -0,0 +1,59 @@
+package graphqlapi
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// metricsTracer records the duration of every resolver that can do work,
+// i.e. takes a context or arguments or can fail, as graphql_resolver_duration.
+// Plain getters and introspection are not recorded.
+type metricsTracer struct {
+	metrics *metrics.AppMetrics
+}
+
+var _ tracer.Tracer = metricsTracer{}
+
+func (t metricsTracer) TraceQuery(ctx context.Context, queryString, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, tracer.QueryFinishFunc) {
+	start := time.Now()
+	return ctx, func(errs []*errors.QueryError) {
+		if len(errs) > 0 {
+			log.Printf("[GRAPHQL] %s - %d errors, first: %s - %dms", operationLabel(operationName), len(errs), errs[0].Message, time.Since(start).Milliseconds())
+		}
+	}
+}
+
+func (t metricsTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, tracer.FieldFinishFunc) {
+	if trivial || strings.HasPrefix(typeName, "__") {
+		return ctx, func(*errors.QueryError) {}
+	}
+
+	start := time.Now()
+	return ctx, func(err *errors.QueryError) {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		attrs := t.metrics.WithServiceName([]attribute.KeyValue{
+			attribute.String("graphql.field", typeName+"."+fieldName),
+			attribute.String("status", status),
+		})
+		t.metrics.GraphQLResolverDuration.Record(ctx, float64(time.Since(start).Milliseconds()), metric.WithAttributes(attrs...))
+	}
+}
+
+func operationLabel(operationName string) string {
+	if operationName == "" {
+		return "anonymous"
+	}
+	return operationName
+}
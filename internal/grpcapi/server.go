@@ -0,0 +1,228 @@
+// Package grpcapi serves the ecommerce.v1 gRPC API (proto/ecommerce/v1) for
+// internal services. It calls the same services as the REST handlers and
+// authenticates with the same sessions and API keys.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=ecommercev1 --go_opt=paths=source_relative --go-grpc_out=ecommercev1 --go-grpc_opt=paths=source_relative ecommerce/v1/ecommerce.proto
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/grpcapi/ecommercev1"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Resolver turns a credential into a principal, like the HTTP
+// Authenticate middleware's resolvers
+type Resolver func(ctx context.Context, credential string) (*auth.Principal, error)
+
+// Server implements the ecommerce.v1 services
+type Server struct {
+	ecommercev1.UnimplementedProductServiceServer
+	ecommercev1.UnimplementedCartServiceServer
+	ecommercev1.UnimplementedOrderServiceServer
+	ecommercev1.UnimplementedUserServiceServer
+
+	products     *services.ProductService
+	carts        *services.CartService
+	orders       *services.OrderService
+	users        *services.UserService
+	audit        func(ctx context.Context, entry models.AuditEntry)
+	baseCurrency string
+}
+
+// NewServer creates a gRPC server with the ecommerce.v1 services registered.
+// RPCs are measured by the OTel stats handler (rpc.server.duration) and
+// authenticated from "authorization" metadata with sessions or apiKeys.
+func NewServer(
+	ps *services.ProductService,
+	cs *services.CartService,
+	os *services.OrderService,
+	us *services.UserService,
+	sessions, apiKeys Resolver,
+	audit func(ctx context.Context, entry models.AuditEntry),
+	baseCurrency string,
+) *grpc.Server {
+	s := &Server{
+		products:     ps,
+		carts:        cs,
+		orders:       os,
+		users:        us,
+		audit:        audit,
+		baseCurrency: baseCurrency,
+	}
+
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			requestIDInterceptor,
+			routingInterceptor,
+			loggingInterceptor,
+			authInterceptor(sessions, apiKeys),
+		),
+	)
+	ecommercev1.RegisterProductServiceServer(server, s)
+	ecommercev1.RegisterCartServiceServer(server, s)
+	ecommercev1.RegisterOrderServiceServer(server, s)
+	ecommercev1.RegisterUserServiceServer(server, s)
+	return server
+}
+
+// requestIDInterceptor stores the caller's x-request-id, or a new one, in
+// the context under the key the HTTP middleware uses
+func requestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := firstMetadata(ctx, "x-request-id")
+	if requestID == "" {
+		requestID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+	return handler(context.WithValue(ctx, "request_id", requestID), req)
+}
+
+// routingInterceptor lets the call's reads go to a database replica until
+// it writes, like the HTTP read routing middleware
+func routingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(db.WithRouting(ctx), req)
+}
+
+// loggingInterceptor logs every call like the HTTP metrics middleware does
+func loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	log.Printf("[GRPC] %s - %s - %dms", info.FullMethod, status.Code(err), time.Since(start).Milliseconds())
+	return resp, err
+}
+
+// authInterceptor resolves "authorization" metadata ("Bearer <token>" or
+// "ApiKey <key>") into a principal. Calls without it are anonymous.
+func authInterceptor(sessions, apiKeys Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		header := firstMetadata(ctx, "authorization")
+		if header == "" {
+			return handler(ctx, req)
+		}
+
+		scheme, credential, _ := strings.Cut(header, " ")
+		var resolve Resolver
+		switch scheme {
+		case "Bearer":
+			resolve = sessions
+		case "ApiKey":
+			resolve = apiKeys
+		}
+		if resolve == nil || credential == "" {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+		}
+		principal, err := resolve(ctx, credential)
+		if err != nil {
+			if err.Error() == "invalid session" || err.Error() == "invalid api key" {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return handler(auth.WithPrincipal(ctx, principal), req)
+	}
+}
+
+// require checks that the caller has perm and audits the decision, like
+// the HTTP RequirePermission middleware
+func (s *Server) require(ctx context.Context, perm auth.Permission, target string) error {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	return s.authorize(ctx, principal, perm, target)
+}
+
+// authorizeUser lets a user act on their own account, and staff with
+// users:manage on anyone's. API keys need users:manage among their scopes
+// for their owner's account too.
+func (s *Server) authorizeUser(ctx context.Context, userID int64, target string) error {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if principal.UserID == userID && principal.APIKeyID == 0 {
+		return nil
+	}
+	return s.authorize(ctx, principal, auth.PermUsersManage, target)
+}
+
+func (s *Server) authorize(ctx context.Context, principal *auth.Principal, perm auth.Permission, target string) error {
+	allowed := principal.Can(perm)
+	entry := models.AuditEntry{
+		ActorUserID: &principal.UserID,
+		ActorRole:   principal.Role,
+		Action:      string(perm),
+		Target:      target,
+		Decision:    auth.DecisionDenied,
+	}
+	if allowed {
+		entry.Decision = auth.DecisionAllowed
+	}
+	if requestID, ok := ctx.Value("request_id").(string); ok {
+		entry.RequestID = requestID
+	}
+	s.audit(ctx, entry)
+
+	if !allowed {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// userID is the user a cart or order call acts for: the caller when
+// authenticated, else the user_id in the request
+func userID(ctx context.Context, requested int64) (int64, error) {
+	if principal := auth.FromContext(ctx); principal != nil {
+		return principal.UserID, nil
+	}
+	if requested <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	return requested, nil
+}
+
+// toStatus maps service errors to gRPC status codes the way the REST
+// handlers map them to HTTP status codes
+func toStatus(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		return status.Error(codes.NotFound, msg)
+	case msg == "user already exists", msg == "email already in use":
+		return status.Error(codes.AlreadyExists, msg)
+	case strings.HasPrefix(msg, "insufficient stock"):
+		return status.Error(codes.FailedPrecondition, msg)
+	case msg == "cart is empty", msg == "invalid email", msg == "name is required",
+		strings.Contains(msg, "must be at most"), strings.Contains(msg, "must be at least"),
+		strings.HasPrefix(msg, "unsupported currency"), strings.HasSuffix(msg, "is not a shipping address"),
+		strings.HasPrefix(msg, "invalid status"):
+		return status.Error(codes.InvalidArgument, msg)
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/grpcapi/ecommercev1"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/services"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// resolver resolves the credentials in principals, failing on "broken"
+func resolver(principals map[string]*auth.Principal, invalid string) Resolver {
+	return func(ctx context.Context, credential string) (*auth.Principal, error) {
+		if credential == "broken" {
+			return nil, errors.New("database is down")
+		}
+		if principal, ok := principals[credential]; ok {
+			return principal, nil
+		}
+		return nil, errors.New(invalid)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	m := testutil.NewMetrics(t)
+	users := services.NewUserService(database, m, events.NewOutbox(m))
+	alice, err := users.CreateUser(ctx, "alice@example.com", "Alice", "us-east", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := users.CreateUser(ctx, "bob@example.com", "Bob", "us-east", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := resolver(map[string]*auth.Principal{
+		"alice":   {UserID: alice.ID, SessionID: 1, Role: auth.RoleCustomer},
+		"bob":     {UserID: bob.ID, SessionID: 2, Role: auth.RoleCustomer},
+		"support": {UserID: 99, SessionID: 3, Role: auth.RoleSupport},
+	}, "invalid session")
+	apiKeys := resolver(map[string]*auth.Principal{
+		"alice-key": {UserID: alice.ID, Role: auth.RoleCustomer, APIKeyID: 1},
+		"admin-key": {UserID: 98, Role: auth.RoleAdmin, APIKeyID: 2, Scopes: []auth.Permission{auth.PermUsersManage}},
+	}, "invalid api key")
+	var mu sync.Mutex
+	var audited []models.AuditEntry
+	audit := func(ctx context.Context, entry models.AuditEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		audited = append(audited, entry)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(nil, nil, nil, users, sessions, apiKeys, audit, "USD")
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := ecommercev1.NewUserServiceClient(conn)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          codes.Code
+		audited       string // the decision audited, if any
+	}{
+		{"anonymous", "", codes.Unauthenticated, ""},
+		{"unknown scheme", "Basic alice", codes.Unauthenticated, ""},
+		{"no credential", "Bearer ", codes.Unauthenticated, ""},
+		{"invalid session", "Bearer mallory", codes.Unauthenticated, ""},
+		{"invalid api key", "ApiKey mallory", codes.Unauthenticated, ""},
+		{"resolver failure", "Bearer broken", codes.Internal, ""},
+		{"own session", "Bearer alice", codes.OK, ""},
+		{"other user", "Bearer bob", codes.PermissionDenied, auth.DecisionDenied},
+		{"own key without users:manage", "ApiKey alice-key", codes.PermissionDenied, auth.DecisionDenied},
+		{"support", "Bearer support", codes.OK, auth.DecisionAllowed},
+		{"admin key", "ApiKey admin-key", codes.OK, auth.DecisionAllowed},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		audited = nil
+		mu.Unlock()
+
+		callCtx := metadata.AppendToOutgoingContext(ctx, "x-request-id", "req-1")
+		if tt.authorization != "" {
+			callCtx = metadata.AppendToOutgoingContext(callCtx, "authorization", tt.authorization)
+		}
+		user, err := client.GetUser(callCtx, &ecommercev1.GetUserRequest{Id: alice.ID})
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("%s: %v, want %s", tt.name, err, tt.want)
+			continue
+		}
+		if err == nil && user.GetEmail() != alice.Email {
+			t.Errorf("%s: got user %q", tt.name, user.GetEmail())
+		}
+
+		mu.Lock()
+		entries := audited
+		mu.Unlock()
+		switch {
+		case tt.audited == "" && len(entries) != 0:
+			t.Errorf("%s: audited %+v", tt.name, entries)
+		case tt.audited != "" && len(entries) != 1:
+			t.Errorf("%s: %d audit entries, want 1", tt.name, len(entries))
+		case tt.audited != "":
+			entry := entries[0]
+			if entry.Decision != tt.audited || entry.Action != string(auth.PermUsersManage) || entry.RequestID != "req-1" ||
+				entry.Target != ecommercev1.UserService_GetUser_FullMethodName+" "+strconv.FormatInt(alice.ID, 10) {
+				t.Errorf("%s: audit entry %+v, want %s", tt.name, entry, tt.audited)
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/grpcapi/ecommercev1"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListProducts implements ecommerce.v1.ProductService
+func (s *Server) ListProducts(ctx context.Context, req *ecommercev1.ListProductsRequest) (*ecommercev1.ListProductsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = 20
+	}
+	products, err := s.products.ListProducts(ctx, limit, int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ecommercev1.ListProductsResponse{}
+	for i := range products {
+		resp.Products = append(resp.Products, toProduct(&products[i]))
+	}
+	return resp, nil
+}
+
+// GetProduct implements ecommerce.v1.ProductService
+func (s *Server) GetProduct(ctx context.Context, req *ecommercev1.GetProductRequest) (*ecommercev1.Product, error) {
+	product, err := s.products.GetProduct(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProduct(product), nil
+}
+
+// GetCart implements ecommerce.v1.CartService
+func (s *Server) GetCart(ctx context.Context, req *ecommercev1.GetCartRequest) (*ecommercev1.Cart, error) {
+	uid, err := userID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return s.cart(ctx, uid)
+}
+
+// AddToCart implements ecommerce.v1.CartService and returns the updated cart
+func (s *Server) AddToCart(ctx context.Context, req *ecommercev1.AddToCartRequest) (*ecommercev1.Cart, error) {
+	uid, err := userID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetQuantity() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+	if err := s.carts.AddToCart(ctx, uid, req.GetProductId(), int(req.GetQuantity())); err != nil {
+		return nil, toStatus(err)
+	}
+	return s.cart(ctx, uid)
+}
+
+// RemoveFromCart implements ecommerce.v1.CartService and returns the
+// updated cart
+func (s *Server) RemoveFromCart(ctx context.Context, req *ecommercev1.RemoveFromCartRequest) (*ecommercev1.Cart, error) {
+	uid, err := userID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.carts.RemoveFromCart(ctx, uid, req.GetProductId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return s.cart(ctx, uid)
+}
+
+func (s *Server) cart(ctx context.Context, userID int64) (*ecommercev1.Cart, error) {
+	cart, err := s.carts.GetCart(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ecommercev1.Cart{
+		Total:    cart.Total.String(),
+		Currency: cart.Currency,
+	}
+	if cart.Cart != nil {
+		resp.Id = cart.Cart.ID
+		resp.UserId = cart.Cart.UserID
+	}
+	for _, item := range cart.Items {
+		resp.Items = append(resp.Items, &ecommercev1.CartItem{
+			Id:        item.ID,
+			ProductId: item.ProductID,
+			Quantity:  int32(item.Quantity),
+			CreatedAt: timestamp(item.CreatedAt),
+			UpdatedAt: timestamp(item.UpdatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// CreateOrder implements ecommerce.v1.OrderService, checking out the cart
+func (s *Server) CreateOrder(ctx context.Context, req *ecommercev1.CreateOrderRequest) (*ecommercev1.Order, error) {
+	uid, err := userID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	// Same defaults as the REST handler
+	orderCurrency := req.GetCurrency()
+	if orderCurrency == "" {
+		orderCurrency = s.baseCurrency
+	}
+	paymentMethod := req.GetPaymentMethod()
+	if paymentMethod == "" {
+		paymentMethod = "credit_card"
+	}
+
+	order, err := s.orders.CreateOrder(ctx, uid, paymentMethod, orderCurrency, req.GetRegion(), req.ShippingAddressId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toOrder(order), nil
+}
+
+// GetOrder implements ecommerce.v1.OrderService
+func (s *Server) GetOrder(ctx context.Context, req *ecommercev1.GetOrderRequest) (*ecommercev1.Order, error) {
+	order, err := s.orders.GetOrder(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toOrder(order), nil
+}
+
+// ListOrders implements ecommerce.v1.OrderService
+func (s *Server) ListOrders(ctx context.Context, req *ecommercev1.ListOrdersRequest) (*ecommercev1.ListOrdersResponse, error) {
+	uid, err := userID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.orders.ListUserOrders(ctx, uid)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ecommercev1.ListOrdersResponse{}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, toOrder(&orders[i]))
+	}
+	return resp, nil
+}
+
+// UpdateOrderStatus implements ecommerce.v1.OrderService
+func (s *Server) UpdateOrderStatus(ctx context.Context, req *ecommercev1.UpdateOrderStatusRequest) (*ecommercev1.Order, error) {
+	target := fmt.Sprintf("%s %d", ecommercev1.OrderService_UpdateOrderStatus_FullMethodName, req.GetId())
+	if err := s.require(ctx, auth.PermOrdersUpdateStatus, target); err != nil {
+		return nil, err
+	}
+	if err := s.orders.UpdateOrderStatus(ctx, req.GetId(), req.GetStatus()); err != nil {
+		return nil, toStatus(err)
+	}
+	order, err := s.orders.GetOrder(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toOrder(order), nil
+}
+
+// CreateUser implements ecommerce.v1.UserService
+func (s *Server) CreateUser(ctx context.Context, req *ecommercev1.CreateUserRequest) (*ecommercev1.User, error) {
+	user, err := s.users.CreateUser(ctx, req.GetEmail(), req.GetName(), req.GetRegion(), req.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUser(user), nil
+}
+
+// GetUser implements ecommerce.v1.UserService
+func (s *Server) GetUser(ctx context.Context, req *ecommercev1.GetUserRequest) (*ecommercev1.User, error) {
+	target := fmt.Sprintf("%s %d", ecommercev1.UserService_GetUser_FullMethodName, req.GetId())
+	if err := s.authorizeUser(ctx, req.GetId(), target); err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetUser(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUser(user), nil
+}
+
+// UpdateUser implements ecommerce.v1.UserService
+func (s *Server) UpdateUser(ctx context.Context, req *ecommercev1.UpdateUserRequest) (*ecommercev1.User, error) {
+	target := fmt.Sprintf("%s %d", ecommercev1.UserService_UpdateUser_FullMethodName, req.GetId())
+	if err := s.authorizeUser(ctx, req.GetId(), target); err != nil {
+		return nil, err
+	}
+	user, err := s.users.UpdateUser(ctx, req.GetId(), models.UpdateUserRequest{
+		Email:  req.Email,
+		Name:   req.Name,
+		Region: req.Region,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUser(user), nil
+}
+
+// DeleteUser implements ecommerce.v1.UserService
+func (s *Server) DeleteUser(ctx context.Context, req *ecommercev1.DeleteUserRequest) (*emptypb.Empty, error) {
+	target := fmt.Sprintf("%s %d", ecommercev1.UserService_DeleteUser_FullMethodName, req.GetId())
+	if err := s.authorizeUser(ctx, req.GetId(), target); err != nil {
+		return nil, err
+	}
+	if err := s.users.DeleteUser(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toProduct(p *models.Product) *ecommercev1.Product {
+	return &ecommercev1.Product{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price.String(),
+		Currency:    p.Currency,
+		Category:    p.Category,
+		Sku:         p.SKU,
+		CreatedAt:   timestamp(p.CreatedAt),
+		UpdatedAt:   timestamp(p.UpdatedAt),
+	}
+}
+
+func toOrder(o *models.Order) *ecommercev1.Order {
+	order := &ecommercev1.Order{
+		Id:            o.ID,
+		UserId:        o.UserID,
+		Status:        o.Status,
+		PaymentMethod: o.PaymentMethod,
+		TotalAmount:   o.TotalAmount.String(),
+		Currency:      o.Currency,
+		BaseAmount:    o.BaseAmount.String(),
+		BaseCurrency:  o.BaseCurrency,
+		ExchangeRate:  o.ExchangeRate,
+		CreatedAt:     timestamp(o.CreatedAt),
+		UpdatedAt:     timestamp(o.UpdatedAt),
+	}
+	if a := o.ShippingAddress; a != nil {
+		order.ShippingAddress = &ecommercev1.OrderAddress{
+			RecipientName: a.RecipientName,
+			Line1:         a.Line1,
+			Line2:         a.Line2,
+			City:          a.City,
+			State:         a.State,
+			PostalCode:    a.PostalCode,
+			Country:       a.Country,
+			Phone:         a.Phone,
+		}
+	}
+	for _, item := range o.Items {
+		order.Items = append(order.Items, &ecommercev1.OrderItem{
+			Id:          item.ID,
+			ProductId:   item.ProductID,
+			Quantity:    int32(item.Quantity),
+			Price:       item.Price.String(),
+			WarehouseId: item.WarehouseID,
+		})
+	}
+	return order
+}
+
+func toUser(u *models.User) *ecommercev1.User {
+	return &ecommercev1.User{
+		Id:        u.ID,
+		Email:     u.Email,
+		Name:      u.Name,
+		Region:    u.Region,
+		Role:      u.Role,
+		CreatedAt: timestamp(u.CreatedAt),
+		UpdatedAt: timestamp(u.UpdatedAt),
+	}
+}
+
+// timestamp converts a time, leaving zero times unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,236 @@
+// Package health runs the checks behind the /livez, /readyz and /healthz
+// probes and reports their results as the health_check_status gauge.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// refreshInterval is how often Run re-runs the checks for the gauge
+const refreshInterval = 15 * time.Second
+
+// Overall statuses
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded" // A check that does not gate readiness is failing
+	StatusDown     = "unavailable"
+	StatusDraining = "draining"
+
+	// StatusFailing is the status of a failed check
+	StatusFailing = "failing"
+)
+
+// Check is one health check
+type Check struct {
+	Name string
+	// Ready checks must pass for /readyz; the others only show in /healthz
+	Ready bool
+	Func  func(ctx context.Context) error
+}
+
+// Result is the outcome of one run of a check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	ready     bool
+}
+
+// Report is the body of the probe responses. /readyz lists the failing
+// checks and /healthz?verbose every check.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// Checker runs the registered checks
+type Checker struct {
+	metrics  *metrics.AppMetrics
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []Check
+	// last is the snapshot reported by the health_check_status gauge
+	last []Result
+}
+
+// NewChecker creates a checker that gives every check timeout to complete
+// and registers the health_check_status gauge callback. Call Run to keep
+// the gauge up to date between probes.
+func NewChecker(m *metrics.AppMetrics, timeout time.Duration) (*Checker, error) {
+	c := &Checker{metrics: m, timeout: timeout}
+	if _, err := m.RegisterCallback(c.observe, m.HealthCheckStatus); err != nil {
+		return nil, fmt.Errorf("failed to register health check gauge callback: %w", err)
+	}
+	return c, nil
+}
+
+// Add registers a check
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+}
+
+// SetDraining makes /readyz fail so load balancers stop sending traffic
+// before the server shuts down
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Run runs the checks immediately and then periodically until ctx is
+// cancelled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs every check concurrently, each with the checker's timeout
+func (c *Checker) Check(ctx context.Context) []Result {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	c.last = results
+	c.mu.Unlock()
+	return results
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Func(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		ready:     check.Ready,
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// observe reports the last result of every check
+func (c *Checker) observe(ctx context.Context, o metric.Observer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, result := range c.last {
+		var value int64
+		if result.Status == StatusOK {
+			value = 1
+		}
+		o.ObserveInt64(c.metrics.HealthCheckStatus, value, metric.WithAttributes(c.metrics.WithServiceName([]attribute.KeyValue{
+			attribute.String("check", result.Name),
+			attribute.Bool("ready", result.ready),
+		})...))
+	}
+	return nil
+}
+
+// LiveHandler handles GET /livez. The process is live as long as it can
+// serve requests; dependencies are not checked, so a database outage does
+// not get the app restarted.
+func (c *Checker) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// ReadyHandler handles GET /readyz. It fails while the server drains and
+// when a Ready check fails.
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeReport(w, http.StatusServiceUnavailable, Report{Status: StatusDraining})
+		return
+	}
+
+	report := Report{Status: StatusOK}
+	for _, result := range c.Check(r.Context()) {
+		if result.ready && result.Status != StatusOK {
+			report.Status = StatusDown
+			report.Checks = append(report.Checks, result)
+		}
+	}
+	if report.Status != StatusOK {
+		log.Printf("[HEALTH] Not ready: %d check(s) failing", len(report.Checks))
+		writeReport(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	writeReport(w, http.StatusOK, report)
+}
+
+// HealthHandler handles GET /healthz. It is unavailable when a Ready check
+// fails and degraded when another check fails. ?verbose lists every check
+// with its latency.
+func (c *Checker) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	results := c.Check(r.Context())
+
+	report := Report{Status: StatusOK}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusOK:
+		case result.ready:
+			report.Status = StatusDown
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	if c.draining.Load() && report.Status == StatusOK {
+		report.Status = StatusDraining
+	}
+	if r.URL.Query().Has("verbose") {
+		report.Checks = results
+	}
+
+	status := http.StatusOK
+	if report.Status == StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
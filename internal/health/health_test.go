@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// probe calls handler and returns the status code and report
+func probe(t *testing.T, handler http.HandlerFunc, target string) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", target, nil))
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, report
+}
+
+func TestProbes(t *testing.T) {
+	c, err := NewChecker(testutil.NewMetrics(t), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dbErr, exporterErr atomic.Pointer[error]
+	check := func(p *atomic.Pointer[error]) func(context.Context) error {
+		return func(context.Context) error {
+			if err := p.Load(); err != nil {
+				return *err
+			}
+			return nil
+		}
+	}
+	c.Add(Check{Name: "database", Ready: true, Func: check(&dbErr)})
+	c.Add(Check{Name: "otlp_exporter", Func: check(&exporterErr)})
+	fail := func(p *atomic.Pointer[error], msg string) {
+		err := errors.New(msg)
+		p.Store(&err)
+	}
+
+	tests := []struct {
+		name          string
+		setup         func()
+		ready, health int
+		readyStatus   string
+		healthStatus  string
+	}{
+		{"healthy", func() {}, http.StatusOK, http.StatusOK, StatusOK, StatusOK},
+		{"exporter failing", func() { fail(&exporterErr, "export failed") }, http.StatusOK, http.StatusOK, StatusOK, StatusDegraded},
+		{"database failing", func() { fail(&dbErr, "connection refused") }, http.StatusServiceUnavailable, http.StatusServiceUnavailable, StatusDown, StatusDown},
+		{"draining", func() { dbErr.Store(nil); exporterErr.Store(nil); c.SetDraining() }, http.StatusServiceUnavailable, http.StatusOK, StatusDraining, StatusDraining},
+		{"draining with the database failing", func() { fail(&dbErr, "connection refused") }, http.StatusServiceUnavailable, http.StatusServiceUnavailable, StatusDraining, StatusDown},
+	}
+	for _, tt := range tests {
+		tt.setup()
+		if code, report := probe(t, c.ReadyHandler, "/readyz"); code != tt.ready || report.Status != tt.readyStatus {
+			t.Errorf("%s: /readyz %d %q, want %d %q", tt.name, code, report.Status, tt.ready, tt.readyStatus)
+		}
+		if code, report := probe(t, c.HealthHandler, "/healthz"); code != tt.health || report.Status != tt.healthStatus {
+			t.Errorf("%s: /healthz %d %q, want %d %q", tt.name, code, report.Status, tt.health, tt.healthStatus)
+		}
+		// Liveness ignores dependencies and draining
+		if code, _ := probe(t, c.LiveHandler, "/livez"); code != http.StatusOK {
+			t.Errorf("%s: /livez %d", tt.name, code)
+		}
+	}
+}
+
+func TestReadyListsFailingChecks(t *testing.T) {
+	c, err := NewChecker(testutil.NewMetrics(t), 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(Check{Name: "database", Ready: true, Func: func(context.Context) error { return nil }})
+	c.Add(Check{Name: "schema", Ready: true, Func: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	c.Add(Check{Name: "db_replicas", Func: func(context.Context) error { return errors.New("1 of 1 replicas ejected") }})
+
+	code, report := probe(t, c.ReadyHandler, "/readyz")
+	if code != http.StatusServiceUnavailable || len(report.Checks) != 1 || report.Checks[0].Name != "schema" ||
+		report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("/readyz %d %+v, want only the timed out schema check", code, report)
+	}
+
+	_, report = probe(t, c.HealthHandler, "/healthz?verbose")
+	if len(report.Checks) != 3 {
+		t.Errorf("/healthz?verbose lists %d checks, want 3", len(report.Checks))
+	}
+}
@@ -0,0 +1,160 @@
+// Package lifecycle stops the app's components in the reverse of the order
+// they were started once the process receives SIGINT or SIGTERM, or a
+// component fails, giving each component its own shutdown timeout.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// component is a started component and how to stop it
+type component struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// Manager tracks the started components. Register each component with
+// OnStop, Go or Serve as soon as it has started, so that a failure later in
+// startup still stops everything started before it.
+type Manager struct {
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	stopSignals context.CancelFunc
+
+	mu         sync.Mutex
+	components []component
+	stopOnce   sync.Once
+	stopErr    error
+}
+
+// New creates a manager whose context is cancelled on SIGINT or SIGTERM
+func New() *Manager {
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithCancelCause(signalCtx)
+	return &Manager{ctx: ctx, cancel: cancel, stopSignals: stopSignals}
+}
+
+// Context is cancelled when the process is told to exit or a component
+// fails. Use it for startup work that should be abandoned on SIGTERM.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// OnStop registers a started component. stop gets a context that expires
+// after timeout; Stop moves on to the next component when it does.
+func (m *Manager) OnStop(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, timeout: timeout, stop: stop})
+}
+
+// Go starts a background worker. The worker's context is its own rather
+// than the manager's, so it keeps running while the components started
+// after it stop, and is cancelled when its turn comes. Stopping waits up to
+// timeout for run to return.
+func (m *Manager) Go(name string, timeout time.Duration, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+
+	m.OnStop(name, timeout, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Serve runs a server until shutdown is called on Stop. An error from serve
+// other than http.ErrServerClosed fails the manager, which starts the
+// shutdown.
+func (m *Manager) Serve(name string, timeout time.Duration, serve func() error, shutdown func(ctx context.Context) error) {
+	go func() {
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.Fail(fmt.Errorf("%s failed: %w", name, err))
+		}
+	}()
+	m.OnStop(name, timeout, shutdown)
+}
+
+// Fail cancels the manager's context so that Wait returns err
+func (m *Manager) Fail(err error) {
+	m.cancel(err)
+}
+
+// Wait blocks until the process is told to exit or a component fails, and
+// returns the failure. After it returns, a second SIGINT or SIGTERM kills
+// the process without waiting for Stop.
+func (m *Manager) Wait() error {
+	<-m.ctx.Done()
+	m.stopSignals()
+
+	if err := context.Cause(m.ctx); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	log.Println("[LIFECYCLE] Shutdown signal received")
+	return nil
+}
+
+// Stop stops the components in reverse order of registration, each within
+// its own timeout. A component that does not stop in time is left behind so
+// that the ones started before it, such as telemetry, still get to stop.
+// Stop is safe to call more than once; later calls return the first
+// result.
+func (m *Manager) Stop() error {
+	m.stopOnce.Do(func() {
+		m.cancel(context.Canceled)
+		m.stopSignals()
+
+		m.mu.Lock()
+		components := m.components
+		m.mu.Unlock()
+
+		var errs []error
+		for i := len(components) - 1; i >= 0; i-- {
+			if err := stopComponent(components[i]); err != nil {
+				log.Printf("[LIFECYCLE] Failed to stop %s: %v", components[i].name, err)
+				errs = append(errs, fmt.Errorf("%s: %w", components[i].name, err))
+			}
+		}
+		m.stopErr = errors.Join(errs...)
+	})
+	return m.stopErr
+}
+
+func stopComponent(c component) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		log.Printf("[LIFECYCLE] Stopped %s in %s", c.name, time.Since(start).Round(time.Millisecond))
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("did not stop within %s", c.timeout)
+	}
+}
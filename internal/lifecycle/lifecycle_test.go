@@ -0,0 +1,140 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// recorder records the order components stop in
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) stop(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = append(r.stopped, name)
+}
+
+func (r *recorder) order() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.stopped, ",")
+}
+
+func TestStopOrder(t *testing.T) {
+	m := New()
+	var r recorder
+
+	m.OnStop("telemetry", time.Second, func(ctx context.Context) error {
+		r.stop("telemetry")
+		return nil
+	})
+	workerCtx := make(chan context.Context, 1)
+	m.Go("worker", time.Second, func(ctx context.Context) {
+		workerCtx <- ctx
+		<-ctx.Done()
+		r.stop("worker")
+	})
+	worker := <-workerCtx
+	shutdown := make(chan struct{})
+	m.Serve("server", time.Second, func() error {
+		<-shutdown
+		return http.ErrServerClosed
+	}, func(ctx context.Context) error {
+		// The worker keeps running while the server drains
+		if worker.Err() != nil {
+			t.Error("worker cancelled before the server stopped")
+		}
+		close(shutdown)
+		r.stop("server")
+		return nil
+	})
+
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.order(), "server,worker,telemetry"; got != want {
+		t.Errorf("stopped %s, want %s", got, want)
+	}
+	if m.Context().Err() == nil {
+		t.Error("the manager's context is not cancelled after Stop")
+	}
+	// Stopping again does nothing
+	if err := m.Stop(); err != nil || r.order() != "server,worker,telemetry" {
+		t.Errorf("second Stop: %v, stopped %s", err, r.order())
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	m := New()
+	var r recorder
+
+	m.OnStop("telemetry", time.Second, func(ctx context.Context) error {
+		r.stop("telemetry")
+		return nil
+	})
+	release := make(chan struct{})
+	defer close(release)
+	m.OnStop("stuck", 50*time.Millisecond, func(ctx context.Context) error {
+		<-release // Ignores its context
+		return nil
+	})
+	m.OnStop("failing", time.Second, func(ctx context.Context) error {
+		return errors.New("flush failed")
+	})
+	m.Go("slow worker", 50*time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+	})
+
+	err := m.Stop()
+	for _, want := range []string{
+		"slow worker: ",
+		"failing: flush failed",
+		"stuck: did not stop within 50ms",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Stop = %v, want it to report %q", err, want)
+		}
+	}
+	// Components started earlier still stop
+	if r.order() != "telemetry" {
+		t.Errorf("stopped %q, want telemetry", r.order())
+	}
+	if again := m.Stop(); again != err {
+		t.Errorf("second Stop = %v, want the first result", again)
+	}
+}
+
+func TestServeFailure(t *testing.T) {
+	m := New()
+	defer m.Stop()
+
+	m.Serve("server", time.Second, func() error {
+		return errors.New("address already in use")
+	}, func(ctx context.Context) error { return nil })
+
+	done := make(chan error, 1)
+	go func() { done <- m.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "server failed: address already in use" {
+			t.Errorf("Wait = %v, want the server's failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the server failed")
+	}
+}
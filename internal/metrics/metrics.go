@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/SigNoz/ecommerce-go-app/pkg/config"
@@ -12,6 +13,7 @@ import (
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
@@ -19,9 +21,10 @@ import (
 // AppMetrics holds all application metrics
 type AppMetrics struct {
 	// HTTP Metrics
-	HTTPRequestsTotal   metric.Int64Counter
-	HTTPRequestsErrors  metric.Int64Counter
-	HTTPRequestDuration metric.Float64Histogram
+	HTTPRequestsTotal     metric.Int64Counter
+	HTTPRequestsErrors    metric.Int64Counter
+	HTTPRequestDuration   metric.Float64Histogram
+	HTTPRequestsThrottled metric.Int64Counter
 
 	// Database Metrics
 	DBQueriesTotal  metric.Int64Counter
@@ -31,17 +34,51 @@ type AppMetrics struct {
 	OrdersCreated  metric.Int64Counter
 	ProductsViewed metric.Int64Counter
 	CartItemsCount metric.Int64Gauge
-	InventoryLevel metric.Int64Gauge
+	InventoryLevel metric.Int64ObservableGauge
 	RevenueTotal   metric.Float64Counter
 
+	// Inventory Metrics
+	InventoryLowStock metric.Int64Counter
+
+	// Fulfilment Metrics
+	FulfilmentDuration metric.Float64Histogram
+
 	// Application Metrics
 	ActiveUsersCount metric.Int64Gauge
 	ActiveCartsCount metric.Int64Gauge
 	CacheHits        metric.Int64Counter
 	CacheMisses      metric.Int64Counter
 
+	// Event Metrics
+	EventsPublished  metric.Int64Counter
+	EventsPublishLag metric.Float64Histogram
+
+	// Webhook Metrics
+	WebhookDeliveries       metric.Int64Counter
+	WebhookDeliveryDuration metric.Float64Histogram
+
+	// Auth Metrics
+	AuthAttempts metric.Int64Counter
+
+	// GraphQL Metrics
+	GraphQLResolverDuration metric.Float64Histogram
+
+	// Health Metrics
+	HealthCheckStatus metric.Int64ObservableGauge
+
+	// exporter remembers the outcome of the last metrics export
+	exporter *trackedExporter
+
+	// dbSystem is the database backend, mysql unless SetDBSystem says otherwise
+	dbSystem string
+	// dbInstance names the database server a query on ctx went to
+	dbInstance func(ctx context.Context) string
+
 	// Service name for adding to all metrics
 	serviceName string
+
+	// meter is kept for registering observable instrument callbacks
+	meter metric.Meter
 }
 
 // InitMetrics initializes OpenTelemetry metrics
@@ -124,10 +161,11 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		fmt.Printf("Metrics exporter: Using secure HTTPS connection\n")
 	}
 
-	exporter, err := otlpmetrichttp.New(ctx, exporterOpts...)
+	otlpExporter, err := otlpmetrichttp.New(ctx, exporterOpts...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
 	}
+	exporter := &trackedExporter{Exporter: otlpExporter}
 
 	// Log exporter configuration
 	fmt.Printf("\n=== Metrics Exporter Configuration ===\n")
@@ -184,9 +222,18 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 	// Set global meter provider
 	otel.SetMeterProvider(meterProvider)
 
-	// Get meter
-	meter := meterProvider.Meter(cfg.OTELServiceName)
+	appMetrics, err := NewAppMetrics(meterProvider.Meter(cfg.OTELServiceName), cfg.OTELServiceName, cfg.BaseCurrency)
+	if err != nil {
+		return nil, nil, err
+	}
+	appMetrics.exporter = exporter
+	return appMetrics, meterProvider, nil
+}
 
+// NewAppMetrics creates the application's instruments on meter, with
+// revenue in baseCurrency. InitMetrics uses it with the OTLP meter
+// provider; tests can pass one with a manual reader.
+func NewAppMetrics(meter metric.Meter, serviceName, baseCurrency string) (*AppMetrics, error) {
 	// SigNoz default histogram buckets in milliseconds, expanded to 60s
 	buckets := []float64{2, 4, 6, 8, 10, 50, 100, 200, 400, 800, 1000, 1400, 2000, 5000, 10000, 15000, 20000, 30000, 45000, 60000}
 
@@ -197,7 +244,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create http requests counter: %w", err)
+		return nil, fmt.Errorf("failed to create http requests counter: %w", err)
 	}
 
 	httpRequestsErrors, err := meter.Int64Counter(
@@ -206,7 +253,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create http errors counter: %w", err)
+		return nil, fmt.Errorf("failed to create http errors counter: %w", err)
 	}
 
 	httpRequestDuration, err := meter.Float64Histogram(
@@ -216,7 +263,16 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithExplicitBucketBoundaries(buckets...),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create http duration histogram: %w", err)
+		return nil, fmt.Errorf("failed to create http duration histogram: %w", err)
+	}
+
+	httpRequestsThrottled, err := meter.Int64Counter(
+		"http.server.request.throttled",
+		metric.WithDescription("Total number of HTTP requests rejected by the rate limiter"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http throttled counter: %w", err)
 	}
 
 	// Initialize database metrics
@@ -226,7 +282,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create db queries counter: %w", err)
+		return nil, fmt.Errorf("failed to create db queries counter: %w", err)
 	}
 
 	dbQueryDuration, err := meter.Float64Histogram(
@@ -236,7 +292,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithExplicitBucketBoundaries(buckets...),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create db duration histogram: %w", err)
+		return nil, fmt.Errorf("failed to create db duration histogram: %w", err)
 	}
 
 	// Initialize business metrics
@@ -246,7 +302,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create orders counter: %w", err)
+		return nil, fmt.Errorf("failed to create orders counter: %w", err)
 	}
 
 	productsViewed, err := meter.Int64Counter(
@@ -255,7 +311,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create products viewed counter: %w", err)
+		return nil, fmt.Errorf("failed to create products viewed counter: %w", err)
 	}
 
 	cartItemsCount, err := meter.Int64Gauge(
@@ -264,25 +320,44 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create cart items gauge: %w", err)
+		return nil, fmt.Errorf("failed to create cart items gauge: %w", err)
 	}
 
-	inventoryLevel, err := meter.Int64Gauge(
+	inventoryLevel, err := meter.Int64ObservableGauge(
 		"inventory_level",
 		metric.WithDescription("Current inventory level for products"),
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create inventory gauge: %w", err)
+		return nil, fmt.Errorf("failed to create inventory gauge: %w", err)
+	}
+
+	inventoryLowStock, err := meter.Int64Counter(
+		"inventory_low_stock_total",
+		metric.WithDescription("Number of times a product fell to or below its reorder point in a warehouse"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inventory low stock counter: %w", err)
 	}
 
 	revenueTotal, err := meter.Float64Counter(
 		"revenue_total",
-		metric.WithDescription("Total revenue generated"),
-		metric.WithUnit("USD"),
+		metric.WithDescription("Total revenue generated in the base currency"),
+		metric.WithUnit(baseCurrency),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revenue counter: %w", err)
+	}
+
+	fulfilmentDuration, err := meter.Float64Histogram(
+		"fulfilment_duration_seconds",
+		metric.WithDescription("Time from order creation to shipment (stage=created_to_shipped) and from shipment to delivery (stage=shipped_to_delivered)"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(60, 300, 900, 1800, 3600, 7200, 14400, 28800, 43200, 86400, 172800, 259200, 432000, 604800, 1209600),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create revenue counter: %w", err)
+		return nil, fmt.Errorf("failed to create fulfilment duration histogram: %w", err)
 	}
 
 	// Initialize application metrics
@@ -292,7 +367,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create active users gauge: %w", err)
+		return nil, fmt.Errorf("failed to create active users gauge: %w", err)
 	}
 
 	cacheHits, err := meter.Int64Counter(
@@ -301,7 +376,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create cache hits counter: %w", err)
+		return nil, fmt.Errorf("failed to create cache hits counter: %w", err)
 	}
 
 	cacheMisses, err := meter.Int64Counter(
@@ -310,7 +385,7 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create cache misses counter: %w", err)
+		return nil, fmt.Errorf("failed to create cache misses counter: %w", err)
 	}
 
 	activeCartsCount, err := meter.Int64Gauge(
@@ -319,26 +394,161 @@ func InitMetrics(ctx context.Context, cfg *config.Config) (*AppMetrics, *sdkmetr
 		metric.WithUnit("1"),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create active carts gauge: %w", err)
+		return nil, fmt.Errorf("failed to create active carts gauge: %w", err)
+	}
+
+	// Initialize event metrics
+	eventsPublished, err := meter.Int64Counter(
+		"events_published_total",
+		metric.WithDescription("Total number of domain event deliveries to sinks"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events published counter: %w", err)
+	}
+
+	eventsPublishLag, err := meter.Float64Histogram(
+		"events_publish_lag",
+		metric.WithDescription("Time from an event being written to the outbox until it was published, in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(buckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events publish lag histogram: %w", err)
+	}
+
+	// Initialize webhook metrics
+	webhookDeliveries, err := meter.Int64Counter(
+		"webhook_deliveries_total",
+		metric.WithDescription("Total number of webhook delivery attempts by outcome"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook deliveries counter: %w", err)
+	}
+
+	webhookDeliveryDuration, err := meter.Float64Histogram(
+		"webhook_delivery_duration",
+		metric.WithDescription("Webhook delivery request duration in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(buckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery duration histogram: %w", err)
+	}
+
+	// Initialize auth metrics
+	authAttempts, err := meter.Int64Counter(
+		"auth_attempts_total",
+		metric.WithDescription("Total number of login and password reset attempts by outcome"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth attempts counter: %w", err)
+	}
+
+	// Initialize GraphQL metrics
+	graphqlResolverDuration, err := meter.Float64Histogram(
+		"graphql_resolver_duration",
+		metric.WithDescription("GraphQL field resolver duration in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(buckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create graphql resolver duration histogram: %w", err)
+	}
+
+	// Initialize health metrics
+	healthCheckStatus, err := meter.Int64ObservableGauge(
+		"health_check_status",
+		metric.WithDescription("Result of the last run of each health check: 1 passing, 0 failing"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health check gauge: %w", err)
 	}
 
 	return &AppMetrics{
-		HTTPRequestsTotal:   httpRequestsTotal,
-		HTTPRequestsErrors:  httpRequestsErrors,
-		HTTPRequestDuration: httpRequestDuration,
-		DBQueriesTotal:      dbQueriesTotal,
-		DBQueryDuration:     dbQueryDuration,
-		OrdersCreated:       ordersCreated,
-		ProductsViewed:      productsViewed,
-		CartItemsCount:      cartItemsCount,
-		InventoryLevel:      inventoryLevel,
-		RevenueTotal:        revenueTotal,
-		ActiveUsersCount:    activeUsersCount,
-		ActiveCartsCount:    activeCartsCount,
-		CacheHits:           cacheHits,
-		CacheMisses:         cacheMisses,
-		serviceName:         cfg.OTELServiceName,
-	}, meterProvider, nil
+		HTTPRequestsTotal:       httpRequestsTotal,
+		HTTPRequestsErrors:      httpRequestsErrors,
+		HTTPRequestsThrottled:   httpRequestsThrottled,
+		HTTPRequestDuration:     httpRequestDuration,
+		DBQueriesTotal:          dbQueriesTotal,
+		DBQueryDuration:         dbQueryDuration,
+		OrdersCreated:           ordersCreated,
+		ProductsViewed:          productsViewed,
+		CartItemsCount:          cartItemsCount,
+		InventoryLevel:          inventoryLevel,
+		InventoryLowStock:       inventoryLowStock,
+		FulfilmentDuration:      fulfilmentDuration,
+		RevenueTotal:            revenueTotal,
+		ActiveUsersCount:        activeUsersCount,
+		ActiveCartsCount:        activeCartsCount,
+		CacheHits:               cacheHits,
+		CacheMisses:             cacheMisses,
+		EventsPublished:         eventsPublished,
+		EventsPublishLag:        eventsPublishLag,
+		WebhookDeliveries:       webhookDeliveries,
+		WebhookDeliveryDuration: webhookDeliveryDuration,
+		AuthAttempts:            authAttempts,
+		GraphQLResolverDuration: graphqlResolverDuration,
+		HealthCheckStatus:       healthCheckStatus,
+		serviceName:             serviceName,
+		dbSystem:                "mysql",
+		meter:                   meter,
+	}, nil
+}
+
+// RegisterCallback registers a callback that observes the given observable instruments
+// on every collection
+func (m *AppMetrics) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	return m.meter.RegisterCallback(f, instruments...)
+}
+
+// CheckExport reports the error of the last metrics export, for health
+// checks. It is nil until the first export.
+func (m *AppMetrics) CheckExport(ctx context.Context) error {
+	if m.exporter == nil {
+		return nil
+	}
+	return m.exporter.lastError()
+}
+
+// trackedExporter remembers the outcome of the last export
+type trackedExporter struct {
+	sdkmetric.Exporter
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func (e *trackedExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	err := e.Exporter.Export(ctx, rm)
+	e.mu.Lock()
+	e.lastErr = err
+	e.mu.Unlock()
+	return err
+}
+
+func (e *trackedExporter) lastError() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastErr != nil {
+		return fmt.Errorf("last metrics export failed: %w", e.lastErr)
+	}
+	return nil
+}
+
+// SetDBSystem sets the db.system attribute of query metrics, such as
+// postgresql
+func (m *AppMetrics) SetDBSystem(system string) {
+	m.dbSystem = system
+}
+
+// SetDBInstance sets how RecordDBQuery finds the db.instance a query on
+// ctx went to, such as the replica a request reads from
+func (m *AppMetrics) SetDBInstance(f func(ctx context.Context) string) {
+	m.dbInstance = f
 }
 
 // WithServiceName adds service.name to attributes
@@ -359,9 +569,12 @@ func (m *AppMetrics) RecordDBQuery(ctx context.Context, operation, table, statem
 		attribute.String("db.operation", operation),
 		attribute.String("db.sql.table", table),
 		attribute.String("db.statement", statement),
-		attribute.String("db.system", "mysql"),
+		attribute.String("db.system", m.dbSystem),
 		attribute.String("status", status),
 	}
+	if m.dbInstance != nil {
+		attrs = append(attrs, attribute.String("db.instance", m.dbInstance(ctx)))
+	}
 
 	m.DBQueriesTotal.Add(ctx, 1, metric.WithAttributes(m.WithServiceName(attrs)...))
 	m.DBQueryDuration.Record(ctx, float64(duration), metric.WithAttributes(m.WithServiceName(attrs)...))
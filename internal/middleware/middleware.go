@@ -5,12 +5,18 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // MetricsMiddleware records HTTP request metrics
@@ -22,6 +28,10 @@ func MetricsMiddleware(metrics *metrics.AppMetrics) mux.MiddlewareFunc {
 			// Wrap response writer to capture status code
 			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
+			// Authenticate runs later in the chain; let it report the API key back
+			client := &requestClient{}
+			r = r.WithContext(context.WithValue(r.Context(), requestClientKey{}, client))
+
 			// Call next handler
 			next.ServeHTTP(rw, r)
 
@@ -45,8 +55,12 @@ func MetricsMiddleware(metrics *metrics.AppMetrics) mux.MiddlewareFunc {
 				attribute.Int("http.status_code", rw.statusCode),
 			}
 
-			// Record total requests
-			metrics.HTTPRequestsTotal.Add(ctx, 1, metric.WithAttributes(metrics.WithServiceName(attrs)...))
+			// Record total requests, by API key for machine clients
+			totalAttrs := attrs
+			if client.apiKeyName != "" {
+				totalAttrs = append(totalAttrs[:len(attrs):len(attrs)], attribute.String("api_key.name", client.apiKeyName))
+			}
+			metrics.HTTPRequestsTotal.Add(ctx, 1, metric.WithAttributes(metrics.WithServiceName(totalAttrs)...))
 
 			// Record error requests (4xx, 5xx)
 			if rw.statusCode >= 400 {
@@ -80,6 +94,14 @@ func MetricsMiddleware(metrics *metrics.AppMetrics) mux.MiddlewareFunc {
 	}
 }
 
+// requestClient carries what Authenticate learns about the caller back out
+// to MetricsMiddleware, which runs before it
+type requestClient struct {
+	apiKeyName string
+}
+
+type requestClientKey struct{}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -104,12 +126,113 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// TraceContextMiddleware extracts incoming trace context (traceparent, baggage)
+// into the request context so it propagates to emitted events
+func TraceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ReadRoutingMiddleware lets the request's reads go to a database replica
+// until it writes
+func ReadRoutingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(db.WithRouting(r.Context())))
+	})
+}
+
+// Authenticate resolves "Authorization: Bearer <token>" headers with
+// sessions and "Authorization: ApiKey <key>" headers with apiKeys, and
+// stores the principal in the request context. Requests without the header
+// pass through anonymously; a credential that does not resolve is rejected
+// so clients notice expired sessions and revoked keys.
+func Authenticate(sessions, apiKeys func(ctx context.Context, token string) (*auth.Principal, error)) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" || r.Method == "OPTIONS" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, credential, _ := strings.Cut(header, " ")
+			var resolve func(ctx context.Context, token string) (*auth.Principal, error)
+			switch scheme {
+			case "Bearer":
+				resolve = sessions
+			case "ApiKey":
+				resolve = apiKeys
+			}
+			if resolve == nil || credential == "" {
+				http.Error(w, "invalid authorization header", http.StatusUnauthorized)
+				return
+			}
+			principal, err := resolve(r.Context(), credential)
+			if err != nil {
+				if err.Error() == "invalid session" || err.Error() == "invalid api key" {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if client, ok := r.Context().Value(requestClientKey{}).(*requestClient); ok {
+				client.apiKeyName = principal.APIKeyName
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequirePermission only lets principals whose role grants perm through.
+// Anonymous requests get 401 and principals without the permission 403.
+// Every decision is passed to audit. It can be used on a subrouter
+// (Use) or to wrap a single handler.
+func RequirePermission(perm auth.Permission, audit func(ctx context.Context, entry models.AuditEntry)) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			allowed := principal.Can(perm)
+
+			entry := models.AuditEntry{
+				Action:   string(perm),
+				Target:   r.Method + " " + r.URL.Path,
+				Decision: auth.DecisionDenied,
+			}
+			if allowed {
+				entry.Decision = auth.DecisionAllowed
+			}
+			if principal != nil {
+				entry.ActorUserID = &principal.UserID
+				entry.ActorRole = principal.Role
+			}
+			if requestID, ok := r.Context().Value("request_id").(string); ok {
+				entry.RequestID = requestID
+			}
+			audit(r.Context(), entry)
+
+			switch {
+			case principal == nil:
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+			case !allowed:
+				http.Error(w, "permission denied", http.StatusForbidden)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
 // CORSMiddleware adds CORS headers
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, traceparent, tracestate, baggage")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,305 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// bucketIdleTTL is how long an unused, refilled bucket is kept
+const bucketIdleTTL = 10 * time.Minute
+
+// RateLimit is a token bucket: Rate requests per second on average, with
+// bursts of up to Burst requests. A zero Rate disables limiting.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseRateLimit parses "rate:burst" (e.g. "20:40"), or just "rate" for a
+// burst equal to the rate. "0" disables the limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: rate must be a non-negative number", s)
+	}
+	limit := RateLimit{Rate: rate, Burst: int(math.Ceil(rate))}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burstStr); err != nil || limit.Burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	if limit.Rate > 0 && limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return limit, nil
+}
+
+// RateLimitGroup applies a limit to every path under PathPrefix
+type RateLimitGroup struct {
+	Name       string
+	PathPrefix string
+	Limit      RateLimit
+}
+
+// RateLimiter throttles clients with one token bucket per client and route
+// group. Clients are identified by API key or authenticated user, falling
+// back to the client IP. Requests outside every group are not limited.
+type RateLimiter struct {
+	metrics        *metrics.AppMetrics
+	groups         []RateLimitGroup
+	trustedProxies []*net.IPNet
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a rate limiter. Groups are matched in order, so list
+// longer prefixes first. trustedProxies are IPs or CIDRs whose
+// X-Forwarded-For header is believed.
+func NewRateLimiter(metrics *metrics.AppMetrics, groups []RateLimitGroup, trustedProxies []string) (*RateLimiter, error) {
+	l := &RateLimiter{
+		metrics:   metrics,
+		groups:    groups,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+	for _, p := range trustedProxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			if strings.Contains(p, ":") {
+				p += "/128"
+			} else {
+				p += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		l.trustedProxies = append(l.trustedProxies, network)
+	}
+	return l, nil
+}
+
+// Middleware rejects requests over their group's limit with 429. It must run
+// after authentication so users are limited by account rather than IP.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := l.group(r.URL.Path)
+		if group == nil || group.Limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := group.Name + "|" + l.clientKey(r)
+		allowed, remaining, retryAfter, reset := l.take(key, group.Limit, time.Now())
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(group.Limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			l.reject(w, r, group, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuthFailureMiddleware limits failed authentication by client IP, so that
+// guessing credentials is throttled although Middleware only sees requests
+// that authenticated. It must run before authentication: every 401 takes a
+// token from the client IP's failure bucket for the route group, and
+// requests with credentials from an IP whose bucket is empty get 429
+// without their credentials being checked.
+func (l *RateLimiter) AuthFailureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := l.group(r.URL.Path)
+		if group == nil || group.Limit.Rate <= 0 || r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := group.Name + "|auth_failure|ip:" + l.ClientIP(r)
+		if ok, retryAfter := l.peek(key, group.Limit, time.Now()); !ok {
+			l.reject(w, r, group, retryAfter)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		if rw.statusCode == http.StatusUnauthorized {
+			l.take(key, group.Limit, time.Now())
+		}
+	})
+}
+
+// reject answers 429 and counts the throttled request
+func (l *RateLimiter) reject(w http.ResponseWriter, r *http.Request, group *RateLimitGroup, retryAfter time.Duration) {
+	routePattern := "unknown"
+	if route := mux.CurrentRoute(r); route != nil {
+		if pathTemplate, err := route.GetPathTemplate(); err == nil {
+			routePattern = pathTemplate
+		}
+	}
+	l.metrics.HTTPRequestsThrottled.Add(r.Context(), 1, metric.WithAttributes(l.metrics.WithServiceName([]attribute.KeyValue{
+		attribute.String("http.route", routePattern),
+		attribute.String("rate_limit.group", group.Name),
+	})...))
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// group returns the first group whose prefix matches path
+func (l *RateLimiter) group(path string) *RateLimitGroup {
+	for i := range l.groups {
+		if strings.HasPrefix(path, l.groups[i].PathPrefix) {
+			return &l.groups[i]
+		}
+	}
+	return nil
+}
+
+// clientKey identifies the caller: the API key or authenticated user, else
+// the client IP
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		if principal.APIKeyID != 0 {
+			return "key:" + strconv.FormatInt(principal.APIKeyID, 10)
+		}
+		return "user:" + strconv.FormatInt(principal.UserID, 10)
+	}
+	return "ip:" + l.ClientIP(r)
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only
+// believed when the request comes from a trusted proxy; the client is the
+// right-most address that is not itself a trusted proxy.
+func (l *RateLimiter) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trusted(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		if !l.trusted(addr) {
+			return addr
+		}
+		host = addr
+	}
+	return host
+}
+
+// trusted reports whether addr is a trusted proxy
+func (l *RateLimiter) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// take removes a token from the key's bucket if one is available. It returns
+// whether the request is allowed, the whole tokens left, the wait until the
+// next token and the wait until the bucket is full again.
+func (l *RateLimiter) take(key string, limit RateLimit, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, limit, now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	var retryAfter time.Duration
+	if b.tokens < 1 {
+		retryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	reset := secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return allowed, int(b.tokens), retryAfter, reset
+}
+
+// peek reports whether the key's bucket has a token, without taking it, and
+// otherwise the wait until it has one
+func (l *RateLimiter) peek(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, limit, now)
+	if b.tokens >= 1 {
+		return true, 0
+	}
+	return false, secondsToDuration((1 - b.tokens) / limit.Rate)
+}
+
+// refill returns the key's bucket topped up for the time since its last
+// use. l.mu must be held.
+func (l *RateLimiter) refill(key string, limit RateLimit, now time.Time) *bucket {
+	if now.Sub(l.lastSweep) > bucketIdleTTL {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	return b
+}
+
+// sweep drops buckets that have been idle long enough to be full again
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ceilSeconds rounds a duration up to whole seconds for headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
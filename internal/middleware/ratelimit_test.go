@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func newTestLimiter(t *testing.T, limit RateLimit, trustedProxies ...string) *RateLimiter {
+	t.Helper()
+	l, err := NewRateLimiter(testutil.NewMetrics(t), []RateLimitGroup{
+		{Name: "auth", PathPrefix: "/api/v1/auth", Limit: limit},
+		{Name: "api", PathPrefix: "/api/v1", Limit: RateLimit{}},
+	}, trustedProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RateLimit
+	}{
+		{"20:40", RateLimit{Rate: 20, Burst: 40}},
+		{"5", RateLimit{Rate: 5, Burst: 5}},
+		{"0.5", RateLimit{Rate: 0.5, Burst: 1}},
+		{"0", RateLimit{}},
+		{" 2:3 ", RateLimit{Rate: 2, Burst: 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "fast", "-1", "5:0", "5:x"} {
+		if _, err := ParseRateLimit(in); err == nil {
+			t.Errorf("ParseRateLimit(%q) succeeded", in)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	l := newTestLimiter(t, RateLimit{})
+	limit := RateLimit{Rate: 2, Burst: 3}
+	now := time.Unix(1_700_000_000, 0)
+
+	// A new bucket allows a burst, then runs dry
+	for i, wantRemaining := range []int{2, 1, 0} {
+		allowed, remaining, _, _ := l.take("k", limit, now)
+		if !allowed || remaining != wantRemaining {
+			t.Fatalf("request %d: allowed=%v remaining=%d, want allowed with %d left", i+1, allowed, remaining, wantRemaining)
+		}
+	}
+	allowed, remaining, retryAfter, reset := l.take("k", limit, now)
+	if allowed || remaining != 0 {
+		t.Fatalf("request over the burst: allowed=%v remaining=%d", allowed, remaining)
+	}
+	// One token comes back every 1/rate seconds; a full bucket takes burst/rate
+	if retryAfter != 500*time.Millisecond || reset != 1500*time.Millisecond {
+		t.Errorf("retryAfter=%s reset=%s, want 500ms and 1.5s", retryAfter, reset)
+	}
+
+	// Half a second later one request goes through
+	allowed, remaining, _, _ = l.take("k", limit, now.Add(500*time.Millisecond))
+	if !allowed || remaining != 0 {
+		t.Errorf("after refill: allowed=%v remaining=%d, want allowed with 0 left", allowed, remaining)
+	}
+
+	// Refilling never exceeds the burst
+	allowed, remaining, retryAfter, reset = l.take("k", limit, now.Add(time.Hour))
+	if !allowed || remaining != 2 || retryAfter != 0 || reset != 500*time.Millisecond {
+		t.Errorf("after an hour: allowed=%v remaining=%d retryAfter=%s reset=%s", allowed, remaining, retryAfter, reset)
+	}
+
+	// Buckets are per key
+	if allowed, _, _, _ := l.take("other", limit, now); !allowed {
+		t.Error("a different key was limited")
+	}
+
+	// Peeking does not take tokens
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.peek("peek", limit, now); !ok {
+			t.Fatal("peek on a full bucket failed")
+		}
+	}
+	if _, remaining, _, _ := l.take("peek", limit, now); remaining != 2 {
+		t.Errorf("remaining after peeks = %d, want 2", remaining)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	l := newTestLimiter(t, RateLimit{}, "10.0.0.1", "192.168.0.0/16", "::1")
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "203.0.113.7:5123", nil, "203.0.113.7"},
+		{"untrusted peer's header is ignored", "203.0.113.7:5123", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:80", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed left-most entry", "10.0.0.1:80", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:80", []string{"198.51.100.1, 192.168.1.5"}, "198.51.100.1"},
+		{"several headers", "10.0.0.1:80", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"only proxies", "10.0.0.1:80", []string{"192.168.1.5"}, "192.168.1.5"},
+		{"no header", "10.0.0.1:80", nil, "10.0.0.1"},
+		{"empty entries", "10.0.0.1:80", []string{" , 198.51.100.1 , "}, "198.51.100.1"},
+		{"ipv6 proxy", "[::1]:80", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"no port", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/v1/products", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, v := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := l.ClientIP(r); got != tt.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewRateLimiter(nil, nil, []string{"not-an-ip"}); err == nil {
+		t.Error("NewRateLimiter accepted an invalid trusted proxy")
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	l := newTestLimiter(t, RateLimit{Rate: 0.5, Burst: 2})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, nil)
+		r.RemoteAddr = "203.0.113.7:5123"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		code       int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{http.StatusOK, "1", "2", ""},
+		{http.StatusOK, "0", "4", "2"},
+		{http.StatusTooManyRequests, "0", "4", "2"},
+	}
+	for i, tt := range tests {
+		w := do("/api/v1/auth/login")
+		if w.Code != tt.code {
+			t.Fatalf("request %d: status %d, want %d", i+1, w.Code, tt.code)
+		}
+		for header, want := range map[string]string{
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Reset":     tt.reset,
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("request %d: %s = %q, want %q", i+1, header, got, want)
+			}
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != tt.retryAfter {
+			t.Errorf("request %d: Retry-After = %q, want %q", i+1, w.Header().Get("Retry-After"), tt.retryAfter)
+		}
+	}
+
+	// Routes outside a limited group get no headers
+	if w := do("/api/v1/products"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("unlimited group: status %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestRateLimitByPrincipal(t *testing.T) {
+	l := newTestLimiter(t, RateLimit{Rate: 1, Burst: 1})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(principal *auth.Principal) int {
+		r := httptest.NewRequest("POST", "/api/v1/auth/sessions", nil)
+		r.RemoteAddr = "203.0.113.7:5123"
+		if principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Users behind one address have their own buckets
+	alice, bob := &auth.Principal{UserID: 1}, &auth.Principal{UserID: 2}
+	for _, p := range []*auth.Principal{alice, bob, nil} {
+		if code := do(p); code != http.StatusOK {
+			t.Errorf("first request of %+v: %d", p, code)
+		}
+	}
+	if code := do(alice); code != http.StatusTooManyRequests {
+		t.Errorf("second request of alice: %d, want 429", code)
+	}
+}
+
+func TestAuthFailuresAreLimited(t *testing.T) {
+	l := newTestLimiter(t, RateLimit{Rate: 0.1, Burst: 2})
+	invalid := errors.New("invalid session")
+	var checked int
+	sessions := func(ctx context.Context, token string) (*auth.Principal, error) {
+		checked++
+		if token == "valid" {
+			return &auth.Principal{UserID: 1}, nil
+		}
+		return nil, invalid
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := l.AuthFailureMiddleware(Authenticate(sessions, sessions)(l.Middleware(handler)))
+
+	do := func(token, remoteAddr string) int {
+		r := httptest.NewRequest("GET", "/api/v1/auth/sessions", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Failed guesses never reach Middleware, so only the failure bucket
+	// stops them
+	for i := 0; i < 2; i++ {
+		if code := do("guess", "203.0.113.7:1"); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: %d, want 401", i+1, code)
+		}
+	}
+	checked = 0
+	if code := do("guess", "203.0.113.7:1"); code != http.StatusTooManyRequests {
+		t.Errorf("third guess: %d, want 429", code)
+	}
+	if checked != 0 {
+		t.Error("credentials were checked for a throttled address")
+	}
+
+	// Other addresses are not affected
+	if code := do("guess", "198.51.100.1:1"); code != http.StatusUnauthorized {
+		t.Errorf("guess from another address: %d, want 401", code)
+	}
+
+	// Successful requests do not count as failures
+	l2 := newTestLimiter(t, RateLimit{Rate: 0.1, Burst: 2})
+	h = l2.AuthFailureMiddleware(Authenticate(sessions, sessions)(handler))
+	for i := 0; i < 5; i++ {
+		if code := do("valid", "203.0.113.7:1"); code != http.StatusOK {
+			t.Fatalf("valid request %d: %d, want 200", i+1, code)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderAddress is the copy of an address taken when an order is placed,
+// so later edits to the address do not change the order
+type OrderAddress struct {
+	AddressID     int64  `json:"address_id,omitempty"`
+	RecipientName string `json:"recipient_name,omitempty"`
+	Line1         string `json:"line1,omitempty"`
+	Line2         string `json:"line2,omitempty"`
+	City          string `json:"city,omitempty"`
+	State         string `json:"state,omitempty"`
+	PostalCode    string `json:"postal_code,omitempty"`
+	Country       string `json:"country"`
+	Phone         string `json:"phone,omitempty"`
+}
+
+// NewOrderAddress snapshots an address for an order
+func NewOrderAddress(a *Address) *OrderAddress {
+	return &OrderAddress{
+		AddressID:     a.ID,
+		RecipientName: a.RecipientName,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		City:          a.City,
+		State:         a.State,
+		PostalCode:    a.PostalCode,
+		Country:       a.Country,
+		Phone:         a.Phone,
+	}
+}
+
+// Scan implements sql.Scanner for JSON columns
+func (a *OrderAddress) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into OrderAddress", src)
+	}
+}
+
+// Value implements driver.Valuer, storing the address as JSON
+func (a OrderAddress) Value() (driver.Value, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
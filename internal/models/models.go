@@ -7,7 +7,8 @@ type Product struct {
 	ID          int64     `json:"id" db:"id"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
-	Price       float64   `json:"price" db:"price"`
+	Price       Money     `json:"price" db:"price"`
+	Currency    string    `json:"currency"` // Base currency the price is stored in
 	Category    string    `json:"category" db:"category"`
 	SKU         string    `json:"sku" db:"sku"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
@@ -19,7 +20,43 @@ type User struct {
 	ID        int64     `json:"id" db:"id"`
 	Email     string    `json:"email" db:"email"`
 	Name      string    `json:"name" db:"name"`
+	Region    string    `json:"region,omitempty" db:"region"` // Used to route orders to nearby warehouses
+	Role      string    `json:"role" db:"role"`               // customer, support, warehouse or admin
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Address is a shipping or billing address of a user
+type Address struct {
+	ID            int64     `json:"id" db:"id"`
+	UserID        int64     `json:"user_id" db:"user_id"`
+	Kind          string    `json:"kind" db:"kind"` // shipping, billing
+	IsDefault     bool      `json:"is_default" db:"is_default"`
+	RecipientName string    `json:"recipient_name" db:"recipient_name"`
+	Line1         string    `json:"line1" db:"line1"`
+	Line2         string    `json:"line2,omitempty" db:"line2"`
+	City          string    `json:"city" db:"city"`
+	State         string    `json:"state,omitempty" db:"state"`
+	PostalCode    string    `json:"postal_code" db:"postal_code"`
+	Country       string    `json:"country" db:"country"` // ISO 3166-1 alpha-2
+	Phone         string    `json:"phone,omitempty" db:"phone"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AddressRequest represents a request to create or update an address.
+// Omitted fields keep their current value on update.
+type AddressRequest struct {
+	Kind          *string `json:"kind"`
+	IsDefault     *bool   `json:"is_default"`
+	RecipientName *string `json:"recipient_name"`
+	Line1         *string `json:"line1"`
+	Line2         *string `json:"line2"`
+	City          *string `json:"city"`
+	State         *string `json:"state"`
+	PostalCode    *string `json:"postal_code"`
+	Country       *string `json:"country"`
+	Phone         *string `json:"phone"`
 }
 
 // Cart represents a shopping cart
@@ -42,24 +79,91 @@ type CartItem struct {
 
 // Order represents an order
 type Order struct {
-	ID            int64     `json:"id" db:"id"`
-	UserID        int64     `json:"user_id" db:"user_id"`
-	Status        string    `json:"status" db:"status"` // pending, completed, cancelled
-	PaymentMethod string    `json:"payment_method" db:"payment_method"`
-	TotalAmount   float64   `json:"total_amount" db:"total_amount"`
-	Currency      string    `json:"currency" db:"currency"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID              int64         `json:"id" db:"id"`
+	UserID          int64         `json:"user_id" db:"user_id"`
+	Status          string        `json:"status" db:"status"` // pending, completed, cancelled
+	PaymentMethod   string        `json:"payment_method" db:"payment_method"`
+	TotalAmount     Money         `json:"total_amount" db:"total_amount"` // Amount charged, in Currency
+	Currency        string        `json:"currency" db:"currency"`
+	BaseAmount      Money         `json:"base_amount" db:"base_amount"` // Amount in BaseCurrency
+	BaseCurrency    string        `json:"base_currency" db:"base_currency"`
+	ExchangeRate    float64       `json:"exchange_rate" db:"exchange_rate"` // Units of Currency per unit of BaseCurrency
+	ShippingAddress *OrderAddress `json:"shipping_address,omitempty" db:"shipping_address"`
+	Items           []OrderItem   `json:"items,omitempty"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
+	ID          int64     `json:"id" db:"id"`
+	OrderID     int64     `json:"order_id" db:"order_id"`
+	ProductID   int64     `json:"product_id" db:"product_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	Price       Money     `json:"price" db:"price"`
+	WarehouseID string    `json:"warehouse_id,omitempty" db:"warehouse_id"` // Warehouse shipping this line
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Shipment is a parcel of order lines sent from one warehouse
+type Shipment struct {
+	ID             int64          `json:"id" db:"id"`
+	OrderID        int64          `json:"order_id" db:"order_id"`
+	WarehouseID    string         `json:"warehouse_id" db:"warehouse_id"`
+	Carrier        string         `json:"carrier" db:"carrier"`
+	TrackingNumber string         `json:"tracking_number" db:"tracking_number"`
+	Status         string         `json:"status" db:"status"` // in_transit, delivered
+	Items          []ShipmentItem `json:"items"`
+	ShippedAt      time.Time      `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ShipmentItem is an order line packed in a shipment
+type ShipmentItem struct {
+	OrderItemID int64 `json:"order_item_id"`
+	ProductID   int64 `json:"product_id"`
+	Quantity    int   `json:"quantity"`
+}
+
+// CreateShipmentRequest represents a request to ship order lines.
+// When OrderItemIDs is empty, every unshipped line allocated to the
+// warehouse is shipped.
+type CreateShipmentRequest struct {
+	WarehouseID    string  `json:"warehouse_id"`
+	Carrier        string  `json:"carrier"`
+	TrackingNumber string  `json:"tracking_number"`
+	OrderItemIDs   []int64 `json:"order_item_ids"`
+}
+
+// CarrierUpdate is a tracking update posted by a carrier
+type CarrierUpdate struct {
+	TrackingNumber string     `json:"tracking_number"`
+	Status         string     `json:"status"` // in_transit, delivered
+	OccurredAt     *time.Time `json:"occurred_at"`
+}
+
+// Warehouse is a location stock is held in and orders are shipped from
+type Warehouse struct {
 	ID        int64     `json:"id" db:"id"`
-	OrderID   int64     `json:"order_id" db:"order_id"`
-	ProductID int64     `json:"product_id" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	Price     float64   `json:"price" db:"price"`
+	Code      string    `json:"code" db:"code"` // The warehouse_id used by inventory and order items
+	Name      string    `json:"name" db:"name"`
+	Region    string    `json:"region" db:"region"`
+	Priority  int       `json:"priority" db:"priority"` // Lower ships first
+	Active    bool      `json:"active" db:"active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WarehouseRequest represents a request to create or update a warehouse.
+// Omitted fields keep their current value on update.
+type WarehouseRequest struct {
+	Code     string  `json:"code"`
+	Name     *string `json:"name"`
+	Region   *string `json:"region"`
+	Priority *int    `json:"priority"`
+	Active   *bool   `json:"active"`
 }
 
 // Inventory represents product inventory
@@ -72,11 +176,74 @@ type Inventory struct {
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ProductStock is a product's stock across all warehouses
+type ProductStock struct {
+	ProductID     int64       `json:"product_id"`
+	TotalQuantity int         `json:"total_quantity"`
+	Warehouses    []Inventory `json:"warehouses"`
+}
+
+// InventoryMovement is an entry in the inventory ledger
+type InventoryMovement struct {
+	ID             int64     `json:"id" db:"id"`
+	ProductID      int64     `json:"product_id" db:"product_id"`
+	WarehouseID    string    `json:"warehouse_id" db:"warehouse_id"`
+	MovementType   string    `json:"movement_type" db:"movement_type"`
+	QuantityChange int       `json:"quantity_change" db:"quantity_change"`
+	QuantityAfter  int       `json:"quantity_after" db:"quantity_after"`
+	Reason         string    `json:"reason,omitempty" db:"reason"`
+	Actor          string    `json:"actor" db:"actor"`
+	Reference      string    `json:"reference,omitempty" db:"reference"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// StockAdjustmentRequest represents a request to adjust stock in one warehouse.
+// Quantity is the number of units received or damaged, or the counted
+// quantity for a count correction.
+type StockAdjustmentRequest struct {
+	ProductID   int64  `json:"product_id"`
+	WarehouseID string `json:"warehouse_id"`
+	Type        string `json:"type"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Actor       string `json:"actor"`
+}
+
+// StockTransferRequest represents a request to move stock between warehouses
+type StockTransferRequest struct {
+	ProductID       int64  `json:"product_id"`
+	FromWarehouseID string `json:"from_warehouse_id"`
+	ToWarehouseID   string `json:"to_warehouse_id"`
+	Quantity        int    `json:"quantity"`
+	Reason          string `json:"reason"`
+	Actor           string `json:"actor"`
+}
+
+// StockTransfer is the result of a transfer: one movement out and one in
+type StockTransfer struct {
+	Reference string              `json:"reference"`
+	Movements []InventoryMovement `json:"movements"`
+}
+
+// LowStockItem is a product/warehouse pair at or below its reorder point
+type LowStockItem struct {
+	ProductID         int64      `json:"product_id"`
+	ProductName       string     `json:"product_name"`
+	SKU               string     `json:"sku"`
+	WarehouseID       string     `json:"warehouse_id"`
+	Quantity          int        `json:"quantity"`
+	ReorderPoint      int        `json:"reorder_point"`
+	LowStockSince     *time.Time `json:"low_stock_since,omitempty"`
+	DailyVelocity     float64    `json:"daily_velocity"`
+	SuggestedQuantity int        `json:"suggested_reorder_quantity"`
+}
+
 // CartResponse represents a cart with its items
 type CartResponse struct {
-	Cart  *Cart      `json:"cart"`
-	Items []CartItem `json:"items"`
-	Total float64    `json:"total"`
+	Cart     *Cart      `json:"cart"`
+	Items    []CartItem `json:"items"`
+	Total    Money      `json:"total"`
+	Currency string     `json:"currency"`
 }
 
 // AddToCartRequest represents a request to add item to cart
@@ -89,11 +256,135 @@ type AddToCartRequest struct {
 type CreateOrderRequest struct {
 	PaymentMethod string `json:"payment_method"`
 	Currency      string `json:"currency"`
+	Region        string `json:"region"` // Optional; defaults to the user's region
+	// Optional; defaults to the user's default shipping address
+	ShippingAddressID *int64 `json:"shipping_address_id"`
 }
 
-// CreateUserRequest represents a request to create a user
+// CreateUserRequest represents a request to create a user.
+// The user ID is assigned by the server.
 type CreateUserRequest struct {
-	ID    int64  `json:"id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Password string `json:"password"` // Optional; users without a password cannot log in
+}
+
+// UpdateUserRequest represents a request to update a user.
+// Omitted fields keep their current value.
+type UpdateUserRequest struct {
+	Email  *string `json:"email"`
+	Name   *string `json:"name"`
+	Region *string `json:"region"`
+}
+
+// WebhookSubscription is a partner endpoint that receives domain events
+type WebhookSubscription struct {
+	ID         int64     `json:"id" db:"id"`
+	URL        string    `json:"url" db:"url"`
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	Secret     string    `json:"secret,omitempty" db:"secret"` // Only returned when the subscription is created
+	Active     bool      `json:"active" db:"active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to a subscription
+type WebhookDelivery struct {
+	ID             int64      `json:"id" db:"id"`
+	SubscriptionID int64      `json:"subscription_id" db:"subscription_id"`
+	EventID        int64      `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Status         string     `json:"status" db:"status"` // pending, retrying, succeeded, dead
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string     `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CreateWebhookRequest represents a request to create a webhook subscription
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"` // Optional; generated when empty
+}
+
+// Session is a login session of a user
+type Session struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	UserAgent  string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress  string    `json:"ip_address,omitempty" db:"ip_address"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Current    bool      `json:"current"` // Session of the request listing the sessions
+}
+
+// APIKey is a key machine clients authenticate with. It acts for its owner,
+// limited to its scopes.
+type APIKey struct {
+	ID          int64      `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	OwnerUserID int64      `json:"owner_user_id" db:"owner_user_id"`
+	KeyPrefix   string     `json:"key_prefix" db:"key_prefix"`
+	Key         string     `json:"key,omitempty"` // Only returned when the key is created
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"` // Nil for keys that do not expire
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name"`
+	OwnerUserID int64      `json:"owner_user_id"` // Defaults to the caller
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// LoginRequest represents a request to log in with a password
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by a successful login. The token is only
+// returned here; send it as "Authorization: Bearer <token>".
+type LoginResponse struct {
+	Token   string   `json:"token"`
+	Session *Session `json:"session"`
+	User    *User    `json:"user"`
+}
+
+// PasswordResetRequest asks for a password reset token to be sent
+type PasswordResetRequest struct {
 	Email string `json:"email"`
-	Name  string `json:"name"`
+}
+
+// PasswordResetConfirmRequest sets a new password with a reset token
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// UpdateRoleRequest represents a request to change a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// AuditEntry records an access decision on a protected operation
+type AuditEntry struct {
+	ID          int64     `json:"id" db:"id"`
+	ActorUserID *int64    `json:"actor_user_id" db:"actor_user_id"` // Nil for anonymous requests
+	ActorRole   string    `json:"actor_role" db:"actor_role"`
+	Action      string    `json:"action" db:"action"`     // Permission that was checked
+	Target      string    `json:"target" db:"target"`     // e.g. "PUT /api/v1/orders/12/status"
+	Decision    string    `json:"decision" db:"decision"` // allowed, denied
+	RequestID   string    `json:"request_id" db:"request_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// minorUnitsPerMajor is the number of minor units (cents) in one unit of currency.
+// Amounts are stored as DECIMAL(10, 2), so every currency is held with two
+// decimal places, even those that use fewer.
+const minorUnitsPerMajor = 100
+
+// currencyDecimals are the ISO 4217 currencies that do not use two decimal
+// places. Amounts in them are rounded to their own precision; those with
+// three cannot be stored and are rejected.
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Decimals returns the number of decimal places of a currency
+func Decimals(currency string) int {
+	if d, ok := currencyDecimals[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+// Money is an exact monetary amount held as an integer number of minor units.
+//
+// Arithmetic on Money is exact. Whenever a result has to be rounded (parsing
+// more decimals than the currency has, currency conversion), halves are
+// rounded to even, so rounding does not bias sums such as revenue.
+//
+// Money is encoded in JSON as a plain decimal number (e.g. 19.99) and in SQL
+// as a decimal string; the currency lives in a sibling field of the enclosing
+// struct and is not part of either encoding.
+type Money struct {
+	Amount   int64  // Minor units (cents)
+	Currency string // ISO 4217 code
+}
+
+// NewMoney creates a Money value from minor units
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "19.99" into Money
+func ParseMoney(s, currency string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("invalid money amount: %q", s)
+	}
+	minor, err := ratToMinor(r, currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Add returns m + o. It panics when they are in different currencies,
+// including when only one has a currency: start sums with NewMoney(0, c).
+func (m Money) Add(o Money) Money {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("models: adding %s to %s", o.Currency, m.Currency))
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// Convert returns m converted to another currency at the given rate
+// (units of the target currency per unit of m's currency)
+func (m Money) Convert(rate float64, currency string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok || r.Sign() <= 0 {
+		return Money{}, fmt.Errorf("invalid exchange rate: %v", rate)
+	}
+	amount := new(big.Rat).SetFrac64(m.Amount, minorUnitsPerMajor)
+	minor, err := ratToMinor(amount.Mul(amount, r), currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Float64 returns the amount in major units, for reporting (e.g. metrics)
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / minorUnitsPerMajor
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats the amount as a decimal with two places, e.g. "19.99"
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorUnitsPerMajor, amount%minorUnitsPerMajor)
+}
+
+// MarshalJSON encodes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or numeric string, keeping the currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		s = str
+	}
+	parsed, err := ParseMoney(s, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		m.Amount = 0
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	parsed, err := ParseMoney(s, m.Currency)
+	if err != nil {
+		return err
+	}
+	m.Amount = parsed.Amount
+	return nil
+}
+
+// Value implements driver.Valuer, storing the amount as a decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// ratToMinor converts an amount in major units to minor units, rounded
+// half to even at the precision of the currency
+func ratToMinor(r *big.Rat, currency string) (int64, error) {
+	decimals := Decimals(currency)
+	if decimals > 2 {
+		return 0, fmt.Errorf("unsupported currency: %s has %d decimal places", currency, decimals)
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(2-decimals)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetFrac(big.NewInt(minorUnitsPerMajor), unit))
+
+	num := new(big.Int).Abs(scaled.Num())
+	den := scaled.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	switch new(big.Int).Mul(rem, big.NewInt(2)).Cmp(den) {
+	case 1:
+		quo.Add(quo, big.NewInt(1))
+	case 0:
+		if quo.Bit(0) == 1 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if scaled.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	quo.Mul(quo, unit)
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("money amount out of range: %s", r.FloatString(2))
+	}
+	return quo.Int64(), nil
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+	}{
+		{"19.99", "USD", 1999},
+		{"0.1", "USD", 10},
+		{"-5.25", "USD", -525},
+		{"1.005", "USD", 100},   // half to even
+		{"1.015", "USD", 102},   // half to even
+		{"-1.005", "USD", -100}, // half to even
+		{"1.0051", "USD", 101},
+		{"1500", "JPY", 150000},
+		{"1500.5", "JPY", 150000}, // whole yen, half to even
+		{"1501.5", "JPY", 150200},
+		{"1500.51", "JPY", 150100},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in, tt.currency)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", tt.in, tt.currency, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("ParseMoney(%q, %s) = %d %s, want %d", tt.in, tt.currency, got.Amount, got.Currency, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1e400000000"} {
+		if _, err := ParseMoney(in, "USD"); err == nil {
+			t.Errorf("ParseMoney(%q) succeeded", in)
+		}
+	}
+	if _, err := ParseMoney("1.234", "BHD"); err == nil {
+		t.Error("ParseMoney in BHD succeeded; three decimal places cannot be stored")
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := func(s string) Money {
+		m, err := ParseMoney(s, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name string
+		got  Money
+		want string
+	}{
+		{"0.1+0.2", usd("0.1").Add(usd("0.2")), "0.30"},
+		{"negative", usd("-10.50").Add(usd("3.25")), "-7.25"},
+		{"negative result below one", usd("0.10").Add(usd("-0.35")), "-0.25"},
+		{"mul", usd("19.99").Mul(3), "59.97"},
+		{"mul negative", usd("-0.01").Mul(7), "-0.07"},
+		{"mul zero", usd("12.34").Mul(0), "0.00"},
+		{"sum of 0.01 a hundred times", sumOf(usd("0.01"), 100), "1.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.got.String(); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+		if tt.got.Currency != "USD" {
+			t.Errorf("%s currency = %q, want USD", tt.name, tt.got.Currency)
+		}
+	}
+}
+
+func sumOf(m Money, n int) Money {
+	sum := NewMoney(0, m.Currency)
+	for i := 0; i < n; i++ {
+		sum = sum.Add(m)
+	}
+	return sum
+}
+
+func TestMoneyAddCurrencyMismatch(t *testing.T) {
+	for _, tt := range []struct{ a, b Money }{
+		{NewMoney(100, "USD"), NewMoney(100, "EUR")},
+		{Money{}, NewMoney(100, "EUR")},
+		{NewMoney(100, "USD"), Money{Amount: 1}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%+v.Add(%+v) did not panic", tt.a, tt.b)
+				}
+			}()
+			tt.a.Add(tt.b)
+		}()
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		rate     float64
+		currency string
+		want     int64
+	}{
+		{10000, 0.92, "EUR", 9200},
+		{1999, 0.85, "EUR", 1699},    // 16.9915
+		{1, 0.5, "EUR", 0},           // 0.005 rounds to even 0.00
+		{3, 0.5, "EUR", 2},           // 0.015 rounds to even 0.02
+		{-3, 0.5, "EUR", -2},         // -0.015 rounds to even -0.02
+		{1999, 147.5, "JPY", 294900}, // 2948.525 yen rounds to 2949
+		{1000, 0.5, "JPY", 500},
+		{100, 2.5, "JPY", 200},   // 2.5 yen rounds to even 2
+		{100, 3.5, "JPY", 400},   // 3.5 yen rounds to even 4
+		{1, 1390, "KRW", 1400},   // 13.9 won rounds to 14
+		{-100, 2.5, "JPY", -200}, // -2.5 yen rounds to even -2
+	}
+	for _, tt := range tests {
+		got, err := NewMoney(tt.amount, "USD").Convert(tt.rate, tt.currency)
+		if err != nil {
+			t.Errorf("Convert(%d, %v, %s): %v", tt.amount, tt.rate, tt.currency, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("Convert(%d, %v, %s) = %d %s, want %d", tt.amount, tt.rate, tt.currency, got.Amount, got.Currency, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		rate     float64
+		currency string
+	}{
+		{0, "EUR"},
+		{-1, "EUR"},
+		{0.38, "BHD"},
+		{0.31, "KWD"},
+	} {
+		if _, err := NewMoney(100, "USD").Convert(tt.rate, tt.currency); err == nil {
+			t.Errorf("Convert(%v, %s) succeeded", tt.rate, tt.currency)
+		}
+	}
+}
+
+func TestMoneyEncoding(t *testing.T) {
+	m := NewMoney(-1999, "USD")
+	data, err := json.Marshal(m)
+	if err != nil || string(data) != "-19.99" {
+		t.Errorf("Marshal = %s, %v; want -19.99", data, err)
+	}
+
+	for _, in := range []string{`19.99`, `"19.99"`} {
+		got := Money{Currency: "EUR"}
+		if err := json.Unmarshal([]byte(in), &got); err != nil || got != NewMoney(1999, "EUR") {
+			t.Errorf("Unmarshal(%s) = %+v, %v", in, got, err)
+		}
+	}
+
+	for _, src := range []interface{}{[]byte("12.30"), "12.30", 12.3, int64(12)} {
+		got := Money{Currency: "USD"}
+		if err := got.Scan(src); err != nil {
+			t.Errorf("Scan(%v): %v", src, err)
+			continue
+		}
+		want := int64(1230)
+		if _, ok := src.(int64); ok {
+			want = 1200
+		}
+		if got.Amount != want || got.Currency != "USD" {
+			t.Errorf("Scan(%v) = %+v, want %d USD", src, got, want)
+		}
+	}
+
+	if v, err := NewMoney(5, "USD").Value(); err != nil || v != "0.05" {
+		t.Errorf("Value() = %v, %v; want 0.05", v, err)
+	}
+}
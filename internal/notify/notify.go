@@ -0,0 +1,83 @@
+// Package notify delivers messages such as password reset links to users
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+)
+
+// PasswordReset is a password reset link to deliver to a user
+type PasswordReset struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Notifier delivers messages to users.
+//
+// tx is the transaction that stores the token, so implementations that
+// write to the database (like the outbox) commit together with it.
+type Notifier interface {
+	Name() string
+	PasswordReset(ctx context.Context, tx events.Execer, msg PasswordReset) error
+}
+
+// New returns the notifier named by kind: log or outbox
+func New(kind string, outbox *events.Outbox) (Notifier, error) {
+	switch kind {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "outbox":
+		return NewOutboxNotifier(outbox), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier: %s", kind)
+	}
+}
+
+// LogNotifier writes messages to the application log.
+// It logs reset tokens in clear text and is meant for development.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a notifier that logs messages
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Name returns the notifier name
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// PasswordReset logs the reset token
+func (n *LogNotifier) PasswordReset(ctx context.Context, tx events.Execer, msg PasswordReset) error {
+	log.Printf("[NOTIFY] Password reset: user_id=%d, email=%s, token=%s, expires_at=%s",
+		msg.UserID, msg.Email, msg.Token, msg.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// OutboxNotifier publishes messages as notification events for a mailer
+// (or another consumer of the event sinks) to deliver
+type OutboxNotifier struct {
+	outbox *events.Outbox
+}
+
+// NewOutboxNotifier creates a notifier that writes notification events
+func NewOutboxNotifier(outbox *events.Outbox) *OutboxNotifier {
+	return &OutboxNotifier{outbox: outbox}
+}
+
+// Name returns the notifier name
+func (n *OutboxNotifier) Name() string {
+	return "outbox"
+}
+
+// PasswordReset enqueues a notification.password_reset event
+func (n *OutboxNotifier) PasswordReset(ctx context.Context, tx events.Execer, msg PasswordReset) error {
+	return n.outbox.Enqueue(ctx, tx, events.TypeNotificationPasswordReset, events.AggregateUser, msg.UserID, msg)
+}
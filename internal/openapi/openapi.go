@@ -0,0 +1,143 @@
+// Package openapi holds the OpenAPI 3 description of the HTTP API, serves it
+// with a documentation page, and can validate request bodies against it.
+//
+// openapi.json is maintained by hand next to SetupRoutes; the api package
+// tests fail when a route is missing from it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+// Document is the subset of an OpenAPI 3 document the app uses
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// PathItem maps lower-case HTTP methods to operations
+type PathItem map[string]*Operation
+
+// Operation is one method on one path
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// RequestBody describes the body an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema of one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the validator understands
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []interface{}      `json:"enum"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	MinItems   *int               `json:"minItems"`
+}
+
+var (
+	loadOnce sync.Once
+	loaded   *Document
+	loadErr  error
+)
+
+// Load parses the embedded document. The result is shared; do not modify it.
+func Load() (*Document, error) {
+	loadOnce.Do(func() {
+		var doc Document
+		if err := json.Unmarshal(specJSON, &doc); err != nil {
+			loadErr = fmt.Errorf("failed to parse openapi.json: %w", err)
+			return
+		}
+		loaded = &doc
+	})
+	return loaded, loadErr
+}
+
+// MustLoad is Load for callers that cannot continue without the document
+func MustLoad() *Document {
+	doc, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// Operation returns the operation for a method and a mux path template,
+// or nil if the document does not describe it
+func (d *Document) Operation(method, pathTemplate string) *Operation {
+	item, ok := d.Paths[NormalizePath(pathTemplate)]
+	if !ok {
+		return nil
+	}
+	return item[strings.ToLower(method)]
+}
+
+// resolve follows a "#/components/schemas/..." reference
+func (d *Document) resolve(s *Schema) (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", s.Ref)
+	}
+	target, ok := d.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return target, nil
+}
+
+var pathVariablePattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+// NormalizePath turns a mux path template into an OpenAPI path by dropping
+// variable patterns, e.g. "/users/{id:[0-9]+}" becomes "/users/{id}"
+func NormalizePath(pathTemplate string) string {
+	return pathVariablePattern.ReplaceAllString(pathTemplate, "{$1}")
+}
+
+// SpecHandler serves the document as JSON
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(specJSON)
+}
+
+// DocsHandler serves a Swagger UI page for the document
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsHTML)
+}
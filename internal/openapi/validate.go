@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxValidatedBody caps how much of a body is read for validation
+const maxValidatedBody = 1 << 20
+
+// ValidateRequests rejects JSON request bodies that do not match the
+// operation's schema with 400. Routes the document does not describe, and
+// operations without a JSON body, pass through unchecked.
+func ValidateRequests(doc *Document) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			pathTemplate, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op := doc.Operation(r.Method, pathTemplate)
+			if op == nil || op.RequestBody == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			media, ok := op.RequestBody.Content["application/json"]
+			if !ok || media.Schema == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxValidatedBody {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err := doc.ValidateBody(media.Schema, body, op.RequestBody.Required); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Hand the handler the body we consumed
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidateBody checks a JSON body against schema
+func (d *Document) ValidateBody(schema *Schema, body []byte, required bool) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if required {
+			return fmt.Errorf("invalid request body: body is required")
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	if err := d.validate(schema, value, "body"); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// validate checks value, decoded with UseNumber, against schema. path names
+// the value in errors, e.g. "body.items[2].quantity".
+func (d *Document) validate(schema *Schema, value interface{}, path string) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("%s must be one of %s", path, formatEnum(schema.Enum))
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		// Unknown properties are ignored, as the handlers ignore them
+		for name, prop := range schema.Properties {
+			if v, ok := obj[name]; ok {
+				if err := d.validate(prop, v, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fmt.Errorf("%s must have at least %d items", path, *schema.MinItems)
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		length := len([]rune(s))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fmt.Errorf("%s must be at least %d characters", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters", path, *schema.MaxLength)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s must be an RFC 3339 date-time", path)
+			}
+		}
+
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be a %s", path, schema.Type)
+		}
+		if schema.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				return fmt.Errorf("%s must be an integer", path)
+			}
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("%s must be a number", path)
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fmt.Errorf("%s must be at least %v", path, *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return fmt.Errorf("%s must be at most %v", path, *schema.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		values[i] = fmt.Sprint(e)
+	}
+	return strings.Join(values, ", ")
+}
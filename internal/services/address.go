@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+)
+
+// Address kinds
+const (
+	AddressShipping = "shipping"
+	AddressBilling  = "billing"
+)
+
+const addressColumns = "id, user_id, kind, is_default, recipient_name, line1, line2, city, state, postal_code, country, phone, created_at, updated_at"
+
+// AddressService handles user addresses.
+//
+// Each user has at most one default address per kind. The first address of
+// a kind becomes the default, and deleting the default promotes the most
+// recently added remaining address of that kind.
+type AddressService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+}
+
+// NewAddressService creates a new address service
+func NewAddressService(db *db.DB, metrics *metrics.AppMetrics) *AddressService {
+	return &AddressService{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+// ListAddresses returns a user's addresses, defaults first
+func (s *AddressService) ListAddresses(ctx context.Context, userID int64) ([]models.Address, error) {
+	start := time.Now()
+	query := "SELECT " + addressColumns + " FROM addresses WHERE user_id = ? ORDER BY kind DESC, is_default DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "addresses", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := []models.Address{}
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+// GetAddress returns one of a user's addresses
+func (s *AddressService) GetAddress(ctx context.Context, userID, id int64) (*models.Address, error) {
+	return s.getAddress(ctx, s.db, userID, id, false)
+}
+
+// CreateAddress adds an address for a user
+func (s *AddressService) CreateAddress(ctx context.Context, userID int64, req models.AddressRequest) (*models.Address, error) {
+	var address *models.Address
+	err := s.db.Retry(ctx, "CreateAddress", func(ctx context.Context) error {
+		var err error
+		address, err = s.createAddress(ctx, userID, req)
+		return err
+	})
+	return address, err
+}
+
+// createAddress is one attempt of CreateAddress
+func (s *AddressService) createAddress(ctx context.Context, userID int64, req models.AddressRequest) (*models.Address, error) {
+	a := models.Address{UserID: userID, Kind: AddressShipping}
+	if err := applyAddressRequest(&a, req); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user serializes default changes of the user's addresses
+	if err := s.lockUser(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if !a.IsDefault {
+		hasDefault, err := s.hasDefault(ctx, tx, userID, a.Kind, 0)
+		if err != nil {
+			return nil, err
+		}
+		a.IsDefault = !hasDefault
+	}
+	if a.IsDefault {
+		if err := s.clearDefault(ctx, tx, userID, a.Kind); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	query := "INSERT INTO addresses (user_id, kind, is_default, recipient_name, line1, line2, city, state, postal_code, country, phone) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, userID, a.Kind, a.IsDefault, a.RecipientName, a.Line1, a.Line2,
+		a.City, a.State, a.PostalCode, a.Country, a.Phone)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "addresses", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address ID: %w", err)
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "addresses", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	address, err := s.GetAddress(ctx, userID, id)
+	return address, db.AfterCommit(err)
+}
+
+// UpdateAddress changes one of a user's addresses. Making an address the
+// default clears the previous default of its kind; an address cannot stop
+// being the default except by making another one the default.
+func (s *AddressService) UpdateAddress(ctx context.Context, userID, id int64, req models.AddressRequest) (*models.Address, error) {
+	var address *models.Address
+	err := s.db.Retry(ctx, "UpdateAddress", func(ctx context.Context) error {
+		var err error
+		address, err = s.updateAddress(ctx, userID, id, req)
+		return err
+	})
+	return address, err
+}
+
+// updateAddress is one attempt of UpdateAddress
+func (s *AddressService) updateAddress(ctx context.Context, userID, id int64, req models.AddressRequest) (*models.Address, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.lockUser(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	a, err := s.getAddress(ctx, tx, userID, id, true)
+	if err != nil {
+		return nil, err
+	}
+	wasDefault, oldKind := a.IsDefault, a.Kind
+	if err := applyAddressRequest(a, req); err != nil {
+		return nil, err
+	}
+
+	if a.Kind != oldKind && wasDefault {
+		if err := s.promoteDefault(ctx, tx, userID, oldKind, id); err != nil {
+			return nil, err
+		}
+	}
+	if !a.IsDefault {
+		if a.Kind == oldKind {
+			a.IsDefault = wasDefault
+		} else {
+			// Moved to another kind: becomes its default if it has none
+			hasDefault, err := s.hasDefault(ctx, tx, userID, a.Kind, id)
+			if err != nil {
+				return nil, err
+			}
+			a.IsDefault = !hasDefault
+		}
+	}
+	if a.IsDefault {
+		if err := s.clearDefault(ctx, tx, userID, a.Kind); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	query := "UPDATE addresses SET kind = ?, is_default = ?, recipient_name = ?, line1 = ?, line2 = ?, city = ?, state = ?, postal_code = ?, country = ?, phone = ? WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, a.Kind, a.IsDefault, a.RecipientName, a.Line1, a.Line2,
+		a.City, a.State, a.PostalCode, a.Country, a.Phone, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "addresses", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "addresses", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	address, err := s.GetAddress(ctx, userID, id)
+	return address, db.AfterCommit(err)
+}
+
+// DeleteAddress removes one of a user's addresses. Orders keep their copy.
+func (s *AddressService) DeleteAddress(ctx context.Context, userID, id int64) error {
+	return s.db.Retry(ctx, "DeleteAddress", func(ctx context.Context) error {
+		return s.deleteAddress(ctx, userID, id)
+	})
+}
+
+// deleteAddress is one attempt of DeleteAddress
+func (s *AddressService) deleteAddress(ctx context.Context, userID, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.lockUser(ctx, tx, userID); err != nil {
+		return err
+	}
+	a, err := s.getAddress(ctx, tx, userID, id, true)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	query := "DELETE FROM addresses WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "addresses", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	if a.IsDefault {
+		if err := s.promoteDefault(ctx, tx, userID, a.Kind, id); err != nil {
+			return err
+		}
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "addresses", "COMMIT", start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// shippingAddress returns the address an order ships to: the given address,
+// which must belong to the user, or the user's default shipping address.
+// It returns nil when the user has no shipping address.
+func (s *AddressService) shippingAddress(ctx context.Context, q queryRower, userID int64, id *int64) (*models.Address, error) {
+	if id != nil {
+		a, err := s.getAddress(ctx, q, userID, *id, false)
+		if err != nil {
+			if err.Error() == "address not found" {
+				return nil, fmt.Errorf("shipping address not found")
+			}
+			return nil, err
+		}
+		if a.Kind != AddressShipping {
+			return nil, fmt.Errorf("address %d is not a shipping address", a.ID)
+		}
+		return a, nil
+	}
+
+	start := time.Now()
+	query := "SELECT " + addressColumns + " FROM addresses WHERE user_id = ? AND kind = ? AND is_default = TRUE"
+	a, err := scanAddress(q.QueryRowContext(ctx, query, userID, AddressShipping))
+	s.metrics.RecordDBQuery(ctx, "SELECT", "addresses", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping address: %w", err)
+	}
+	return a, nil
+}
+
+// getAddress reads one of a user's addresses, locking it when forUpdate is set
+func (s *AddressService) getAddress(ctx context.Context, q queryRower, userID, id int64, forUpdate bool) (*models.Address, error) {
+	start := time.Now()
+	query := "SELECT " + addressColumns + " FROM addresses WHERE id = ? AND user_id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	a, err := scanAddress(q.QueryRowContext(ctx, query, id, userID))
+	s.metrics.RecordDBQuery(ctx, "SELECT", "addresses", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("address not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	return a, nil
+}
+
+// lockUser locks an active user's row
+func (s *AddressService) lockUser(ctx context.Context, tx *db.Tx, userID int64) error {
+	start := time.Now()
+	query := "SELECT id FROM users WHERE id = ? AND deleted_at IS NULL FOR UPDATE"
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&userID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "users", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return nil
+}
+
+// hasDefault reports whether the user has a default address of kind other than exceptID
+func (s *AddressService) hasDefault(ctx context.Context, tx *db.Tx, userID int64, kind string, exceptID int64) (bool, error) {
+	start := time.Now()
+	query := "SELECT COUNT(*) FROM addresses WHERE user_id = ? AND kind = ? AND is_default = TRUE AND id != ?"
+	var count int
+	err := tx.QueryRowContext(ctx, query, userID, kind, exceptID).Scan(&count)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "addresses", query, start, err == nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get default address: %w", err)
+	}
+	return count > 0, nil
+}
+
+// clearDefault unsets the default address of kind
+func (s *AddressService) clearDefault(ctx context.Context, tx *db.Tx, userID int64, kind string) error {
+	start := time.Now()
+	query := "UPDATE addresses SET is_default = FALSE WHERE user_id = ? AND kind = ? AND is_default = TRUE"
+	_, err := tx.ExecContext(ctx, query, userID, kind)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "addresses", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to clear default address: %w", err)
+	}
+	return nil
+}
+
+// promoteDefault makes the newest address of kind other than exceptID the default
+func (s *AddressService) promoteDefault(ctx context.Context, tx *db.Tx, userID int64, kind string, exceptID int64) error {
+	start := time.Now()
+	selectQuery := "SELECT id FROM addresses WHERE user_id = ? AND kind = ? AND id != ? ORDER BY id DESC LIMIT 1"
+	var id int64
+	err := tx.QueryRowContext(ctx, selectQuery, userID, kind, exceptID).Scan(&id)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "addresses", selectQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to promote default address: %w", err)
+	}
+
+	start = time.Now()
+	query := "UPDATE addresses SET is_default = TRUE WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "addresses", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to promote default address: %w", err)
+	}
+	return nil
+}
+
+// applyAddressRequest copies the fields set in req onto a and validates the result
+func applyAddressRequest(a *models.Address, req models.AddressRequest) error {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = strings.TrimSpace(*src)
+		}
+	}
+	set(&a.Kind, req.Kind)
+	set(&a.RecipientName, req.RecipientName)
+	set(&a.Line1, req.Line1)
+	set(&a.Line2, req.Line2)
+	set(&a.City, req.City)
+	set(&a.State, req.State)
+	set(&a.PostalCode, req.PostalCode)
+	set(&a.Country, req.Country)
+	set(&a.Phone, req.Phone)
+	if req.IsDefault != nil {
+		a.IsDefault = *req.IsDefault
+	}
+	a.Kind = strings.ToLower(a.Kind)
+	a.Country = strings.ToUpper(a.Country)
+
+	if a.Kind != AddressShipping && a.Kind != AddressBilling {
+		return fmt.Errorf("invalid address kind: %s", a.Kind)
+	}
+	if a.RecipientName == "" || a.Line1 == "" || a.City == "" || a.PostalCode == "" || a.Country == "" {
+		return fmt.Errorf("recipient_name, line1, city, postal_code and country are required")
+	}
+	if len(a.Country) != 2 {
+		return fmt.Errorf("country must be a two-letter code")
+	}
+	return nil
+}
+
+// scanAddress scans a row of addressColumns
+func scanAddress(row rowScanner) (*models.Address, error) {
+	var a models.Address
+	err := row.Scan(&a.ID, &a.UserID, &a.Kind, &a.IsDefault, &a.RecipientName, &a.Line1, &a.Line2,
+		&a.City, &a.State, &a.PostalCode, &a.Country, &a.Phone, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+)
+
+const (
+	// apiKeyPrefix starts every key so leaked keys are easy to scan for
+	apiKeyPrefix = "eck_"
+
+	// apiKeyPrefixLength is how much of a key is stored in clear to tell
+	// keys apart in listings
+	apiKeyPrefixLength = 12
+
+	maxAPIKeyNameLength = 100
+)
+
+// APIKeyService manages the API keys machine clients authenticate with.
+//
+// A key acts for its owner: requests made with it get the owner's role, and
+// permission checks also require the permission among the key's scopes. As
+// with sessions only a SHA-256 hash of the key is stored.
+type APIKeyService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(db *db.DB, metrics *metrics.AppMetrics) *APIKeyService {
+	return &APIKeyService{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+// CreateAPIKey creates a key for ownerUserID on behalf of caller. The key
+// itself is only ever returned here.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, caller *auth.Principal, name string, ownerUserID int64, scopes []string, expiresAt *time.Time) (*models.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", maxAPIKeyNameLength)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	start := time.Now()
+	ownerQuery := "SELECT role FROM users WHERE id = ? AND deleted_at IS NULL"
+	var ownerRole string
+	err := s.db.QueryRowContext(ctx, ownerQuery, ownerUserID).Scan(&ownerRole)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "users", ownerQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("owner not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner: %w", err)
+	}
+
+	// A key cannot do more than its owner
+	for _, scope := range scopes {
+		perm := auth.Permission(scope)
+		if !auth.ValidPermission(perm) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !auth.HasPermission(ownerRole, perm) {
+			return nil, fmt.Errorf("owner role %s does not grant scope %s", ownerRole, scope)
+		}
+		// nor than the key that created it
+		if caller != nil && caller.APIKeyID != 0 && !caller.Can(perm) {
+			return nil, fmt.Errorf("calling api key does not grant scope %s", scope)
+		}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scopes: %w", err)
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := apiKeyPrefix + secret
+
+	start = time.Now()
+	query := `
+		INSERT INTO api_keys (name, owner_user_id, key_prefix, key_hash, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := s.db.ExecContext(ctx, query, name, ownerUserID, key[:apiKeyPrefixLength], hashToken(key), scopesJSON, expiresAt)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "api_keys", query, start, err == nil)
+	if err != nil {
+		if db.IsDuplicateEntry(err) {
+			return nil, fmt.Errorf("api key name already exists")
+		}
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key id: %w", err)
+	}
+
+	apiKey, err := s.getAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Key = key
+	log.Printf("[API_KEY] Created api key: id=%d, name=%s, owner_user_id=%d, scopes=%v", id, name, ownerUserID, scopes)
+	return apiKey, nil
+}
+
+// ListAPIKeys returns every key, newest first. Revoked keys are only
+// included when includeRevoked is set.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, includeRevoked bool) ([]models.APIKey, error) {
+	start := time.Now()
+	query := "SELECT " + apiKeyColumns + " FROM api_keys"
+	if !includeRevoked {
+		query += " WHERE revoked_at IS NULL"
+	}
+	query += " ORDER BY id DESC"
+	rows, err := s.db.QueryContext(ctx, query)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "api_keys", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey stops a key from authenticating. Revoking a revoked key is
+// not an error.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	start := time.Now()
+	query := "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?"
+	result, err := s.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "api_keys", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		// Unchanged rows are not counted, so tell "already revoked" apart
+		if _, err := s.getAPIKey(ctx, id); err != nil {
+			return err
+		}
+	}
+	log.Printf("[API_KEY] Revoked api key: id=%d", id)
+	return nil
+}
+
+// Authenticate resolves an API key to a principal acting for the key's
+// owner. It fails with "invalid api key" for unknown, revoked or expired
+// keys.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	// Read from the primary so a just-created credential works at once
+	ctx = db.UsePrimary(ctx)
+	now := time.Now().UTC()
+
+	start := time.Now()
+	query := `
+		SELECT k.id, k.name, k.owner_user_id, u.role, k.scopes, k.last_used_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.owner_user_id
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > ?) AND u.deleted_at IS NULL
+	`
+	var p auth.Principal
+	var scopes []byte
+	var lastUsedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, hashToken(key), now).Scan(&p.APIKeyID, &p.APIKeyName, &p.UserID, &p.Role, &scopes, &lastUsedAt)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "api_keys", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid api key")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if err := json.Unmarshal(scopes, &p.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode api key scopes: %w", err)
+	}
+
+	if !lastUsedAt.Valid || now.Sub(lastUsedAt.Time) > sessionTouchInterval {
+		start = time.Now()
+		touchQuery := "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+		_, err = s.db.ExecContext(ctx, touchQuery, now, p.APIKeyID)
+		s.metrics.RecordDBQuery(ctx, "UPDATE", "api_keys", touchQuery, start, err == nil)
+		if err != nil {
+			// Not worth failing the request over
+			log.Printf("[API_KEY] Failed to update api key last use: api_key_id=%d, error=%v", p.APIKeyID, err)
+		}
+	}
+	return &p, nil
+}
+
+const apiKeyColumns = "id, name, owner_user_id, key_prefix, scopes, expires_at, last_used_at, created_at, revoked_at"
+
+func (s *APIKeyService) getAPIKey(ctx context.Context, id int64) (*models.APIKey, error) {
+	start := time.Now()
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE id = ?"
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, id))
+	s.metrics.RecordDBQuery(ctx, "SELECT", "api_keys", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes []byte
+	if err := row.Scan(&key.ID, &key.Name, &key.OwnerUserID, &key.KeyPrefix, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode api key scopes: %w", err)
+	}
+	return &key, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+)
+
+// AuditService records and lists access decisions on protected operations
+type AuditService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *db.DB, metrics *metrics.AppMetrics) *AuditService {
+	return &AuditService{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+// Record writes an audit entry. A failure is logged rather than returned so
+// the audit log never decides whether a request succeeds.
+func (s *AuditService) Record(ctx context.Context, entry models.AuditEntry) {
+	start := time.Now()
+	query := "INSERT INTO audit_log (actor_user_id, actor_role, action, target, decision, request_id) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := s.db.ExecContext(ctx, query, entry.ActorUserID, entry.ActorRole, entry.Action,
+		truncate(entry.Target, 255), entry.Decision, truncate(entry.RequestID, 64))
+	s.metrics.RecordDBQuery(ctx, "INSERT", "audit_log", query, start, err == nil)
+	if err != nil {
+		log.Printf("[AUDIT] Failed to record entry: action=%s, target=%s, decision=%s, error=%v",
+			entry.Action, entry.Target, entry.Decision, err)
+	}
+}
+
+// ListAuditLog returns the newest audit entries, optionally only those of
+// one actor (actorUserID > 0) or action
+func (s *AuditService) ListAuditLog(ctx context.Context, actorUserID int64, action string, limit int) ([]models.AuditEntry, error) {
+	query := "SELECT id, actor_user_id, actor_role, action, target, decision, request_id, created_at FROM audit_log WHERE 1 = 1"
+	var args []interface{}
+	if actorUserID > 0 {
+		query += " AND actor_user_id = ?"
+		args = append(args, actorUserID)
+	}
+	if action != "" {
+		query += " AND action = ?"
+		args = append(args, action)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "audit_log", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var actorUserID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &actorUserID, &entry.ActorRole, &entry.Action, &entry.Target,
+			&entry.Decision, &entry.RequestID, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if actorUserID.Valid {
+			entry.ActorUserID = &actorUserID.Int64
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	return entries, nil
+}
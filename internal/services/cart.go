@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
 	"github.com/SigNoz/ecommerce-go-app/internal/models"
 	"go.opentelemetry.io/otel/attribute"
@@ -16,28 +17,34 @@ import (
 
 // CartService handles cart-related operations
 type CartService struct {
-	db      *db.DB
-	metrics *metrics.AppMetrics
+	db           *db.DB
+	metrics      *metrics.AppMetrics
+	baseCurrency string
+	outbox       *events.Outbox
 }
 
 // NewCartService creates a new cart service
-func NewCartService(db *db.DB, metrics *metrics.AppMetrics) *CartService {
-	cs := &CartService{
-		db:      db,
-		metrics: metrics,
-	}
-	// Start monitoring active carts
-	go cs.monitorActiveCarts()
-	return cs
+func NewCartService(db *db.DB, metrics *metrics.AppMetrics, baseCurrency string, outbox *events.Outbox) *CartService {
+	return &CartService{
+		db:           db,
+		metrics:      metrics,
+		baseCurrency: baseCurrency,
+		outbox:       outbox,
+	}
 }
 
-// monitorActiveCarts periodically updates active carts count
-func (s *CartService) monitorActiveCarts() {
+// Run updates the active carts count until ctx is cancelled
+func (s *CartService) Run(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		ctx := context.Background()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		query := "SELECT COUNT(DISTINCT c.id) FROM carts c INNER JOIN cart_items ci ON c.id = ci.cart_id"
 		start := time.Now()
 		var count int
@@ -93,6 +100,13 @@ func (s *CartService) GetOrCreateCart(ctx context.Context, userID int64) (*model
 
 // AddToCart adds an item to the cart
 func (s *CartService) AddToCart(ctx context.Context, userID int64, productID int64, quantity int) error {
+	return s.db.Retry(ctx, "AddToCart", func(ctx context.Context) error {
+		return s.addToCart(ctx, userID, productID, quantity)
+	})
+}
+
+// addToCart is one attempt of AddToCart
+func (s *CartService) addToCart(ctx context.Context, userID int64, productID int64, quantity int) error {
 	cart, err := s.GetOrCreateCart(ctx, userID)
 	if err != nil {
 		return err
@@ -108,20 +122,26 @@ func (s *CartService) AddToCart(ctx context.Context, userID int64, productID int
 		return fmt.Errorf("product not found")
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	start := time.Now()
 
 	// Check if item already exists in cart
-	checkQuery := "SELECT id, quantity FROM cart_items WHERE cart_id = ? AND product_id = ?"
+	checkQuery := "SELECT id, quantity FROM cart_items WHERE cart_id = ? AND product_id = ? FOR UPDATE"
 	var existingID int64
 	var existingQty int
-	err = s.db.QueryRowContext(ctx, checkQuery, cart.ID, productID).Scan(&existingID, &existingQty)
+	err = tx.QueryRowContext(ctx, checkQuery, cart.ID, productID).Scan(&existingID, &existingQty)
 	s.metrics.RecordDBQuery(ctx, "SELECT", "cart_items", checkQuery, start, err == nil || err == sql.ErrNoRows)
 
 	if err == sql.ErrNoRows {
 		// Insert new item
 		start = time.Now()
 		insertQuery := "INSERT INTO cart_items (cart_id, product_id, quantity) VALUES (?, ?, ?)"
-		_, err = s.db.ExecContext(ctx, insertQuery, cart.ID, productID, quantity)
+		_, err = tx.ExecContext(ctx, insertQuery, cart.ID, productID, quantity)
 		s.metrics.RecordDBQuery(ctx, "INSERT", "cart_items", insertQuery, start, err == nil)
 		if err != nil {
 			s.metrics.RecordDBQuery(ctx, "INSERT", "cart_items", insertQuery, start, false)
@@ -133,8 +153,8 @@ func (s *CartService) AddToCart(ctx context.Context, userID int64, productID int
 	} else {
 		// Update existing item
 		start = time.Now()
-		updateQuery := "UPDATE cart_items SET quantity = quantity + ?, updated_at = NOW() WHERE id = ?"
-		_, err = s.db.ExecContext(ctx, updateQuery, quantity, existingID)
+		updateQuery := "UPDATE cart_items SET quantity = quantity + ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		_, err = tx.ExecContext(ctx, updateQuery, quantity, existingID)
 		s.metrics.RecordDBQuery(ctx, "UPDATE", "cart_items", updateQuery, start, err == nil)
 		if err != nil {
 			s.metrics.RecordDBQuery(ctx, "UPDATE", "cart_items", updateQuery, start, false)
@@ -142,6 +162,20 @@ func (s *CartService) AddToCart(ctx context.Context, userID int64, productID int
 		}
 	}
 
+	added := events.CartItemAdded{
+		UserID:    userID,
+		CartID:    cart.ID,
+		ProductID: productID,
+		Quantity:  quantity,
+	}
+	if err := s.outbox.Enqueue(ctx, tx, events.TypeCartItemAdded, events.AggregateUser, userID, added); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Update cart items count gauge
 	s.updateCartItemsCount(ctx, cart.ID)
 
@@ -196,24 +230,25 @@ func (s *CartService) GetCart(ctx context.Context, userID int64) (*models.CartRe
 	defer rows.Close()
 
 	var items []models.CartItem
-	var total float64
+	total := models.NewMoney(0, s.baseCurrency)
 	for rows.Next() {
 		var item models.CartItem
-		var price float64
+		price := models.NewMoney(0, s.baseCurrency)
 		if err := rows.Scan(&item.ID, &item.CartID, &item.ProductID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt, &price); err != nil {
 			return nil, fmt.Errorf("failed to scan cart item: %w", err)
 		}
 		items = append(items, item)
-		total += price * float64(item.Quantity)
+		total = total.Add(price.Mul(item.Quantity))
 	}
 
 	// Update cart items count gauge
 	s.updateCartItemsCount(ctx, cart.ID)
 
 	return &models.CartResponse{
-		Cart:  cart,
-		Items: items,
-		Total: total,
+		Cart:     cart,
+		Items:    items,
+		Total:    total,
+		Currency: s.baseCurrency,
 	}, rows.Err()
 }
 
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestServicesEmitEvents drives the cart and order services in a traced
+// request and checks what the dispatcher hands the in-memory publisher
+func TestServicesEmitEvents(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	userID, _ := s.testUser(t, "events@example.com")
+	ids := testProducts(t, database, 1, 10)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	if err := s.carts.AddToCart(ctx, userID, ids[0], 2); err != nil {
+		t.Fatal(err)
+	}
+	order, err := s.orders.CreateOrder(ctx, userID, "credit_card", "USD", "us-east", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.orders.UpdateOrderStatus(ctx, order.ID, "processing"); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := events.NewMemoryPublisher()
+	dispatcher := events.NewDispatcher(database, s.metrics, events.NewPublisherSink("memory", publisher, "ecommerce."))
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(runCtx)
+		close(done)
+	}()
+	// Checkout may also report low stock; wait for the last of ours
+	deadline := time.Now().Add(10 * time.Second)
+	for len(publisher.MessagesFor("ecommerce."+events.TypeOrderStatusChanged)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	tests := []struct {
+		eventType string
+		key       int64
+		check     func(payload []byte) bool
+	}{
+		{events.TypeCartItemAdded, userID, func(payload []byte) bool {
+			var added events.CartItemAdded
+			return json.Unmarshal(payload, &added) == nil && added.UserID == userID && added.ProductID == ids[0] && added.Quantity == 2
+		}},
+		{events.TypeOrderCreated, order.ID, func(payload []byte) bool {
+			var created events.OrderCreated
+			return json.Unmarshal(payload, &created) == nil && created.OrderID == order.ID && created.UserID == userID
+		}},
+		{events.TypeOrderStatusChanged, order.ID, func(payload []byte) bool {
+			var changed events.OrderStatusChanged
+			return json.Unmarshal(payload, &changed) == nil && changed.OrderID == order.ID &&
+				changed.OldStatus == "pending" && changed.NewStatus == "processing"
+		}},
+	}
+	for _, tt := range tests {
+		msgs := publisher.MessagesFor("ecommerce." + tt.eventType)
+		if len(msgs) != 1 {
+			t.Errorf("%s: %d messages, want 1", tt.eventType, len(msgs))
+			continue
+		}
+		msg := msgs[0]
+		if msg.Key != strconv.FormatInt(tt.key, 10) {
+			t.Errorf("%s: key %q, want %d", tt.eventType, msg.Key, tt.key)
+		}
+		if !tt.check(msg.Value) {
+			t.Errorf("%s: unexpected payload %s", tt.eventType, msg.Value)
+		}
+		if got := msg.Headers[events.HeaderEventType]; got != tt.eventType {
+			t.Errorf("%s: %s header %q", tt.eventType, events.HeaderEventType, got)
+		}
+		if got := msg.Headers["traceparent"]; !strings.HasPrefix(got, "00-"+traceID.String()+"-") {
+			t.Errorf("%s: traceparent %q, want the request's trace %s", tt.eventType, got, traceID)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+)
+
+// fulfilmentActor is recorded on the inventory movements made by orders
+const fulfilmentActor = "fulfilment"
+
+// FulfilmentRouter decides which warehouses ship each order line and takes
+// the stock out of them.
+//
+// Active warehouses in the customer's region are preferred, then warehouses
+// by priority. The router first looks for a single warehouse that can ship
+// the whole order; failing that, each line ships from the first warehouse
+// that can fill it, and a line no single warehouse can fill is split across
+// warehouses in preference order.
+type FulfilmentRouter struct {
+	metrics   *metrics.AppMetrics
+	inventory *InventoryService
+}
+
+// NewFulfilmentRouter creates a new fulfilment router
+func NewFulfilmentRouter(metrics *metrics.AppMetrics, inventory *InventoryService) *FulfilmentRouter {
+	return &FulfilmentRouter{
+		metrics: metrics,
+		// Movements go through the inventory service so the ledger,
+		// low stock marks and inventory_level stay in step
+		inventory: inventory,
+	}
+}
+
+// allocatedItem is the part of an order line shipped from one warehouse
+type allocatedItem struct {
+	checkoutItem
+	warehouseID string
+}
+
+// fulfilmentPlan is the outcome of routing an order
+type fulfilmentPlan struct {
+	items    []allocatedItem
+	rows     []inventoryRow // Inventory rows after the sale movements
+	lowStock []inventoryRow // Rows that fell to their reorder point
+}
+
+// warehouses returns the distinct warehouses shipping the plan's items
+func (p *fulfilmentPlan) warehouses() []string {
+	var warehouses []string
+	seen := make(map[string]bool)
+	for _, item := range p.items {
+		if !seen[item.warehouseID] {
+			seen[item.warehouseID] = true
+			warehouses = append(warehouses, item.warehouseID)
+		}
+	}
+	return warehouses
+}
+
+// stockCandidate is a locked inventory row of an active warehouse
+type stockCandidate struct {
+	row      inventoryRow
+	region   string
+	priority int
+}
+
+// Route allocates every line of an order to warehouses, records the sale
+// movements in tx and returns the allocation. It fails with
+// "insufficient stock" if the warehouses cannot ship the order.
+func (r *FulfilmentRouter) Route(ctx context.Context, tx *db.Tx, orderID int64, region string, items []checkoutItem) (*fulfilmentPlan, error) {
+	candidates, err := r.lockCandidates(ctx, tx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Preference order: customer's region, then priority, then code
+	for _, c := range candidates {
+		sort.SliceStable(c, func(i, j int) bool {
+			if inI, inJ := c[i].region == region, c[j].region == region; inI != inJ {
+				return inI
+			}
+			if c[i].priority != c[j].priority {
+				return c[i].priority < c[j].priority
+			}
+			return c[i].row.warehouseID < c[j].row.warehouseID
+		})
+	}
+
+	allocations, err := allocate(items, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &fulfilmentPlan{}
+	reference := fmt.Sprintf("order:%d", orderID)
+	for _, a := range allocations {
+		row := &a.candidate.row
+		_, low, err := r.inventory.applyMovement(ctx, tx, row, MovementSale, -a.item.quantity, "", fulfilmentActor, reference)
+		if err != nil {
+			return nil, err
+		}
+		plan.items = append(plan.items, a.item)
+		plan.rows = append(plan.rows, *row)
+		if low {
+			plan.lowStock = append(plan.lowStock, *row)
+		}
+	}
+	return plan, nil
+}
+
+// Release puts the stock of an order's lines back into the warehouses they
+// were allocated to, e.g. when the order is cancelled
+func (r *FulfilmentRouter) Release(ctx context.Context, tx *db.Tx, orderID int64) (*fulfilmentPlan, error) {
+	start := time.Now()
+	query := "SELECT product_id, quantity, warehouse_id FROM order_items WHERE order_id = ? AND warehouse_id IS NOT NULL ORDER BY id"
+	rows, err := tx.QueryContext(ctx, query, orderID)
+	r.metrics.RecordDBQuery(ctx, "SELECT", "order_items", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order allocation: %w", err)
+	}
+	var items []allocatedItem
+	for rows.Next() {
+		var item allocatedItem
+		if err := rows.Scan(&item.productID, &item.quantity, &item.warehouseID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order allocation: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order allocation: %w", err)
+	}
+
+	plan := &fulfilmentPlan{items: items}
+	reference := fmt.Sprintf("order:%d", orderID)
+	for _, item := range items {
+		row, err := r.inventory.lockInventory(ctx, tx, item.productID, item.warehouseID, true)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := r.inventory.applyMovement(ctx, tx, &row, MovementCancellation, item.quantity, "", fulfilmentActor, reference); err != nil {
+			return nil, err
+		}
+		plan.rows = append(plan.rows, row)
+	}
+	return plan, nil
+}
+
+// Committed updates inventory_level and low stock metrics once the
+// transaction that routed or released an order has committed
+func (r *FulfilmentRouter) Committed(ctx context.Context, plan *fulfilmentPlan) {
+	for _, row := range plan.rows {
+		r.inventory.setLevel(row.productID, row.warehouseID, row.quantity)
+	}
+	for _, row := range plan.lowStock {
+		r.inventory.recordLowStock(ctx, row)
+	}
+}
+
+// lockCandidates locks the in-stock inventory rows of active warehouses for
+// every product in the order, keyed by product
+func (r *FulfilmentRouter) lockCandidates(ctx context.Context, tx *db.Tx, items []checkoutItem) (map[int64][]stockCandidate, error) {
+	placeholders := make([]string, len(items))
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		placeholders[i] = "?"
+		args[i] = item.productID
+	}
+
+	start := time.Now()
+	query := `
+		SELECT i.id, i.product_id, i.warehouse_id, i.quantity, i.low_stock_since, p.reorder_point, w.region, w.priority
+		FROM inventory i
+		JOIN warehouses w ON w.code = i.warehouse_id
+		JOIN products p ON p.id = i.product_id
+		WHERE i.product_id IN (` + strings.Join(placeholders, ", ") + `) AND w.active = TRUE AND i.quantity > 0
+		ORDER BY i.id
+		FOR UPDATE OF i
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	r.metrics.RecordDBQuery(ctx, "SELECT", "inventory", "SELECT ... FROM inventory i JOIN warehouses w ... WHERE i.product_id IN (...) FOR UPDATE OF i", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make(map[int64][]stockCandidate)
+	for rows.Next() {
+		var c stockCandidate
+		if err := rows.Scan(&c.row.id, &c.row.productID, &c.row.warehouseID, &c.row.quantity,
+			&c.row.lowStockSince, &c.row.reorderPoint, &c.region, &c.priority); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		candidates[c.row.productID] = append(candidates[c.row.productID], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+	return candidates, nil
+}
+
+// allocation is one allocated item and the row it is taken from
+type allocation struct {
+	item      allocatedItem
+	candidate *stockCandidate
+}
+
+// allocate assigns order lines to warehouses. Candidates must be in
+// preference order.
+func allocate(items []checkoutItem, candidates map[int64][]stockCandidate) ([]allocation, error) {
+	// Warehouses in overall preference order, taken from the candidate lists
+	var warehouses []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		for _, c := range candidates[item.productID] {
+			if !seen[c.row.warehouseID] {
+				seen[c.row.warehouseID] = true
+				warehouses = append(warehouses, c.row.warehouseID)
+			}
+		}
+	}
+
+	find := func(productID int64, warehouseID string) *stockCandidate {
+		list := candidates[productID]
+		for i := range list {
+			if list[i].row.warehouseID == warehouseID {
+				return &list[i]
+			}
+		}
+		return nil
+	}
+
+	// Single shipment: the first warehouse that can fill every line
+	for _, warehouseID := range warehouses {
+		var allocations []allocation
+		for _, item := range items {
+			c := find(item.productID, warehouseID)
+			if c == nil || c.row.quantity < item.quantity {
+				allocations = nil
+				break
+			}
+			allocations = append(allocations, allocation{
+				item:      allocatedItem{checkoutItem: item, warehouseID: warehouseID},
+				candidate: c,
+			})
+		}
+		if allocations != nil {
+			return allocations, nil
+		}
+	}
+
+	// Split shipment: fill each line from as few warehouses as possible
+	remaining := make(map[*stockCandidate]int)
+	available := func(c *stockCandidate) int {
+		if q, ok := remaining[c]; ok {
+			return q
+		}
+		return c.row.quantity
+	}
+
+	var allocations []allocation
+	for _, item := range items {
+		list := candidates[item.productID]
+
+		filled := false
+		for i := range list {
+			c := &list[i]
+			if available(c) >= item.quantity {
+				remaining[c] = available(c) - item.quantity
+				allocations = append(allocations, allocation{
+					item:      allocatedItem{checkoutItem: item, warehouseID: c.row.warehouseID},
+					candidate: c,
+				})
+				filled = true
+				break
+			}
+		}
+		if filled {
+			continue
+		}
+
+		needed := item.quantity
+		for i := range list {
+			c := &list[i]
+			take := available(c)
+			if take == 0 {
+				continue
+			}
+			if take > needed {
+				take = needed
+			}
+			remaining[c] = available(c) - take
+
+			part := item
+			part.quantity = take
+			allocations = append(allocations, allocation{
+				item:      allocatedItem{checkoutItem: part, warehouseID: c.row.warehouseID},
+				candidate: c,
+			})
+			needed -= take
+			if needed == 0 {
+				break
+			}
+		}
+		if needed > 0 {
+			return nil, fmt.Errorf("insufficient stock for product %d", item.productID)
+		}
+	}
+	return allocations, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/currency"
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// testExec runs statements that set up a test
+func testExec(tb testing.TB, database *db.DB, query string, args ...interface{}) {
+	tb.Helper()
+	if _, err := database.ExecContext(context.Background(), query, args...); err != nil {
+		tb.Fatalf("%s: %v", query, err)
+	}
+}
+
+// testProducts returns the ids of n products, adding products beyond the
+// seed data as needed, each stocked in WH-001 with quantity
+func testProducts(tb testing.TB, database *db.DB, n, quantity int) []int64 {
+	tb.Helper()
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		var count int
+		if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM products").Scan(&count); err != nil {
+			tb.Fatal(err)
+		}
+		if count >= n {
+			break
+		}
+		testExec(tb, database, "INSERT INTO products (name, description, price, category, sku) VALUES (?, ?, ?, ?, ?)",
+			fmt.Sprintf("Test product %d", i), "", "9.99", "Test", fmt.Sprintf("TST-%03d", i))
+	}
+
+	rows, err := database.QueryContext(ctx, "SELECT id FROM products ORDER BY id LIMIT ?", n)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			tb.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	for _, id := range ids {
+		testExec(tb, database, "INSERT INTO inventory (product_id, warehouse_id, quantity) VALUES (?, 'WH-001', ?) ON DUPLICATE KEY UPDATE id = id", id, quantity)
+		testExec(tb, database, "UPDATE inventory SET quantity = ? WHERE product_id = ? AND warehouse_id = 'WH-001'", quantity, id)
+	}
+	return ids
+}
+
+// testServices are the services of an app on a test database
+type testServices struct {
+	db        *db.DB
+	metrics   *metrics.AppMetrics
+	outbox    *events.Outbox
+	users     *UserService
+	carts     *CartService
+	inventory *InventoryService
+	addresses *AddressService
+	orders    *OrderService
+}
+
+func newTestServices(tb testing.TB, database *db.DB, m *metrics.AppMetrics) *testServices {
+	tb.Helper()
+	outbox := events.NewOutbox(m)
+	inventory, err := NewInventoryService(database, m, outbox)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	addresses := NewAddressService(database, m)
+	rates := currency.NewStaticRateProvider("USD", map[string]float64{"EUR": 0.9})
+	return &testServices{
+		db:        database,
+		metrics:   m,
+		outbox:    outbox,
+		users:     NewUserService(database, m, outbox),
+		carts:     NewCartService(database, m, "USD", outbox),
+		inventory: inventory,
+		addresses: addresses,
+		orders:    NewOrderService(database, m, rates, outbox, NewFulfilmentRouter(m, inventory), addresses),
+	}
+}
+
+// testUser creates a user with a cart
+func (s *testServices) testUser(tb testing.TB, email string) (userID, cartID int64) {
+	tb.Helper()
+	ctx := context.Background()
+	user, err := s.users.CreateUser(ctx, email, "Test User", "us-east", "correct horse battery staple")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cart, err := s.carts.GetOrCreateCart(ctx, user.ID)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return user.ID, cart.ID
+}
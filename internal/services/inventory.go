@@ -0,0 +1,768 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Inventory movement types
+const (
+	MovementReceive         = "receive"
+	MovementDamage          = "damage"
+	MovementCountCorrection = "count_correction"
+	MovementTransferOut     = "transfer_out"
+	MovementTransferIn      = "transfer_in"
+	MovementSale            = "sale"
+	MovementCancellation    = "cancellation"
+)
+
+const (
+	inventoryScanInterval = 30 * time.Second
+
+	// defaultMovementActor is recorded when a request names no actor
+	defaultMovementActor = "api"
+
+	// DefaultVelocityDays is the order history window used for sales velocity
+	DefaultVelocityDays = 30
+	// DefaultCoverDays is how many days of sales a suggested reorder should cover
+	DefaultCoverDays = 30
+)
+
+// inventoryKey identifies a product in a warehouse
+type inventoryKey struct {
+	productID   int64
+	warehouseID string
+}
+
+// inventoryRow is an inventory row with its product's reorder point
+type inventoryRow struct {
+	id            int64
+	productID     int64
+	warehouseID   string
+	quantity      int
+	reorderPoint  int
+	lowStockSince sql.NullTime
+}
+
+// InventoryService manages and monitors stock levels across warehouses
+type InventoryService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+	outbox  *events.Outbox
+
+	// levels is the snapshot reported by the inventory_level gauge
+	mu     sync.RWMutex
+	levels map[inventoryKey]int
+}
+
+// NewInventoryService creates a new inventory service and registers the
+// inventory_level gauge callback. Call Run to keep the levels up to date.
+func NewInventoryService(db *db.DB, metrics *metrics.AppMetrics, outbox *events.Outbox) (*InventoryService, error) {
+	s := &InventoryService{
+		db:      db,
+		metrics: metrics,
+		outbox:  outbox,
+		levels:  make(map[inventoryKey]int),
+	}
+
+	if _, err := metrics.RegisterCallback(s.observeLevels, metrics.InventoryLevel); err != nil {
+		return nil, fmt.Errorf("failed to register inventory gauge callback: %w", err)
+	}
+	return s, nil
+}
+
+// observeLevels reports the last scanned level of every product/warehouse pair
+func (s *InventoryService) observeLevels(ctx context.Context, o metric.Observer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, quantity := range s.levels {
+		o.ObserveInt64(s.metrics.InventoryLevel, int64(quantity), metric.WithAttributes(s.metrics.WithServiceName([]attribute.KeyValue{
+			attribute.Int64("product_id", key.productID),
+			attribute.String("warehouse_id", key.warehouseID),
+		})...))
+	}
+	return nil
+}
+
+// Run scans inventory immediately and then periodically until ctx is cancelled
+func (s *InventoryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(inventoryScanInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Scan(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[INVENTORY] Inventory scan failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan refreshes the level snapshot and evaluates reorder points.
+//
+// A product/warehouse pair that drops to or below its reorder point is
+// marked with low_stock_since and an inventory.low event is emitted
+// once; the mark is cleared when stock recovers. The mark lives in the
+// database, so several app instances scanning concurrently emit one event.
+func (s *InventoryService) Scan(ctx context.Context) error {
+	start := time.Now()
+	query := `
+		SELECT i.id, i.product_id, i.warehouse_id, i.quantity, p.reorder_point, i.low_stock_since
+		FROM inventory i
+		INNER JOIN products p ON p.id = i.product_id
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var scanned []inventoryRow
+	for rows.Next() {
+		var r inventoryRow
+		if err := rows.Scan(&r.id, &r.productID, &r.warehouseID, &r.quantity, &r.reorderPoint, &r.lowStockSince); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		scanned = append(scanned, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	levels := make(map[inventoryKey]int, len(scanned))
+	for _, r := range scanned {
+		levels[inventoryKey{r.productID, r.warehouseID}] = r.quantity
+	}
+	s.mu.Lock()
+	s.levels = levels
+	s.mu.Unlock()
+
+	// Clear the mark on pairs that have been restocked
+	start = time.Now()
+	clearQuery := `
+		UPDATE inventory SET low_stock_since = NULL
+		WHERE low_stock_since IS NOT NULL
+		AND quantity > (SELECT reorder_point FROM products WHERE products.id = inventory.product_id)
+	`
+	_, err = s.db.ExecContext(ctx, clearQuery)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "inventory", clearQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to clear low stock marks: %w", err)
+	}
+
+	for _, r := range scanned {
+		if r.quantity > r.reorderPoint || r.lowStockSince.Valid {
+			continue
+		}
+		if err := s.markLowStockOnce(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markLowStockOnce marks a pair found by the scanner as low on stock,
+// unless another scanner or a stock movement got there first
+func (s *InventoryService) markLowStockOnce(ctx context.Context, r inventoryRow) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	marked, err := s.markLowStock(ctx, tx, r)
+	if err != nil || !marked {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit low stock mark: %w", err)
+	}
+	s.recordLowStock(ctx, r)
+	return nil
+}
+
+// markLowStock sets low_stock_since on a pair at or below its reorder point and
+// enqueues inventory.low in tx. It reports false if the pair was already marked.
+func (s *InventoryService) markLowStock(ctx context.Context, tx *db.Tx, r inventoryRow) (bool, error) {
+	start := time.Now()
+	query := "UPDATE inventory SET low_stock_since = ? WHERE id = ? AND low_stock_since IS NULL AND quantity <= ?"
+	result, err := tx.ExecContext(ctx, query, time.Now().UTC(), r.id, r.reorderPoint)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "inventory", query, start, err == nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark low stock: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+
+	err = s.outbox.Enqueue(ctx, tx, events.TypeInventoryLowStock, events.AggregateProduct, r.productID, events.InventoryLowStock{
+		ProductID:    r.productID,
+		WarehouseID:  r.warehouseID,
+		Quantity:     r.quantity,
+		ReorderPoint: r.reorderPoint,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordLowStock logs and counts a committed low stock transition
+func (s *InventoryService) recordLowStock(ctx context.Context, r inventoryRow) {
+	log.Printf("[INVENTORY] Low stock: product_id=%d, warehouse_id=%s, quantity=%d, reorder_point=%d",
+		r.productID, r.warehouseID, r.quantity, r.reorderPoint)
+	s.metrics.InventoryLowStock.Add(ctx, 1, metric.WithAttributes(s.metrics.WithServiceName([]attribute.KeyValue{
+		attribute.Int64("product_id", r.productID),
+		attribute.String("warehouse_id", r.warehouseID),
+	})...))
+}
+
+// setLevel updates the gauge snapshot for one pair
+func (s *InventoryService) setLevel(productID int64, warehouseID string, quantity int) {
+	s.mu.Lock()
+	s.levels[inventoryKey{productID, warehouseID}] = quantity
+	s.mu.Unlock()
+}
+
+// GetLowStock lists product/warehouse pairs at or below their reorder point with
+// a suggested reorder quantity.
+//
+// Sales velocity is the average number of units sold per day over the last
+// velocityDays (cancelled orders excluded), split evenly across the warehouses
+// stocking the product. The suggestion tops stock back up to the reorder point
+// plus coverDays of sales.
+func (s *InventoryService) GetLowStock(ctx context.Context, velocityDays, coverDays int) ([]models.LowStockItem, error) {
+	start := time.Now()
+	query := `
+		SELECT i.product_id, p.name, p.sku, i.warehouse_id, i.quantity, p.reorder_point, i.low_stock_since,
+			(SELECT COUNT(*) FROM inventory i2 WHERE i2.product_id = i.product_id)
+		FROM inventory i
+		INNER JOIN products p ON p.id = i.product_id
+		WHERE i.quantity <= p.reorder_point
+		ORDER BY i.product_id, i.warehouse_id
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get low stock: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.LowStockItem{}
+	warehouses := make(map[int64]int)
+	for rows.Next() {
+		var item models.LowStockItem
+		var since sql.NullTime
+		var warehouseCount int
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.SKU, &item.WarehouseID,
+			&item.Quantity, &item.ReorderPoint, &since, &warehouseCount); err != nil {
+			return nil, fmt.Errorf("failed to scan low stock: %w", err)
+		}
+		if since.Valid {
+			item.LowStockSince = &since.Time
+		}
+		warehouses[item.ProductID] = warehouseCount
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get low stock: %w", err)
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	sold, err := s.unitsSold(ctx, time.Now().UTC().AddDate(0, 0, -velocityDays))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		velocity := float64(sold[item.ProductID]) / float64(velocityDays)
+		if n := warehouses[item.ProductID]; n > 1 {
+			velocity /= float64(n)
+		}
+		item.DailyVelocity = math.Round(velocity*100) / 100
+
+		target := item.ReorderPoint + int(math.Ceil(velocity*float64(coverDays)))
+		if suggested := target - item.Quantity; suggested > 0 {
+			item.SuggestedQuantity = suggested
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].SuggestedQuantity > items[j].SuggestedQuantity
+	})
+	return items, nil
+}
+
+// unitsSold returns the units sold per product since the given time
+func (s *InventoryService) unitsSold(ctx context.Context, since time.Time) (map[int64]int, error) {
+	start := time.Now()
+	query := `
+		SELECT oi.product_id, SUM(oi.quantity)
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id
+		WHERE o.created_at >= ? AND o.status <> 'cancelled'
+		GROUP BY oi.product_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, since)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "order_items", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales velocity: %w", err)
+	}
+	defer rows.Close()
+
+	sold := make(map[int64]int)
+	for rows.Next() {
+		var productID int64
+		var quantity int
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan sales velocity: %w", err)
+		}
+		sold[productID] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sales velocity: %w", err)
+	}
+	return sold, nil
+}
+
+// GetProductStock returns a product's stock in every warehouse
+func (s *InventoryService) GetProductStock(ctx context.Context, productID int64) (*models.ProductStock, error) {
+	start := time.Now()
+	query := "SELECT id, product_id, warehouse_id, quantity, created_at, updated_at FROM inventory WHERE product_id = ? ORDER BY warehouse_id"
+	rows, err := s.db.QueryContext(ctx, query, productID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	defer rows.Close()
+
+	stock := &models.ProductStock{ProductID: productID, Warehouses: []models.Inventory{}}
+	for rows.Next() {
+		var inv models.Inventory
+		if err := rows.Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		stock.TotalQuantity += inv.Quantity
+		stock.Warehouses = append(stock.Warehouses, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+
+	if len(stock.Warehouses) == 0 {
+		if _, err := s.reorderPoint(ctx, s.db, productID); err != nil {
+			return nil, err
+		}
+	}
+	return stock, nil
+}
+
+// GetProductsStock returns the stock of several products in one query,
+// keyed by product ID. Every requested product has an entry, empty when it
+// is not stocked anywhere; unlike GetProductStock, unknown products are not
+// reported.
+func (s *InventoryService) GetProductsStock(ctx context.Context, productIDs []int64) (map[int64]*models.ProductStock, error) {
+	stocks := make(map[int64]*models.ProductStock, len(productIDs))
+	for _, id := range productIDs {
+		stocks[id] = &models.ProductStock{ProductID: id, Warehouses: []models.Inventory{}}
+	}
+	if len(productIDs) == 0 {
+		return stocks, nil
+	}
+
+	placeholders, args := inArgs(productIDs)
+	start := time.Now()
+	query := "SELECT id, product_id, warehouse_id, quantity, created_at, updated_at FROM inventory WHERE product_id IN (" + placeholders + ") ORDER BY product_id, warehouse_id"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", "SELECT ... FROM inventory WHERE product_id IN (...)", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var inv models.Inventory
+		if err := rows.Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		stock := stocks[inv.ProductID]
+		stock.TotalQuantity += inv.Quantity
+		stock.Warehouses = append(stock.Warehouses, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return stocks, nil
+}
+
+// AdjustStock receives, writes off or corrects the stock of a product in one
+// warehouse and records the movement in the ledger
+func (s *InventoryService) AdjustStock(ctx context.Context, req models.StockAdjustmentRequest) (*models.InventoryMovement, error) {
+	var movement *models.InventoryMovement
+	err := s.db.Retry(ctx, "AdjustStock", func(ctx context.Context) error {
+		var err error
+		movement, err = s.adjustStock(ctx, req)
+		return err
+	})
+	return movement, err
+}
+
+// adjustStock is one attempt of AdjustStock
+func (s *InventoryService) adjustStock(ctx context.Context, req models.StockAdjustmentRequest) (*models.InventoryMovement, error) {
+	if req.WarehouseID == "" {
+		return nil, fmt.Errorf("warehouse_id is required")
+	}
+	switch req.Type {
+	case MovementReceive, MovementDamage:
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity must be positive")
+		}
+	case MovementCountCorrection:
+		if req.Quantity < 0 {
+			return nil, fmt.Errorf("quantity must not be negative")
+		}
+	default:
+		return nil, fmt.Errorf("invalid adjustment type")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Receiving or counting stock may put a product into a warehouse for the first time
+	row, err := s.lockInventory(ctx, tx, req.ProductID, req.WarehouseID, req.Type != MovementDamage)
+	if err != nil {
+		return nil, err
+	}
+
+	change := req.Quantity
+	switch req.Type {
+	case MovementDamage:
+		change = -req.Quantity
+	case MovementCountCorrection:
+		change = req.Quantity - row.quantity
+	}
+
+	movement, lowStock, err := s.applyMovement(ctx, tx, &row, req.Type, change, req.Reason, req.Actor, "")
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "inventory", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit stock adjustment: %w", err)
+	}
+
+	s.setLevel(row.productID, row.warehouseID, row.quantity)
+	if lowStock {
+		s.recordLowStock(ctx, row)
+	}
+	log.Printf("[INVENTORY] Stock adjusted: product_id=%d, warehouse_id=%s, type=%s, change=%d, quantity=%d, actor=%s",
+		row.productID, row.warehouseID, req.Type, change, row.quantity, movement.Actor)
+
+	return movement, nil
+}
+
+// TransferStock moves stock of a product between two warehouses atomically
+func (s *InventoryService) TransferStock(ctx context.Context, req models.StockTransferRequest) (*models.StockTransfer, error) {
+	var transfer *models.StockTransfer
+	err := s.db.Retry(ctx, "TransferStock", func(ctx context.Context) error {
+		var err error
+		transfer, err = s.transferStock(ctx, req)
+		return err
+	})
+	return transfer, err
+}
+
+// transferStock is one attempt of TransferStock
+func (s *InventoryService) transferStock(ctx context.Context, req models.StockTransferRequest) (*models.StockTransfer, error) {
+	if req.FromWarehouseID == "" || req.ToWarehouseID == "" {
+		return nil, fmt.Errorf("from_warehouse_id and to_warehouse_id are required")
+	}
+	if req.FromWarehouseID == req.ToWarehouseID {
+		return nil, fmt.Errorf("cannot transfer to the same warehouse")
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+
+	suffix, err := randomHex(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate transfer reference: %w", err)
+	}
+	reference := "trf_" + suffix
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both rows in warehouse order so concurrent transfers cannot deadlock
+	locked := make(map[string]inventoryRow, 2)
+	warehouses := []string{req.FromWarehouseID, req.ToWarehouseID}
+	sort.Strings(warehouses)
+	for _, warehouseID := range warehouses {
+		row, err := s.lockInventory(ctx, tx, req.ProductID, warehouseID, warehouseID == req.ToWarehouseID)
+		if err != nil {
+			return nil, err
+		}
+		locked[warehouseID] = row
+	}
+	from, to := locked[req.FromWarehouseID], locked[req.ToWarehouseID]
+
+	out, fromLow, err := s.applyMovement(ctx, tx, &from, MovementTransferOut, -req.Quantity, req.Reason, req.Actor, reference)
+	if err != nil {
+		return nil, err
+	}
+	in, toLow, err := s.applyMovement(ctx, tx, &to, MovementTransferIn, req.Quantity, req.Reason, req.Actor, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "inventory", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit stock transfer: %w", err)
+	}
+
+	for _, r := range []struct {
+		row inventoryRow
+		low bool
+	}{{from, fromLow}, {to, toLow}} {
+		s.setLevel(r.row.productID, r.row.warehouseID, r.row.quantity)
+		if r.low {
+			s.recordLowStock(ctx, r.row)
+		}
+	}
+	log.Printf("[INVENTORY] Stock transferred: product_id=%d, from=%s, to=%s, quantity=%d, reference=%s, actor=%s",
+		req.ProductID, req.FromWarehouseID, req.ToWarehouseID, req.Quantity, reference, out.Actor)
+
+	return &models.StockTransfer{
+		Reference: reference,
+		Movements: []models.InventoryMovement{*out, *in},
+	}, nil
+}
+
+// ListMovements returns the most recent ledger entries, newest first.
+// productID and warehouseID filter the entries when set.
+func (s *InventoryService) ListMovements(ctx context.Context, productID int64, warehouseID string, limit int) ([]models.InventoryMovement, error) {
+	query := "SELECT id, product_id, warehouse_id, movement_type, quantity_change, quantity_after, reason, actor, reference, created_at FROM inventory_movements WHERE 1 = 1"
+	var args []interface{}
+	if productID > 0 {
+		query += " AND product_id = ?"
+		args = append(args, productID)
+	}
+	if warehouseID != "" {
+		query += " AND warehouse_id = ?"
+		args = append(args, warehouseID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory_movements", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inventory movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []models.InventoryMovement{}
+	for rows.Next() {
+		var m models.InventoryMovement
+		var reason, reference sql.NullString
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.WarehouseID, &m.MovementType, &m.QuantityChange,
+			&m.QuantityAfter, &reason, &m.Actor, &reference, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory movement: %w", err)
+		}
+		m.Reason = reason.String
+		m.Reference = reference.String
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list inventory movements: %w", err)
+	}
+	return movements, nil
+}
+
+// queryRower is implemented by *db.Tx and *db.DB
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// reorderPoint returns a product's reorder point, or "product not found"
+func (s *InventoryService) reorderPoint(ctx context.Context, q queryRower, productID int64) (int, error) {
+	start := time.Now()
+	query := "SELECT reorder_point FROM products WHERE id = ?"
+	var reorderPoint int
+	err := q.QueryRowContext(ctx, query, productID).Scan(&reorderPoint)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "products", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get product: %w", err)
+	}
+	return reorderPoint, nil
+}
+
+// lockInventory locks a product's inventory row in a warehouse for update.
+// With create set, a missing row is created with zero stock.
+func (s *InventoryService) lockInventory(ctx context.Context, tx *db.Tx, productID int64, warehouseID string, create bool) (inventoryRow, error) {
+	row := inventoryRow{productID: productID, warehouseID: warehouseID}
+
+	reorderPoint, err := s.reorderPoint(ctx, tx, productID)
+	if err != nil {
+		return row, err
+	}
+	row.reorderPoint = reorderPoint
+
+	if create {
+		start := time.Now()
+		warehouseQuery := "SELECT 1 FROM warehouses WHERE code = ?"
+		var exists int
+		err := tx.QueryRowContext(ctx, warehouseQuery, warehouseID).Scan(&exists)
+		s.metrics.RecordDBQuery(ctx, "SELECT", "warehouses", warehouseQuery, start, err == nil || err == sql.ErrNoRows)
+		if err == sql.ErrNoRows {
+			return row, fmt.Errorf("warehouse not found")
+		}
+		if err != nil {
+			return row, fmt.Errorf("failed to get warehouse: %w", err)
+		}
+
+		start = time.Now()
+		insertQuery := "INSERT INTO inventory (product_id, warehouse_id, quantity) VALUES (?, ?, 0) ON DUPLICATE KEY UPDATE id = id"
+		_, err = tx.ExecContext(ctx, insertQuery, productID, warehouseID)
+		s.metrics.RecordDBQuery(ctx, "INSERT", "inventory", insertQuery, start, err == nil)
+		if err != nil {
+			return row, fmt.Errorf("failed to create inventory: %w", err)
+		}
+	}
+
+	start := time.Now()
+	query := "SELECT id, quantity, low_stock_since FROM inventory WHERE product_id = ? AND warehouse_id = ? FOR UPDATE"
+	err = tx.QueryRowContext(ctx, query, productID, warehouseID).Scan(&row.id, &row.quantity, &row.lowStockSince)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return row, fmt.Errorf("inventory not found")
+	}
+	if err != nil {
+		return row, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+	return row, nil
+}
+
+// applyMovement changes the quantity of a locked row, writes the ledger entry
+// and keeps the low stock mark in step. It reports whether the row became low
+// on stock so the caller can record it after commit.
+func (s *InventoryService) applyMovement(ctx context.Context, tx *db.Tx, row *inventoryRow, movementType string, change int, reason, actor, reference string) (*models.InventoryMovement, bool, error) {
+	quantity := row.quantity + change
+	if quantity < 0 {
+		return nil, false, fmt.Errorf("insufficient stock")
+	}
+	if actor == "" {
+		actor = defaultMovementActor
+	}
+
+	start := time.Now()
+	updateQuery := "UPDATE inventory SET quantity = ? WHERE id = ?"
+	_, err := tx.ExecContext(ctx, updateQuery, quantity, row.id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "inventory", updateQuery, start, err == nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update inventory: %w", err)
+	}
+	row.quantity = quantity
+
+	now := time.Now().UTC()
+	start = time.Now()
+	insertQuery := `
+		INSERT INTO inventory_movements (product_id, warehouse_id, movement_type, quantity_change, quantity_after, reason, actor, reference, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := tx.ExecContext(ctx, insertQuery, row.productID, row.warehouseID, movementType, change, quantity,
+		nullableString(reason), actor, nullableString(reference), now)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "inventory_movements", insertQuery, start, err == nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record inventory movement: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get inventory movement ID: %w", err)
+	}
+
+	movement := &models.InventoryMovement{
+		ID:             id,
+		ProductID:      row.productID,
+		WarehouseID:    row.warehouseID,
+		MovementType:   movementType,
+		QuantityChange: change,
+		QuantityAfter:  quantity,
+		Reason:         reason,
+		Actor:          actor,
+		Reference:      reference,
+		CreatedAt:      now,
+	}
+
+	if quantity > row.reorderPoint {
+		if row.lowStockSince.Valid {
+			start = time.Now()
+			clearQuery := "UPDATE inventory SET low_stock_since = NULL WHERE id = ?"
+			_, err := tx.ExecContext(ctx, clearQuery, row.id)
+			s.metrics.RecordDBQuery(ctx, "UPDATE", "inventory", clearQuery, start, err == nil)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to clear low stock mark: %w", err)
+			}
+			row.lowStockSince = sql.NullTime{}
+		}
+		return movement, false, nil
+	}
+
+	if row.lowStockSince.Valid {
+		return movement, false, nil
+	}
+	marked, err := s.markLowStock(ctx, tx, *row)
+	if err != nil {
+		return nil, false, err
+	}
+	if marked {
+		row.lowStockSince = sql.NullTime{Time: now, Valid: true}
+	}
+	return movement, marked, nil
+}
+
+// nullableString maps an empty string to NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestInventoryScanEmitsLowStockOnce(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	ids := testProducts(t, database, 1, 3)
+	testExec(t, database, "UPDATE products SET reorder_point = 5 WHERE id = ?", ids[0])
+
+	for i := 0; i < 2; i++ {
+		if err := s.inventory.Scan(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The event keeps the inventory.low name existing subscribers match on
+	var count int
+	query := "SELECT COUNT(*) FROM outbox WHERE event_type = 'inventory.low' AND aggregate_id = ?"
+	if err := database.QueryRowContext(ctx, query, ids[0]).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d inventory.low events, want 1", count)
+	}
+}
+
+// testQuantity returns the stock of a product in a warehouse and the number
+// of ledger entries written for it
+func testQuantity(tb testing.TB, database *db.DB, productID int64, warehouseID string) (quantity, movements int) {
+	tb.Helper()
+	ctx := context.Background()
+	if err := database.QueryRowContext(ctx, "SELECT quantity FROM inventory WHERE product_id = ? AND warehouse_id = ?", productID, warehouseID).Scan(&quantity); err != nil {
+		tb.Fatal(err)
+	}
+	if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM inventory_movements WHERE product_id = ? AND warehouse_id = ?", productID, warehouseID).Scan(&movements); err != nil {
+		tb.Fatal(err)
+	}
+	return quantity, movements
+}
+
+func TestAdjustStockNeverGoesNegative(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	ids := testProducts(t, database, 1, 5)
+
+	tests := []struct {
+		typ      string
+		quantity int
+		wantErr  string
+		want     int
+	}{
+		{MovementDamage, 6, "insufficient stock", 5},
+		{MovementDamage, 5, "", 0},
+		{MovementDamage, 1, "insufficient stock", 0},
+		{MovementReceive, 3, "", 3},
+		{MovementCountCorrection, -1, "quantity must not be negative", 3},
+		{MovementCountCorrection, 0, "", 0},
+		{MovementReceive, 0, "quantity must be positive", 0},
+		{"sale", 1, "invalid adjustment type", 0},
+	}
+	movements := 0
+	for i, tt := range tests {
+		movement, err := s.inventory.AdjustStock(ctx, models.StockAdjustmentRequest{
+			ProductID: ids[0], WarehouseID: "WH-001", Type: tt.typ, Quantity: tt.quantity,
+		})
+		switch {
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("%d: %s %d: error %v, want %q", i, tt.typ, tt.quantity, err, tt.wantErr)
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%d: %s %d: %v", i, tt.typ, tt.quantity, err)
+		case tt.wantErr == "":
+			movements++
+			if movement.QuantityAfter != tt.want {
+				t.Errorf("%d: %s %d: quantity_after %d, want %d", i, tt.typ, tt.quantity, movement.QuantityAfter, tt.want)
+			}
+		}
+		// A rejected adjustment changes nothing and leaves no ledger entry
+		if quantity, n := testQuantity(t, database, ids[0], "WH-001"); quantity != tt.want || n != movements {
+			t.Errorf("%d: %s %d: quantity %d with %d movements, want %d with %d", i, tt.typ, tt.quantity, quantity, n, tt.want, movements)
+		}
+	}
+
+	// Damage cannot create a row for a warehouse that never stocked the product
+	testExec(t, database, "DELETE FROM inventory WHERE product_id = ? AND warehouse_id = 'WH-003'", ids[0])
+	if _, err := s.inventory.AdjustStock(ctx, models.StockAdjustmentRequest{
+		ProductID: ids[0], WarehouseID: "WH-003", Type: MovementDamage, Quantity: 1,
+	}); err == nil {
+		t.Error("damage in an empty warehouse succeeded")
+	}
+}
+
+func TestTransferStockIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	ids := testProducts(t, database, 1, 5)
+	testExec(t, database, "DELETE FROM inventory WHERE product_id = ? AND warehouse_id = 'WH-002'", ids[0])
+
+	transfer, err := s.inventory.TransferStock(ctx, models.StockTransferRequest{
+		ProductID: ids[0], FromWarehouseID: "WH-001", ToWarehouseID: "WH-002", Quantity: 4, Actor: "ops",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfer.Movements) != 2 || transfer.Movements[0].QuantityChange != -4 || transfer.Movements[1].QuantityChange != 4 ||
+		transfer.Movements[0].Reference != transfer.Reference || transfer.Movements[1].Reference != transfer.Reference {
+		t.Errorf("transfer %+v, want -4 and +4 under one reference", transfer)
+	}
+	if from, _ := testQuantity(t, database, ids[0], "WH-001"); from != 1 {
+		t.Errorf("WH-001 has %d after the transfer, want 1", from)
+	}
+	if to, _ := testQuantity(t, database, ids[0], "WH-002"); to != 4 {
+		t.Errorf("WH-002 has %d after the transfer, want 4", to)
+	}
+
+	// Moving more than the source holds fails without touching either side
+	for _, req := range []models.StockTransferRequest{
+		{ProductID: ids[0], FromWarehouseID: "WH-001", ToWarehouseID: "WH-002", Quantity: 2},
+		{ProductID: ids[0], FromWarehouseID: "WH-002", ToWarehouseID: "WH-003", Quantity: 5},
+	} {
+		if _, err := s.inventory.TransferStock(ctx, req); err == nil || err.Error() != "insufficient stock" {
+			t.Errorf("transfer of %d from %s: %v, want insufficient stock", req.Quantity, req.FromWarehouseID, err)
+		}
+	}
+	for warehouse, want := range map[string][2]int{"WH-001": {1, 1}, "WH-002": {4, 1}} {
+		if quantity, n := testQuantity(t, database, ids[0], warehouse); quantity != want[0] || n != want[1] {
+			t.Errorf("%s: quantity %d with %d movements, want %d with %d", warehouse, quantity, n, want[0], want[1])
+		}
+	}
+
+	for _, req := range []models.StockTransferRequest{
+		{ProductID: ids[0], FromWarehouseID: "WH-001", ToWarehouseID: "WH-001", Quantity: 1},
+		{ProductID: ids[0], FromWarehouseID: "WH-001", ToWarehouseID: "WH-002", Quantity: -1},
+	} {
+		if _, err := s.inventory.TransferStock(ctx, req); err == nil {
+			t.Errorf("transfer %+v succeeded", req)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/SigNoz/ecommerce-go-app/internal/currency"
 	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
 	"github.com/SigNoz/ecommerce-go-app/internal/models"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,20 +19,52 @@ import (
 
 // OrderService handles order-related operations
 type OrderService struct {
-	db      *db.DB
-	metrics *metrics.AppMetrics
+	db        *db.DB
+	metrics   *metrics.AppMetrics
+	rates     currency.RateProvider
+	outbox    *events.Outbox
+	router    *FulfilmentRouter
+	addresses *AddressService
 }
 
 // NewOrderService creates a new order service
-func NewOrderService(db *db.DB, metrics *metrics.AppMetrics) *OrderService {
+func NewOrderService(db *db.DB, metrics *metrics.AppMetrics, rates currency.RateProvider, outbox *events.Outbox, router *FulfilmentRouter, addresses *AddressService) *OrderService {
 	return &OrderService{
-		db:      db,
-		metrics: metrics,
+		db:        db,
+		metrics:   metrics,
+		rates:     rates,
+		outbox:    outbox,
+		router:    router,
+		addresses: addresses,
 	}
 }
 
-// CreateOrder creates a new order from the user's cart
-func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMethod, currency string) (*models.Order, error) {
+// CreateOrder creates a new order from the user's cart.
+// Cart prices are in the base currency; the order is charged in orderCurrency
+// at the current exchange rate and both amounts are stored.
+// Stock is allocated to warehouses by the fulfilment router, preferring
+// region (the user's region when empty).
+// The shipping address (the user's default shipping address when nil) is
+// copied onto the order.
+func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMethod, orderCurrency, region string, shippingAddressID *int64) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.Retry(ctx, "CreateOrder", func(ctx context.Context) error {
+		var err error
+		order, err = s.createOrder(ctx, userID, paymentMethod, orderCurrency, region, shippingAddressID)
+		return err
+	})
+	return order, err
+}
+
+// createOrder is one attempt of CreateOrder
+func (s *OrderService) createOrder(ctx context.Context, userID int64, paymentMethod, orderCurrency, region string, shippingAddressID *int64) (*models.Order, error) {
+	orderCurrency = currency.Normalize(orderCurrency)
+	baseCurrency := s.rates.BaseCurrency()
+	exchangeRate, err := s.rates.Rate(ctx, orderCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Start transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -38,112 +72,70 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMet
 	}
 	defer tx.Rollback()
 
+	// Lock the user's cart so concurrent checkouts of the same cart serialize
 	start := time.Now()
+	cartIDQuery := "SELECT id FROM carts WHERE user_id = ? FOR UPDATE"
+	var cartID int64
+	err = tx.QueryRowContext(ctx, cartIDQuery, userID).Scan(&cartID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "carts", cartIDQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("cart is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cart: %w", err)
+	}
 
-	// Get cart items
+	// Get cart items with price and category in one read.
+	// Cart rows are locked for the delete below; product rows are share-locked
+	// so prices cannot change until the order is committed.
+	start = time.Now()
 	cartQuery := `
-		SELECT ci.product_id, ci.quantity, p.price
+		SELECT ci.product_id, ci.quantity, p.price, p.category
 		FROM cart_items ci
 		JOIN products p ON ci.product_id = p.id
-		JOIN carts c ON ci.cart_id = c.id
-		WHERE c.user_id = ?
+		WHERE ci.cart_id = ?
+		FOR UPDATE OF ci FOR SHARE OF p
 	`
-	rows, err := tx.QueryContext(ctx, cartQuery, userID)
+	items, err := s.readCheckoutItems(ctx, tx, cartQuery, cartID, baseCurrency)
 	s.metrics.RecordDBQuery(ctx, "SELECT", "cart_items", cartQuery, start, err == nil)
 	if err != nil {
-		s.metrics.RecordDBQuery(ctx, "SELECT", "cart_items", cartQuery, start, false)
-		return nil, fmt.Errorf("failed to get cart items: %w", err)
-	}
-
-	var items []struct {
-		ProductID int64
-		Quantity  int
-		Price     float64
-	}
-	var totalAmount float64
-
-	for rows.Next() {
-		var item struct {
-			ProductID int64
-			Quantity  int
-			Price     float64
-		}
-		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.Price); err != nil {
-			return nil, fmt.Errorf("failed to scan cart item: %w", err)
-		}
-		items = append(items, item)
-		totalAmount += item.Price * float64(item.Quantity)
+		return nil, err
 	}
-	rows.Close()
 
 	if len(items) == 0 {
 		return nil, fmt.Errorf("cart is empty")
 	}
 
-	// ============================================
-	// GET PRODUCT CATEGORIES FOR ALL ITEMS
-	// ============================================
-	type itemWithCategory struct {
-		productID int64
-		quantity  int
-		price     float64
-		category  string
-	}
-
-	var itemsWithCategories []itemWithCategory
-
-	// Query all product categories
-	productIDs := make([]interface{}, len(items))
-	for i, item := range items {
-		productIDs[i] = item.ProductID
-	}
-
-	placeholders := make([]string, len(items))
-	for i := range items {
-		placeholders[i] = "?"
-	}
-
-	start = time.Now()
-	catQuery := fmt.Sprintf("SELECT id, category FROM products WHERE id IN (%s)",
-		strings.Join(placeholders, ","))
-	catRows, err := s.db.QueryContext(ctx, catQuery, productIDs...)
-	s.metrics.RecordDBQuery(ctx, "SELECT", "products", catQuery, start, err == nil)
-
-	categoryMap := make(map[int64]string)
-	if err == nil {
-		for catRows.Next() {
-			var id int64
-			var category string
-			if err := catRows.Scan(&id, &category); err == nil {
-				categoryMap[id] = category
-			}
-		}
-		catRows.Close()
+	baseAmount := models.NewMoney(0, baseCurrency)
+	for _, item := range items {
+		baseAmount = baseAmount.Add(item.price.Mul(item.quantity))
 	}
 
-	// Build items with categories
-	for _, item := range items {
-		category := categoryMap[item.ProductID]
-		if category == "" {
-			category = "unknown"
-		}
-		itemsWithCategories = append(itemsWithCategories, itemWithCategory{
-			productID: item.ProductID,
-			quantity:  item.Quantity,
-			price:     item.Price,
-			category:  category,
-		})
+	// ============================================
+	// CONVERT TO ORDER CURRENCY
+	// ============================================
+	totalAmount, err := baseAmount.Convert(exchangeRate, orderCurrency)
+	if err != nil {
+		return nil, err
 	}
 
 	// ============================================
 	// CREATE ORDER
 	// ============================================
+	address, err := s.addresses.shippingAddress(ctx, tx, userID, shippingAddressID)
+	if err != nil {
+		return nil, err
+	}
+	var shippingAddress *models.OrderAddress
+	if address != nil {
+		shippingAddress = models.NewOrderAddress(address)
+	}
+
 	start = time.Now()
-	orderQuery := "INSERT INTO orders (user_id, status, payment_method, total_amount, currency) VALUES (?, 'pending', ?, ?, ?)"
-	result, err := tx.ExecContext(ctx, orderQuery, userID, paymentMethod, totalAmount, currency)
+	orderQuery := "INSERT INTO orders (user_id, status, payment_method, total_amount, currency, base_amount, base_currency, exchange_rate, shipping_address) VALUES (?, 'pending', ?, ?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, orderQuery, userID, paymentMethod, totalAmount, orderCurrency, baseAmount, baseCurrency, exchangeRate, shippingAddress)
 	s.metrics.RecordDBQuery(ctx, "INSERT", "orders", orderQuery, start, err == nil)
 	if err != nil {
-		s.metrics.RecordDBQuery(ctx, "INSERT", "orders", orderQuery, start, false)
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
 
@@ -152,33 +144,68 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMet
 		return nil, fmt.Errorf("failed to get order ID: %w", err)
 	}
 
-	// Create order items
-	start = time.Now()
-	itemQuery := "INSERT INTO order_items (order_id, product_id, quantity, price) VALUES (?, ?, ?, ?)"
-	for _, item := range items {
-		_, err = tx.ExecContext(ctx, itemQuery, orderID, item.ProductID, item.Quantity, item.Price)
-		s.metrics.RecordDBQuery(ctx, "INSERT", "order_items", itemQuery, start, err == nil)
-		if err != nil {
-			s.metrics.RecordDBQuery(ctx, "INSERT", "order_items", itemQuery, start, false)
-			return nil, fmt.Errorf("failed to create order item: %w", err)
+	// ============================================
+	// ALLOCATE STOCK TO WAREHOUSES
+	// ============================================
+	if region == "" {
+		start = time.Now()
+		regionQuery := "SELECT region FROM users WHERE id = ?"
+		err = tx.QueryRowContext(ctx, regionQuery, userID).Scan(&region)
+		s.metrics.RecordDBQuery(ctx, "SELECT", "users", regionQuery, start, err == nil || err == sql.ErrNoRows)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get user region: %w", err)
 		}
 	}
+	plan, err := s.router.Route(ctx, tx, orderID, region, items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create all order items in a single statement
+	if err := s.insertOrderItems(ctx, tx, orderID, plan.items); err != nil {
+		return nil, err
+	}
 
 	// Clear cart
 	start = time.Now()
-	cartIDQuery := "SELECT id FROM carts WHERE user_id = ?"
-	var cartID int64
-	err = tx.QueryRowContext(ctx, cartIDQuery, userID).Scan(&cartID)
-	if err == nil {
-		deleteQuery := "DELETE FROM cart_items WHERE cart_id = ?"
-		_, err = tx.ExecContext(ctx, deleteQuery, cartID)
-		s.metrics.RecordDBQuery(ctx, "DELETE", "cart_items", deleteQuery, start, err == nil)
+	deleteQuery := "DELETE FROM cart_items WHERE cart_id = ?"
+	_, err = tx.ExecContext(ctx, deleteQuery, cartID)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "cart_items", deleteQuery, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	// Publish order.created together with the order
+	created := events.OrderCreated{
+		OrderID:       orderID,
+		UserID:        userID,
+		Status:        "pending",
+		PaymentMethod: paymentMethod,
+		TotalAmount:   totalAmount,
+		Currency:      orderCurrency,
+		BaseAmount:    baseAmount,
+		BaseCurrency:  baseCurrency,
+	}
+	for _, item := range plan.items {
+		created.Items = append(created.Items, events.OrderLineEvent{
+			ProductID:   item.productID,
+			Quantity:    item.quantity,
+			Price:       item.price,
+			WarehouseID: item.warehouseID,
+		})
+	}
+	if err := s.outbox.Enqueue(ctx, tx, events.TypeOrderCreated, events.AggregateOrder, orderID, created); err != nil {
+		return nil, err
 	}
 
 	// Commit transaction
-	if err = tx.Commit(); err != nil {
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "orders", "COMMIT", start, err == nil)
+	if err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.router.Committed(ctx, plan)
 
 	// Order stays "pending" as created
 	// Traffic script will handle 70/30 completion via PUT /api/v1/orders/{id}/status
@@ -187,24 +214,31 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMet
 	// Get created order with UPDATED status
 	order, err := s.GetOrder(ctx, orderID)
 	if err != nil {
-		return nil, err
+		return nil, db.AfterCommit(err)
 	}
 
 	// ============================================
-	// CALCULATE TOTALS PER CATEGORY
+	// CALCULATE TOTALS PER CATEGORY AND WAREHOUSE
 	// ============================================
-	categoryRevenue := make(map[string]float64)
-	categoryOrders := make(map[string]int)
-
-	for _, item := range itemsWithCategories {
-		categoryRevenue[item.category] += item.price * float64(item.quantity)
-		categoryOrders[item.category]++
+	// An order counts once per category, however many of its lines or
+	// warehouses the category spans; revenue is split by warehouse
+	groupRevenue := make(map[orderMetricGroup]models.Money)
+	categories := make(map[string]bool)
+
+	for _, item := range plan.items {
+		group := orderMetricGroup{category: item.category, warehouseID: item.warehouseID}
+		revenue, ok := groupRevenue[group]
+		if !ok {
+			revenue = models.NewMoney(0, baseCurrency)
+		}
+		groupRevenue[group] = revenue.Add(item.price.Mul(item.quantity))
+		categories[item.category] = true
 	}
 
 	// ============================================
-	// RECORD METRICS PER CATEGORY
+	// RECORD METRICS PER CATEGORY AND WAREHOUSE
 	// ============================================
-	for category, orderCount := range categoryOrders {
+	for category := range categories {
 		// Record order metric WITH CATEGORY and STATUS
 		orderAttrs := s.metrics.WithServiceName([]attribute.KeyValue{
 			attribute.String("order_status", order.Status),
@@ -212,41 +246,131 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, paymentMet
 			attribute.String("product_category", category),
 		})
 
-		log.Printf("[METRICS] Recording order: category=%s, count=%d, status=%s, payment_method=%s, order_id=%d",
-			category, orderCount, order.Status, order.PaymentMethod, orderID)
-		s.metrics.OrdersCreated.Add(ctx, int64(orderCount), metric.WithAttributes(orderAttrs...))
+		log.Printf("[METRICS] Recording order: category=%s, status=%s, payment_method=%s, order_id=%d",
+			category, order.Status, order.PaymentMethod, orderID)
+		s.metrics.OrdersCreated.Add(ctx, 1, metric.WithAttributes(orderAttrs...))
 		log.Printf("[METRICS] ✓ OrdersCreated metric recorded for category %s with status=%s", category, order.Status)
+	}
 
-		// Record revenue metric WITH CATEGORY and STATUS
-		amount := categoryRevenue[category]
+	for group, amount := range groupRevenue {
+		category := group.category
+
+		// Record revenue metric WITH CATEGORY, WAREHOUSE and STATUS (always in the base currency)
 		revenueAttrs := s.metrics.WithServiceName([]attribute.KeyValue{
-			attribute.String("currency", currency),
+			attribute.String("currency", baseCurrency),
+			attribute.String("original_currency", orderCurrency),
 			attribute.String("payment_method", paymentMethod),
 			attribute.String("product_category", category),
+			attribute.String("warehouse_id", group.warehouseID),
 			attribute.String("order_status", order.Status),
 		})
 
-		log.Printf("[METRICS] Recording revenue: amount=%.2f, currency=%s, category=%s, status=%s, payment_method=%s, order_id=%d",
-			amount, currency, category, order.Status, paymentMethod, orderID)
-		s.metrics.RevenueTotal.Add(ctx, amount, metric.WithAttributes(revenueAttrs...))
-		log.Printf("[METRICS] ✓ RevenueTotal metric recorded for category %s (value=%.2f %s)", category, amount, currency)
+		log.Printf("[METRICS] Recording revenue: amount=%s, currency=%s, original_currency=%s, category=%s, warehouse_id=%s, status=%s, payment_method=%s, order_id=%d",
+			amount, baseCurrency, orderCurrency, category, group.warehouseID, order.Status, paymentMethod, orderID)
+		s.metrics.RevenueTotal.Add(ctx, amount.Float64(), metric.WithAttributes(revenueAttrs...))
+		log.Printf("[METRICS] ✓ RevenueTotal metric recorded for category %s (value=%s %s)", category, amount, baseCurrency)
 	}
 
-	log.Printf("[ORDER] Order complete: order_id=%d, total=%.2f %s, status=%s, categories=%d, items=%d",
-		orderID, totalAmount, currency, order.Status, len(categoryRevenue), len(itemsWithCategories))
+	log.Printf("[ORDER] Order complete: order_id=%d, total=%s %s (%s %s), status=%s, categories=%d, items=%d, warehouses=%d",
+		orderID, totalAmount, orderCurrency, baseAmount, baseCurrency, order.Status, len(categories), len(items), len(plan.warehouses()))
 
 	return order, nil
 }
 
+// orderMetricGroup is the category/warehouse breakdown of order metrics
+type orderMetricGroup struct {
+	category    string
+	warehouseID string
+}
+
+// setOrderStatus updates the status of an order locked in tx and, if it
+// changed, enqueues order.status_changed
+func setOrderStatus(ctx context.Context, tx *db.Tx, m *metrics.AppMetrics, outbox *events.Outbox, orderID, userID int64, oldStatus, status string) error {
+	start := time.Now()
+	query := "UPDATE orders SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	_, err := tx.ExecContext(ctx, query, status, orderID)
+	m.RecordDBQuery(ctx, "UPDATE", "orders", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if oldStatus == status {
+		return nil
+	}
+	changed := events.OrderStatusChanged{
+		OrderID:   orderID,
+		UserID:    userID,
+		OldStatus: oldStatus,
+		NewStatus: status,
+	}
+	return outbox.Enqueue(ctx, tx, events.TypeOrderStatusChanged, events.AggregateOrder, orderID, changed)
+}
+
+// checkoutItem is a cart line read at checkout time
+type checkoutItem struct {
+	productID int64
+	quantity  int
+	price     models.Money
+	category  string
+}
+
+// readCheckoutItems runs the checkout cart query inside the transaction
+func (s *OrderService) readCheckoutItems(ctx context.Context, tx *db.Tx, query string, cartID int64, baseCurrency string) ([]checkoutItem, error) {
+	rows, err := tx.QueryContext(ctx, query, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []checkoutItem
+	for rows.Next() {
+		var item checkoutItem
+		var category sql.NullString
+		if err := rows.Scan(&item.productID, &item.quantity, &item.price, &category); err != nil {
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		item.price.Currency = baseCurrency
+		item.category = category.String
+		if item.category == "" {
+			item.category = "unknown"
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cart items: %w", err)
+	}
+	return items, nil
+}
+
+// insertOrderItems inserts every allocated order line with one multi-row INSERT
+func (s *OrderService) insertOrderItems(ctx context.Context, tx *db.Tx, orderID int64, items []allocatedItem) error {
+	placeholders := make([]string, len(items))
+	args := make([]interface{}, 0, len(items)*5)
+	for i, item := range items {
+		placeholders[i] = "(?, ?, ?, ?, ?)"
+		args = append(args, orderID, item.productID, item.quantity, item.price, item.warehouseID)
+	}
+
+	start := time.Now()
+	itemQuery := "INSERT INTO order_items (order_id, product_id, quantity, price, warehouse_id) VALUES " + strings.Join(placeholders, ", ")
+	_, err := tx.ExecContext(ctx, itemQuery, args...)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "order_items", "INSERT INTO order_items (order_id, product_id, quantity, price, warehouse_id) VALUES (?, ?, ?, ?, ?), ...", start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to create order items: %w", err)
+	}
+	return nil
+}
+
 // GetOrder returns an order by ID
 func (s *OrderService) GetOrder(ctx context.Context, orderID int64) (*models.Order, error) {
 	start := time.Now()
 
-	query := "SELECT id, user_id, status, payment_method, total_amount, currency, created_at, updated_at FROM orders WHERE id = ?"
+	query := "SELECT id, user_id, status, payment_method, total_amount, currency, base_amount, base_currency, exchange_rate, shipping_address, created_at, updated_at FROM orders WHERE id = ?"
 	var order models.Order
 	err := s.db.QueryRowContext(ctx, query, orderID).Scan(
 		&order.ID, &order.UserID, &order.Status, &order.PaymentMethod,
-		&order.TotalAmount, &order.Currency, &order.CreatedAt, &order.UpdatedAt,
+		&order.TotalAmount, &order.Currency, &order.BaseAmount, &order.BaseCurrency, &order.ExchangeRate,
+		&order.ShippingAddress, &order.CreatedAt, &order.UpdatedAt,
 	)
 
 	s.metrics.RecordDBQuery(ctx, "SELECT", "orders", query, start, err == nil)
@@ -258,14 +382,91 @@ func (s *OrderService) GetOrder(ctx context.Context, orderID int64) (*models.Ord
 		s.metrics.RecordDBQuery(ctx, "SELECT", "orders", query, start, false)
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
+	order.TotalAmount.Currency = order.Currency
+	order.BaseAmount.Currency = order.BaseCurrency
+
+	items, err := s.getOrderItems(ctx, orderID, order.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items
 
 	return &order, nil
 }
 
+// getOrderItems returns the lines of an order with their warehouse allocation
+func (s *OrderService) getOrderItems(ctx context.Context, orderID int64, baseCurrency string) ([]models.OrderItem, error) {
+	start := time.Now()
+	query := "SELECT id, order_id, product_id, quantity, price, warehouse_id, created_at FROM order_items WHERE order_id = ? ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query, orderID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "order_items", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.OrderItem
+	for rows.Next() {
+		var item models.OrderItem
+		var warehouseID sql.NullString
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price, &warehouseID, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		item.Price.Currency = baseCurrency
+		item.WarehouseID = warehouseID.String
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	return items, nil
+}
+
+// ListOrderItems returns the lines of several orders in one query, keyed
+// by order ID
+func (s *OrderService) ListOrderItems(ctx context.Context, orderIDs []int64) (map[int64][]models.OrderItem, error) {
+	items := make(map[int64][]models.OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	placeholders, args := inArgs(orderIDs)
+	start := time.Now()
+	query := `
+		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, oi.warehouse_id, oi.created_at, o.base_currency
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.order_id IN (` + placeholders + `)
+		ORDER BY oi.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "order_items", "SELECT ... FROM order_items oi JOIN orders o ... WHERE oi.order_id IN (...)", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.OrderItem
+		var warehouseID sql.NullString
+		var baseCurrency string
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price, &warehouseID, &item.CreatedAt, &baseCurrency); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		item.Price.Currency = baseCurrency
+		item.WarehouseID = warehouseID.String
+		items[item.OrderID] = append(items[item.OrderID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	return items, nil
+}
+
 // ListUserOrders returns all orders for a user
 func (s *OrderService) ListUserOrders(ctx context.Context, userID int64) ([]models.Order, error) {
 	start := time.Now()
-	query := "SELECT id, user_id, status, payment_method, total_amount, currency, created_at, updated_at FROM orders WHERE user_id = ? ORDER BY created_at DESC"
+	query := "SELECT id, user_id, status, payment_method, total_amount, currency, base_amount, base_currency, exchange_rate, shipping_address, created_at, updated_at FROM orders WHERE user_id = ? ORDER BY created_at DESC"
 	rows, err := s.db.QueryContext(ctx, query, userID)
 	s.metrics.RecordDBQuery(ctx, "SELECT", "orders", query, start, err == nil)
 	if err != nil {
@@ -279,10 +480,13 @@ func (s *OrderService) ListUserOrders(ctx context.Context, userID int64) ([]mode
 		var order models.Order
 		if err := rows.Scan(
 			&order.ID, &order.UserID, &order.Status, &order.PaymentMethod,
-			&order.TotalAmount, &order.Currency, &order.CreatedAt, &order.UpdatedAt,
+			&order.TotalAmount, &order.Currency, &order.BaseAmount, &order.BaseCurrency, &order.ExchangeRate,
+			&order.ShippingAddress, &order.CreatedAt, &order.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
+		order.TotalAmount.Currency = order.Currency
+		order.BaseAmount.Currency = order.BaseCurrency
 		orders = append(orders, order)
 	}
 
@@ -291,8 +495,13 @@ func (s *OrderService) ListUserOrders(ctx context.Context, userID int64) ([]mode
 
 // UpdateOrderStatus updates the status of an order
 func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, status string) error {
-	start := time.Now()
+	return s.db.Retry(ctx, "UpdateOrderStatus", func(ctx context.Context) error {
+		return s.updateOrderStatus(ctx, orderID, status)
+	})
+}
 
+// updateOrderStatus is one attempt of UpdateOrderStatus
+func (s *OrderService) updateOrderStatus(ctx context.Context, orderID int64, status string) error {
 	// Validate status
 	validStatuses := map[string]bool{
 		"pending":    true,
@@ -306,26 +515,50 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, sta
 		return fmt.Errorf("invalid status: %s", status)
 	}
 
-	query := "UPDATE orders SET status = ?, updated_at = NOW() WHERE id = ?"
-	result, err := s.db.ExecContext(ctx, query, status, orderID)
-	s.metrics.RecordDBQuery(ctx, "UPDATE", "orders", query, start, err == nil)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		s.metrics.RecordDBQuery(ctx, "UPDATE", "orders", query, start, false)
-		return fmt.Errorf("failed to update order status: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	start := time.Now()
+	selectQuery := "SELECT user_id, status FROM orders WHERE id = ? FOR UPDATE"
+	var userID int64
+	var oldStatus string
+	err = tx.QueryRowContext(ctx, selectQuery, orderID).Scan(&userID, &oldStatus)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "orders", selectQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order not found")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get order: %w", err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("order not found")
+
+	if err := setOrderStatus(ctx, tx, s.metrics, s.outbox, orderID, userID, oldStatus, status); err != nil {
+		return err
+	}
+
+	// Cancelling an order that has not shipped returns its stock
+	var released *fulfilmentPlan
+	if status == "cancelled" && (oldStatus == "pending" || oldStatus == "processing") {
+		released, err = s.router.Release(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if released != nil {
+		s.router.Committed(ctx, released)
 	}
 
 	// ============================================
 	// RECORD METRICS WHEN ORDER IS COMPLETED
 	// ============================================
-	if status == "completed" {
+	// Only the transition counts; completing a completed order again does not
+	if status == "completed" && oldStatus != status {
 		// Fetch the completed order
 		order, err := s.GetOrder(ctx, orderID)
 		if err != nil {
@@ -333,9 +566,9 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, sta
 			return nil
 		}
 
-		// Get order items with categories for this order
+		// Get order items with categories and warehouses for this order
 		itemQuery := `
-        SELECT oi.product_id, oi.quantity, oi.price, p.category
+        SELECT oi.product_id, oi.quantity, oi.price, p.category, oi.warehouse_id
         FROM order_items oi
         JOIN products p ON oi.product_id = p.id
         WHERE oi.order_id = ?
@@ -347,27 +580,39 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, sta
 		}
 		defer itemRows.Close()
 
-		// Build category-wise revenue
-		categoryRevenue := make(map[string]float64)
-		categoryOrders := make(map[string]int)
+		// Build category and warehouse-wise revenue; the order counts once
+		// per category
+		groupRevenue := make(map[orderMetricGroup]models.Money)
+		categories := make(map[string]bool)
 
 		for itemRows.Next() {
 			var productID int64
 			var quantity int
-			var price float64
-			var category string
+			price := models.NewMoney(0, order.BaseCurrency)
+			var category, warehouseID sql.NullString
 
-			if err := itemRows.Scan(&productID, &quantity, &price, &category); err != nil {
+			if err := itemRows.Scan(&productID, &quantity, &price, &category, &warehouseID); err != nil {
 				log.Printf("[WARNING] Failed to scan order item: %v", err)
 				continue
 			}
 
-			categoryRevenue[category] += price * float64(quantity)
-			categoryOrders[category]++
+			group := orderMetricGroup{category: category.String, warehouseID: warehouseID.String}
+			if group.category == "" {
+				group.category = "unknown"
+			}
+			if group.warehouseID == "" {
+				group.warehouseID = "unknown"
+			}
+			revenue, ok := groupRevenue[group]
+			if !ok {
+				revenue = models.NewMoney(0, order.BaseCurrency)
+			}
+			groupRevenue[group] = revenue.Add(price.Mul(quantity))
+			categories[group.category] = true
 		}
 
-		// Record metrics per category with COMPLETED status
-		for category, orderCount := range categoryOrders {
+		// Record metrics per category (and warehouse for revenue) with COMPLETED status
+		for category := range categories {
 			// Record orders_created_total with status="completed"
 			orderAttrs := s.metrics.WithServiceName([]attribute.KeyValue{
 				attribute.String("order_status", "completed"),
@@ -377,20 +622,25 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, sta
 
 			log.Printf("[METRICS] Recording completed order: order_id=%d, status=completed, category=%s, payment_method=%s",
 				orderID, category, order.PaymentMethod)
-			s.metrics.OrdersCreated.Add(ctx, int64(orderCount), metric.WithAttributes(orderAttrs...))
+			s.metrics.OrdersCreated.Add(ctx, 1, metric.WithAttributes(orderAttrs...))
+		}
+
+		for group, amount := range groupRevenue {
+			category := group.category
 
-			// Record revenue_total with status="completed"
-			amount := categoryRevenue[category]
+			// Record revenue_total with status="completed" (item prices are in the base currency)
 			revenueAttrs := s.metrics.WithServiceName([]attribute.KeyValue{
-				attribute.String("currency", order.Currency),
+				attribute.String("currency", order.BaseCurrency),
+				attribute.String("original_currency", order.Currency),
 				attribute.String("payment_method", order.PaymentMethod),
 				attribute.String("product_category", category),
+				attribute.String("warehouse_id", group.warehouseID),
 				attribute.String("order_status", "completed"),
 			})
 
-			log.Printf("[METRICS] Recording completed order revenue: order_id=%d, amount=%.2f, category=%s",
-				orderID, amount, category)
-			s.metrics.RevenueTotal.Add(ctx, amount, metric.WithAttributes(revenueAttrs...))
+			log.Printf("[METRICS] Recording completed order revenue: order_id=%d, amount=%s %s, category=%s, warehouse_id=%s",
+				orderID, amount, order.BaseCurrency, category, group.warehouseID)
+			s.metrics.RevenueTotal.Add(ctx, amount.Float64(), metric.WithAttributes(revenueAttrs...))
 		}
 	}
 
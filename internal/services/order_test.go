@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// BenchmarkCreateOrder50Items checks out a cart of 50 products on SQLite.
+// The InsertItems cases compare the single multi-row INSERT of order lines
+// with the one INSERT per line that checkout used to run, each in its own
+// transaction as at checkout.
+func BenchmarkCreateOrder50Items(b *testing.B) {
+	const lines = 50
+	ctx := context.Background()
+	database := testutil.NewDB(b)
+	s := newTestServices(b, database, testutil.NewMetrics(b))
+	productIDs := testProducts(b, database, lines, 1_000_000_000)
+	userID, cartID := s.testUser(b, "bench@example.com")
+
+	b.Run("CreateOrder", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for _, productID := range productIDs {
+				testExec(b, database, "INSERT INTO cart_items (cart_id, product_id, quantity) VALUES (?, ?, 1)", cartID, productID)
+			}
+			b.StartTimer()
+
+			if _, err := s.orders.CreateOrder(ctx, userID, "credit_card", "USD", "", nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	items := make([]allocatedItem, lines)
+	for i, productID := range productIDs {
+		items[i] = allocatedItem{
+			checkoutItem: checkoutItem{productID: productID, quantity: 1, price: models.NewMoney(999, "USD"), category: "Test"},
+			warehouseID:  "WH-001",
+		}
+	}
+	for _, bc := range []struct {
+		name   string
+		insert func(ctx context.Context, tx *db.Tx, orderID int64, items []allocatedItem) error
+	}{
+		{"InsertItems/MultiRow", s.orders.insertOrderItems},
+		{"InsertItems/PerRow", s.orders.insertOrderItemsPerRow},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tx, err := database.BeginTx(ctx, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				total := models.NewMoney(999*lines, "USD")
+				result, err := tx.ExecContext(ctx, "INSERT INTO orders (user_id, status, payment_method, total_amount, currency, base_amount, base_currency, exchange_rate) VALUES (?, 'pending', 'credit_card', ?, 'USD', ?, 'USD', 1)", userID, total, total)
+				if err != nil {
+					tx.Rollback()
+					b.Fatal(err)
+				}
+				orderID, _ := result.LastInsertId()
+				if err := bc.insert(ctx, tx, orderID, items); err != nil {
+					tx.Rollback()
+					b.Fatal(err)
+				}
+				if err := tx.Commit(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// insertOrderItemsPerRow is how checkout inserted order lines before they
+// were batched, kept to compare against
+func (s *OrderService) insertOrderItemsPerRow(ctx context.Context, tx *db.Tx, orderID int64, items []allocatedItem) error {
+	itemQuery := "INSERT INTO order_items (order_id, product_id, quantity, price, warehouse_id) VALUES (?, ?, ?, ?, ?)"
+	for _, item := range items {
+		start := time.Now()
+		_, err := tx.ExecContext(ctx, itemQuery, orderID, item.productID, item.quantity, item.price, item.warehouseID)
+		s.metrics.RecordDBQuery(ctx, "INSERT", "order_items", itemQuery, start, err == nil)
+		if err != nil {
+			return fmt.Errorf("failed to create order item: %w", err)
+		}
+	}
+	return nil
+}
+
+func TestOrderMetricsCountSplitOrderOnce(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	reader := sdkmetric.NewManualReader()
+	m, err := metrics.NewAppMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"), "test", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServices(t, database, m)
+	userID, cartID := s.testUser(t, "split@example.com")
+
+	// Two Electronics products; the laptop only ships split across two
+	// warehouses, neither of which has all three
+	testExec(t, database, "DELETE FROM inventory WHERE product_id IN (1, 2)")
+	testExec(t, database, "INSERT INTO inventory (product_id, warehouse_id, quantity) VALUES (1, 'WH-001', 1), (1, 'WH-002', 2), (2, 'WH-001', 5)")
+	testExec(t, database, "INSERT INTO cart_items (cart_id, product_id, quantity) VALUES (?, 1, 3), (?, 2, 1)", cartID, cartID)
+
+	order, err := s.orders.CreateOrder(ctx, userID, "credit_card", "USD", "us-east", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The mouse loses its category before the order completes, and the
+	// order is completed twice; only the first time counts
+	testExec(t, database, "UPDATE products SET category = NULL WHERE id = 2")
+	for i := 0; i < 2; i++ {
+		if err := s.orders.UpdateOrderStatus(ctx, order.ID, "completed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	orders := map[string]int64{}
+	revenue := map[string]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			switch data := metric.Data.(type) {
+			case metricdata.Sum[int64]:
+				if metric.Name != "orders_created_total" {
+					continue
+				}
+				for _, dp := range data.DataPoints {
+					if _, ok := dp.Attributes.Value("warehouse_id"); ok {
+						t.Errorf("orders_created_total has a warehouse_id: %v", dp.Attributes.ToSlice())
+					}
+					status, _ := dp.Attributes.Value("order_status")
+					category, _ := dp.Attributes.Value("product_category")
+					orders[status.AsString()+"/"+category.AsString()] += dp.Value
+				}
+			case metricdata.Sum[float64]:
+				if metric.Name != "revenue_total" {
+					continue
+				}
+				for _, dp := range data.DataPoints {
+					status, _ := dp.Attributes.Value("order_status")
+					warehouse, _ := dp.Attributes.Value("warehouse_id")
+					revenue[status.AsString()+"/"+warehouse.AsString()] += dp.Value
+				}
+			}
+		}
+	}
+
+	if want := map[string]int64{"pending/Electronics": 1, "completed/Electronics": 1, "completed/unknown": 1}; fmt.Sprint(orders) != fmt.Sprint(want) {
+		t.Errorf("orders_created_total = %v, want %v", orders, want)
+	}
+	// WH-001 ships one laptop and the mouse, WH-002 the other two laptops
+	want := map[string]float64{
+		"pending/WH-001":   999.99 + 29.99,
+		"pending/WH-002":   2 * 999.99,
+		"completed/WH-001": 999.99 + 29.99,
+		"completed/WH-002": 2 * 999.99,
+	}
+	for key, amount := range want {
+		if got := revenue[key]; math.Abs(got-amount) > 1e-6 {
+			t.Errorf("revenue_total %s = %v, want %v", key, got, amount)
+		}
+	}
+	if len(revenue) != len(want) {
+		t.Errorf("revenue_total = %v, want %v", revenue, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,9 +29,10 @@ type cachedProduct struct {
 
 // ProductService handles product-related operations
 type ProductService struct {
-	db      *db.DB
-	metrics *metrics.AppMetrics
-	cache   ProductCache
+	db           *db.DB
+	metrics      *metrics.AppMetrics
+	cache        ProductCache
+	baseCurrency string
 }
 
 func NewProductCache() ProductCache {
@@ -40,11 +42,12 @@ func NewProductCache() ProductCache {
 }
 
 // NewProductService creates a new product service
-func NewProductService(db *db.DB, metrics *metrics.AppMetrics) *ProductService {
+func NewProductService(db *db.DB, metrics *metrics.AppMetrics, baseCurrency string) *ProductService {
 	return &ProductService{
-		db:      db,
-		metrics: metrics,
-		cache:   NewProductCache(),
+		db:           db,
+		metrics:      metrics,
+		cache:        NewProductCache(),
+		baseCurrency: baseCurrency,
 	}
 }
 
@@ -67,6 +70,8 @@ func (s *ProductService) ListProducts(ctx context.Context, limit, offset int) ([
 		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.Category, &p.SKU, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
+		p.Currency = s.baseCurrency
+		p.Price.Currency = s.baseCurrency
 		products = append(products, p)
 	}
 
@@ -115,6 +120,9 @@ func (s *ProductService) GetProduct(ctx context.Context, id int64) (*models.Prod
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
+	p.Currency = s.baseCurrency
+	p.Price.Currency = s.baseCurrency
+
 	// Cache the product
 	s.cache.mu.Lock()
 	s.cache.items[id] = cachedProduct{
@@ -135,6 +143,54 @@ func (s *ProductService) GetProduct(ctx context.Context, id int64) (*models.Prod
 	return &p, nil
 }
 
+// GetProductsByIDs returns the products with the given IDs in one query,
+// keyed by ID. IDs that do not exist are left out. Unlike GetProduct it
+// neither uses the cache nor counts product views.
+func (s *ProductService) GetProductsByIDs(ctx context.Context, ids []int64) (map[int64]models.Product, error) {
+	products := make(map[int64]models.Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	placeholders, args := inArgs(ids)
+	start := time.Now()
+	query := `SELECT id, name, description, price, category, sku, created_at, updated_at FROM products WHERE id IN (` + placeholders + `)`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "products", "SELECT ... FROM products WHERE id IN (...)", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Product
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.Category, &p.SKU, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		p.Currency = s.baseCurrency
+		p.Price.Currency = s.baseCurrency
+		products[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	return products, nil
+}
+
+// inArgs returns the placeholders and arguments of an IN (...) list.
+// Statements with a variable number of placeholders, like this list or a
+// multi-row VALUES, are recorded by their shape, "IN (...)" or "VALUES
+// (?, ?), ...", rather than one entry per placeholder count.
+func inArgs(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
 // GetProductInventory returns inventory level for a product
 func (s *ProductService) GetProductInventory(ctx context.Context, productID int64, warehouseID string) (*models.Inventory, error) {
 	start := time.Now()
@@ -152,14 +208,5 @@ func (s *ProductService) GetProductInventory(ctx context.Context, productID int6
 		return nil, fmt.Errorf("failed to get inventory: %w", err)
 	}
 
-	// Update inventory gauge metric
-	invAttrs := s.metrics.WithServiceName([]attribute.KeyValue{
-		attribute.Int64("product_id", productID),
-		attribute.String("warehouse_id", warehouseID),
-	})
-	log.Printf("[METRICS] Recording inventory level: product_id=%d, warehouse_id=%s, quantity=%d", productID, warehouseID, inv.Quantity)
-	s.metrics.InventoryLevel.Record(ctx, int64(inv.Quantity), metric.WithAttributes(invAttrs...))
-	log.Printf("[METRICS] InventoryLevel metric recorded")
-
 	return &inv, nil
 }
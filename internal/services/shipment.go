@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Shipment statuses
+const (
+	ShipmentStatusInTransit = "in_transit"
+	ShipmentStatusDelivered = "delivered"
+)
+
+// Fulfilment stages reported by fulfilment_duration_seconds
+const (
+	stageCreatedToShipped   = "created_to_shipped"
+	stageShippedToDelivered = "shipped_to_delivered"
+)
+
+// ShipmentService handles shipments and carrier tracking updates.
+//
+// An order moves to "shipped" once all of its lines are in shipments (to
+// "processing" while only some are) and to "delivered" once every shipment
+// has been delivered.
+type ShipmentService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+	outbox  *events.Outbox
+}
+
+// NewShipmentService creates a new shipment service
+func NewShipmentService(db *db.DB, metrics *metrics.AppMetrics, outbox *events.Outbox) *ShipmentService {
+	return &ShipmentService{
+		db:      db,
+		metrics: metrics,
+		outbox:  outbox,
+	}
+}
+
+// unshippedItem is an order line not yet in a shipment
+type unshippedItem struct {
+	id          int64
+	productID   int64
+	quantity    int
+	warehouseID string
+}
+
+// CreateShipment ships order lines from one warehouse
+func (s *ShipmentService) CreateShipment(ctx context.Context, orderID int64, req models.CreateShipmentRequest) (*models.Shipment, error) {
+	var shipment *models.Shipment
+	err := s.db.Retry(ctx, "CreateShipment", func(ctx context.Context) error {
+		var err error
+		shipment, err = s.createShipment(ctx, orderID, req)
+		return err
+	})
+	return shipment, err
+}
+
+// createShipment is one attempt of CreateShipment
+func (s *ShipmentService) createShipment(ctx context.Context, orderID int64, req models.CreateShipmentRequest) (*models.Shipment, error) {
+	req.Carrier = normalizeCarrier(req.Carrier)
+	req.TrackingNumber = strings.TrimSpace(req.TrackingNumber)
+	if req.Carrier == "" || req.TrackingNumber == "" {
+		return nil, fmt.Errorf("carrier and tracking_number are required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userID, status, orderCreatedAt, err := s.lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if status == "cancelled" || status == "delivered" {
+		return nil, fmt.Errorf("order cannot be shipped")
+	}
+
+	unshipped, err := s.unshippedItems(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(unshipped) == 0 {
+		return nil, fmt.Errorf("no items to ship")
+	}
+
+	// Without an explicit warehouse, ship from the only one left
+	if req.WarehouseID == "" {
+		for _, item := range unshipped {
+			if item.warehouseID == "" {
+				continue
+			}
+			if req.WarehouseID != "" && req.WarehouseID != item.warehouseID {
+				return nil, fmt.Errorf("warehouse_id is required")
+			}
+			req.WarehouseID = item.warehouseID
+		}
+		if req.WarehouseID == "" {
+			return nil, fmt.Errorf("warehouse_id is required")
+		}
+	}
+
+	// Lines allocated before warehouse routing have no warehouse and may ship from any
+	selected, err := selectShipmentItems(unshipped, req)
+	if err != nil {
+		return nil, err
+	}
+
+	shippedAt := time.Now().UTC()
+	start := time.Now()
+	insertQuery := "INSERT INTO shipments (order_id, warehouse_id, carrier, tracking_number, status, shipped_at) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, insertQuery, orderID, req.WarehouseID, req.Carrier, req.TrackingNumber, ShipmentStatusInTransit, shippedAt)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "shipments", insertQuery, start, err == nil)
+	if err != nil {
+		if db.IsDuplicateEntry(err) {
+			return nil, fmt.Errorf("shipment already exists")
+		}
+		return nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+	shipmentID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment ID: %w", err)
+	}
+
+	placeholders := make([]string, len(selected))
+	args := make([]interface{}, 0, len(selected)*2)
+	for i, item := range selected {
+		placeholders[i] = "(?, ?)"
+		args = append(args, shipmentID, item.id)
+	}
+	start = time.Now()
+	itemQuery := "INSERT INTO shipment_items (shipment_id, order_item_id) VALUES " + strings.Join(placeholders, ", ")
+	_, err = tx.ExecContext(ctx, itemQuery, args...)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "shipment_items", "INSERT INTO shipment_items (shipment_id, order_item_id) VALUES (?, ?), ...", start, err == nil)
+	if err != nil {
+		if db.IsDuplicateEntry(err) {
+			return nil, fmt.Errorf("order items already shipped")
+		}
+		return nil, fmt.Errorf("failed to create shipment items: %w", err)
+	}
+
+	newStatus := "shipped"
+	if len(selected) < len(unshipped) {
+		newStatus = status
+		if status == "pending" {
+			newStatus = "processing"
+		}
+	}
+	if err := setOrderStatus(ctx, tx, s.metrics, s.outbox, orderID, userID, status, newStatus); err != nil {
+		return nil, err
+	}
+
+	err = s.outbox.Enqueue(ctx, tx, events.TypeShipmentCreated, events.AggregateOrder, orderID, events.ShipmentUpdated{
+		ShipmentID:     shipmentID,
+		OrderID:        orderID,
+		WarehouseID:    req.WarehouseID,
+		Carrier:        req.Carrier,
+		TrackingNumber: req.TrackingNumber,
+		Status:         ShipmentStatusInTransit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "shipments", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("[SHIPMENT] Shipment created: shipment_id=%d, order_id=%d, warehouse_id=%s, carrier=%s, items=%d, order_status=%s",
+		shipmentID, orderID, req.WarehouseID, req.Carrier, len(selected), newStatus)
+	s.recordFulfilmentDuration(ctx, stageCreatedToShipped, req.WarehouseID, req.Carrier, shippedAt.Sub(orderCreatedAt))
+
+	shipment, err := s.GetShipment(ctx, shipmentID)
+	return shipment, db.AfterCommit(err)
+}
+
+// selectShipmentItems picks the unshipped lines a shipment request covers
+func selectShipmentItems(unshipped []unshippedItem, req models.CreateShipmentRequest) ([]unshippedItem, error) {
+	shippable := func(item unshippedItem) bool {
+		return item.warehouseID == "" || item.warehouseID == req.WarehouseID
+	}
+
+	var selected []unshippedItem
+	if len(req.OrderItemIDs) == 0 {
+		for _, item := range unshipped {
+			if shippable(item) {
+				selected = append(selected, item)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("no items to ship")
+		}
+		return selected, nil
+	}
+
+	byID := make(map[int64]unshippedItem, len(unshipped))
+	for _, item := range unshipped {
+		byID[item.id] = item
+	}
+	seen := make(map[int64]bool, len(req.OrderItemIDs))
+	for _, id := range req.OrderItemIDs {
+		item, ok := byID[id]
+		if !ok || seen[id] || !shippable(item) {
+			return nil, fmt.Errorf("order item %d cannot be shipped from %s", id, req.WarehouseID)
+		}
+		seen[id] = true
+		selected = append(selected, item)
+	}
+	return selected, nil
+}
+
+// ApplyCarrierUpdate applies a tracking update from a carrier. Updates are
+// idempotent: a repeated delivery notice leaves the shipment unchanged.
+func (s *ShipmentService) ApplyCarrierUpdate(ctx context.Context, carrier string, update models.CarrierUpdate) (*models.Shipment, error) {
+	var shipment *models.Shipment
+	err := s.db.Retry(ctx, "ApplyCarrierUpdate", func(ctx context.Context) error {
+		var err error
+		shipment, err = s.applyCarrierUpdate(ctx, carrier, update)
+		return err
+	})
+	return shipment, err
+}
+
+// applyCarrierUpdate is one attempt of ApplyCarrierUpdate
+func (s *ShipmentService) applyCarrierUpdate(ctx context.Context, carrier string, update models.CarrierUpdate) (*models.Shipment, error) {
+	if update.Status != ShipmentStatusInTransit && update.Status != ShipmentStatusDelivered {
+		return nil, fmt.Errorf("invalid shipment status")
+	}
+	carrier = normalizeCarrier(carrier)
+
+	// Find the order first so locks are taken in the same order as CreateShipment
+	start := time.Now()
+	findQuery := "SELECT id, order_id FROM shipments WHERE carrier = ? AND tracking_number = ?"
+	var shipmentID, orderID int64
+	err := s.db.QueryRowContext(ctx, findQuery, carrier, update.TrackingNumber).Scan(&shipmentID, &orderID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "shipments", findQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("shipment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment: %w", err)
+	}
+
+	if update.Status == ShipmentStatusInTransit {
+		return s.GetShipment(ctx, shipmentID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userID, orderStatus, _, err := s.lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	start = time.Now()
+	lockQuery := "SELECT warehouse_id, status, shipped_at FROM shipments WHERE id = ? FOR UPDATE"
+	var warehouseID, status string
+	var shippedAt time.Time
+	err = tx.QueryRowContext(ctx, lockQuery, shipmentID).Scan(&warehouseID, &status, &shippedAt)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "shipments", lockQuery, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock shipment: %w", err)
+	}
+	if status == ShipmentStatusDelivered {
+		return s.GetShipment(ctx, shipmentID)
+	}
+
+	deliveredAt := time.Now().UTC()
+	if update.OccurredAt != nil && !update.OccurredAt.IsZero() {
+		deliveredAt = update.OccurredAt.UTC()
+	}
+
+	start = time.Now()
+	updateQuery := "UPDATE shipments SET status = ?, delivered_at = ? WHERE id = ?"
+	_, err = tx.ExecContext(ctx, updateQuery, ShipmentStatusDelivered, deliveredAt, shipmentID)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "shipments", updateQuery, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipment: %w", err)
+	}
+
+	err = s.outbox.Enqueue(ctx, tx, events.TypeShipmentDelivered, events.AggregateOrder, orderID, events.ShipmentUpdated{
+		ShipmentID:     shipmentID,
+		OrderID:        orderID,
+		WarehouseID:    warehouseID,
+		Carrier:        carrier,
+		TrackingNumber: update.TrackingNumber,
+		Status:         ShipmentStatusDelivered,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The order is delivered once nothing is left unshipped or in transit
+	start = time.Now()
+	pendingQuery := `
+		SELECT
+			(SELECT COUNT(*) FROM order_items oi
+				LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+				WHERE oi.order_id = ? AND si.id IS NULL) +
+			(SELECT COUNT(*) FROM shipments WHERE order_id = ? AND status <> ?)
+	`
+	var pending int
+	err = tx.QueryRowContext(ctx, pendingQuery, orderID, orderID, ShipmentStatusDelivered).Scan(&pending)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "shipments", pendingQuery, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check order shipments: %w", err)
+	}
+	if pending == 0 && orderStatus != "cancelled" {
+		if err := setOrderStatus(ctx, tx, s.metrics, s.outbox, orderID, userID, orderStatus, "delivered"); err != nil {
+			return nil, err
+		}
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "shipments", "COMMIT", start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("[SHIPMENT] Shipment delivered: shipment_id=%d, order_id=%d, warehouse_id=%s, carrier=%s, order_delivered=%t",
+		shipmentID, orderID, warehouseID, carrier, pending == 0)
+	s.recordFulfilmentDuration(ctx, stageShippedToDelivered, warehouseID, carrier, deliveredAt.Sub(shippedAt))
+
+	shipment, err := s.GetShipment(ctx, shipmentID)
+	return shipment, db.AfterCommit(err)
+}
+
+// GetShipment returns a shipment with its items
+func (s *ShipmentService) GetShipment(ctx context.Context, shipmentID int64) (*models.Shipment, error) {
+	shipments, err := s.listShipments(ctx, "s.id = ?", shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return nil, fmt.Errorf("shipment not found")
+	}
+	return &shipments[0], nil
+}
+
+// ListOrderShipments returns the shipments of an order
+func (s *ShipmentService) ListOrderShipments(ctx context.Context, orderID int64) ([]models.Shipment, error) {
+	start := time.Now()
+	query := "SELECT 1 FROM orders WHERE id = ?"
+	var exists int
+	err := s.db.QueryRowContext(ctx, query, orderID).Scan(&exists)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "orders", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	return s.listShipments(ctx, "s.order_id = ?", orderID)
+}
+
+// listShipments loads shipments matching a condition on s (shipments) with their items
+func (s *ShipmentService) listShipments(ctx context.Context, condition string, arg interface{}) ([]models.Shipment, error) {
+	start := time.Now()
+	query := `
+		SELECT s.id, s.order_id, s.warehouse_id, s.carrier, s.tracking_number, s.status,
+			s.shipped_at, s.delivered_at, s.created_at, s.updated_at,
+			oi.id, oi.product_id, oi.quantity
+		FROM shipments s
+		LEFT JOIN shipment_items si ON si.shipment_id = s.id
+		LEFT JOIN order_items oi ON oi.id = si.order_item_id
+		WHERE ` + condition + `
+		ORDER BY s.id, oi.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, arg)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "shipments", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipments: %w", err)
+	}
+	defer rows.Close()
+
+	shipments := []models.Shipment{}
+	for rows.Next() {
+		var sh models.Shipment
+		var deliveredAt sql.NullTime
+		var itemID, productID sql.NullInt64
+		var quantity sql.NullInt32
+		if err := rows.Scan(&sh.ID, &sh.OrderID, &sh.WarehouseID, &sh.Carrier, &sh.TrackingNumber, &sh.Status,
+			&sh.ShippedAt, &deliveredAt, &sh.CreatedAt, &sh.UpdatedAt, &itemID, &productID, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+
+		if n := len(shipments); n == 0 || shipments[n-1].ID != sh.ID {
+			if deliveredAt.Valid {
+				sh.DeliveredAt = &deliveredAt.Time
+			}
+			sh.Items = []models.ShipmentItem{}
+			shipments = append(shipments, sh)
+		}
+		if itemID.Valid {
+			last := &shipments[len(shipments)-1]
+			last.Items = append(last.Items, models.ShipmentItem{
+				OrderItemID: itemID.Int64,
+				ProductID:   productID.Int64,
+				Quantity:    int(quantity.Int32),
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get shipments: %w", err)
+	}
+	return shipments, nil
+}
+
+// lockOrder locks an order and returns its user, status and creation time
+func (s *ShipmentService) lockOrder(ctx context.Context, tx *db.Tx, orderID int64) (int64, string, time.Time, error) {
+	start := time.Now()
+	query := "SELECT user_id, status, created_at FROM orders WHERE id = ? FOR UPDATE"
+	var userID int64
+	var status string
+	var createdAt time.Time
+	err := tx.QueryRowContext(ctx, query, orderID).Scan(&userID, &status, &createdAt)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "orders", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return 0, "", time.Time{}, fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("failed to get order: %w", err)
+	}
+	return userID, status, createdAt, nil
+}
+
+// unshippedItems returns the lines of an order that are not in a shipment yet
+func (s *ShipmentService) unshippedItems(ctx context.Context, tx *db.Tx, orderID int64) ([]unshippedItem, error) {
+	start := time.Now()
+	query := `
+		SELECT oi.id, oi.product_id, oi.quantity, oi.warehouse_id
+		FROM order_items oi
+		LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+		WHERE oi.order_id = ? AND si.id IS NULL
+		ORDER BY oi.id
+	`
+	rows, err := tx.QueryContext(ctx, query, orderID)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "order_items", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []unshippedItem
+	for rows.Next() {
+		var item unshippedItem
+		var warehouseID sql.NullString
+		if err := rows.Scan(&item.id, &item.productID, &item.quantity, &warehouseID); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		item.warehouseID = warehouseID.String
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	return items, nil
+}
+
+// normalizeCarrier returns the canonical (lower-case, trimmed) carrier name
+func normalizeCarrier(carrier string) string {
+	return strings.ToLower(strings.TrimSpace(carrier))
+}
+
+// recordFulfilmentDuration records one fulfilment stage of a shipment
+func (s *ShipmentService) recordFulfilmentDuration(ctx context.Context, stage, warehouseID, carrier string, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	s.metrics.FulfilmentDuration.Record(ctx, d.Seconds(), metric.WithAttributes(s.metrics.WithServiceName([]attribute.KeyValue{
+		attribute.String("stage", stage),
+		attribute.String("warehouse_id", warehouseID),
+		attribute.String("carrier", carrier),
+	})...))
+}
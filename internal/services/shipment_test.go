@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestShipmentStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	shipments := NewShipmentService(database, s.metrics, s.outbox)
+	userID, _ := s.testUser(t, "shipping@example.com")
+	ids := testProducts(t, database, 2, 10)
+
+	// placeOrder checks out one unit of product
+	placeOrder := func(product int64) int64 {
+		t.Helper()
+		if err := s.carts.AddToCart(ctx, userID, product, 1); err != nil {
+			t.Fatal(err)
+		}
+		order, err := s.orders.CreateOrder(ctx, userID, "credit_card", "USD", "us-east", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order.ID
+	}
+	orderStatus := func(orderID int64) string {
+		t.Helper()
+		order, err := s.orders.GetOrder(ctx, orderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order.Status
+	}
+
+	orderID := placeOrder(ids[0])
+	shipment, err := shipments.CreateShipment(ctx, orderID, models.CreateShipmentRequest{Carrier: "UPS", TrackingNumber: "1Z001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shipment.Status != ShipmentStatusInTransit || orderStatus(orderID) != "shipped" {
+		t.Fatalf("shipment %s, order %s; want in_transit and shipped", shipment.Status, orderStatus(orderID))
+	}
+	if _, err := shipments.CreateShipment(ctx, orderID, models.CreateShipmentRequest{Carrier: "ups", TrackingNumber: "1Z002"}); err == nil || err.Error() != "no items to ship" {
+		t.Errorf("second shipment of a shipped order: %v, want no items to ship", err)
+	}
+
+	// Carriers only report in_transit and delivered, for shipments we know
+	for _, update := range []struct {
+		carrier string
+		update  models.CarrierUpdate
+		want    string
+	}{
+		{"ups", models.CarrierUpdate{TrackingNumber: "1Z001", Status: "returned"}, "invalid shipment status"},
+		{"ups", models.CarrierUpdate{TrackingNumber: "1Z001", Status: ""}, "invalid shipment status"},
+		{"ups", models.CarrierUpdate{TrackingNumber: "1Z999", Status: ShipmentStatusDelivered}, "shipment not found"},
+		{"fedex", models.CarrierUpdate{TrackingNumber: "1Z001", Status: ShipmentStatusDelivered}, "shipment not found"},
+	} {
+		if _, err := shipments.ApplyCarrierUpdate(ctx, update.carrier, update.update); err == nil || err.Error() != update.want {
+			t.Errorf("%s update %+v: %v, want %q", update.carrier, update.update, err, update.want)
+		}
+	}
+
+	deliveredAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	delivered, err := shipments.ApplyCarrierUpdate(ctx, " UPS ", models.CarrierUpdate{TrackingNumber: "1Z001", Status: ShipmentStatusDelivered, OccurredAt: &deliveredAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != ShipmentStatusDelivered || orderStatus(orderID) != "delivered" {
+		t.Errorf("after delivery: shipment %s, order %s; want delivered for both", delivered.Status, orderStatus(orderID))
+	}
+
+	// A delivered shipment stays delivered: repeated and late in_transit
+	// notices change nothing and emit no events
+	later := deliveredAt.Add(time.Hour)
+	for _, status := range []string{ShipmentStatusDelivered, ShipmentStatusInTransit} {
+		got, err := shipments.ApplyCarrierUpdate(ctx, "ups", models.CarrierUpdate{TrackingNumber: "1Z001", Status: status, OccurredAt: &later})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != ShipmentStatusDelivered || got.DeliveredAt == nil || !got.DeliveredAt.Equal(deliveredAt) {
+			t.Errorf("after a %s notice: %s delivered at %v, want delivered at %s", status, got.Status, got.DeliveredAt, deliveredAt)
+		}
+	}
+	var deliveredEvents int
+	if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox WHERE event_type = 'shipment.delivered' AND aggregate_id = ?", orderID).Scan(&deliveredEvents); err != nil {
+		t.Fatal(err)
+	}
+	if deliveredEvents != 1 {
+		t.Errorf("got %d shipment.delivered events, want 1", deliveredEvents)
+	}
+
+	// Delivered and cancelled orders cannot ship
+	if _, err := shipments.CreateShipment(ctx, orderID, models.CreateShipmentRequest{Carrier: "ups", TrackingNumber: "1Z003"}); err == nil || err.Error() != "order cannot be shipped" {
+		t.Errorf("shipping a delivered order: %v, want order cannot be shipped", err)
+	}
+	cancelled := placeOrder(ids[1])
+	if err := s.orders.UpdateOrderStatus(ctx, cancelled, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shipments.CreateShipment(ctx, cancelled, models.CreateShipmentRequest{Carrier: "ups", TrackingNumber: "1Z004"}); err == nil || err.Error() != "order cannot be shipped" {
+		t.Errorf("shipping a cancelled order: %v, want order cannot be shipped", err)
+	}
+	if _, err := shipments.CreateShipment(ctx, cancelled, models.CreateShipmentRequest{Carrier: "ups"}); err == nil || err.Error() != "carrier and tracking_number are required" {
+		t.Errorf("shipment without a tracking number: %v", err)
+	}
+}
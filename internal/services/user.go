@@ -4,10 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"net/mail"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
 	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
 	"github.com/SigNoz/ecommerce-go-app/internal/models"
 	"go.opentelemetry.io/otel/attribute"
@@ -18,54 +23,102 @@ import (
 type UserService struct {
 	db      *db.DB
 	metrics *metrics.AppMetrics
+	outbox  *events.Outbox
 }
 
 // NewUserService creates a new user service
-func NewUserService(db *db.DB, metrics *metrics.AppMetrics) *UserService {
+func NewUserService(db *db.DB, metrics *metrics.AppMetrics, outbox *events.Outbox) *UserService {
 	return &UserService{
 		db:      db,
 		metrics: metrics,
+		outbox:  outbox,
 	}
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(ctx context.Context, id int64, email, name string) (*models.User, error) {
+// Limits of the user columns
+const (
+	maxEmailLength  = 255
+	maxNameLength   = 255
+	maxRegionLength = 50
+)
+
+// CreateUser registers a new user. The ID is assigned by the database.
+// The password is optional; users without one cannot log in.
+func (s *UserService) CreateUser(ctx context.Context, email, name, region, password string) (*models.User, error) {
+	email, name, region, err := normalizeUser(email, name, region)
+	if err != nil {
+		return nil, err
+	}
+	var passwordHash sql.NullString
+	if password != "" {
+		if err := auth.ValidatePassword(password); err != nil {
+			return nil, err
+		}
+		if passwordHash.String, err = auth.HashPassword(password); err != nil {
+			return nil, err
+		}
+		passwordHash.Valid = true
+	}
+
 	start := time.Now()
 
-	query := "INSERT INTO users (id, email, name) VALUES (?, ?, ?)"
-	_, err := s.db.ExecContext(ctx, query, id, email, name)
+	query := "INSERT INTO users (email, name, region, password_hash) VALUES (?, ?, ?, ?)"
+	result, err := s.db.ExecContext(ctx, query, email, name, region, passwordHash)
 	s.metrics.RecordDBQuery(ctx, "INSERT", "users", query, start, err == nil)
 	if err != nil {
-		s.metrics.RecordDBQuery(ctx, "INSERT", "users", query, start, false)
-		// Check for duplicate entry error (MySQL Error 1062)
-		if strings.Contains(err.Error(), "Duplicate entry") {
+		// Unique email (MySQL error 1062)
+		if db.IsDuplicateEntry(err) {
 			return nil, fmt.Errorf("user already exists")
 		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user ID: %w", err)
+	}
+
 	// Update active users count - include user_id to track unique users
 	s.metrics.ActiveUsersCount.Record(ctx, 1, metric.WithAttributes(s.metrics.WithServiceName([]attribute.KeyValue{
 		attribute.String("session_type", "active"),
 		attribute.Int64("user_id", id),
 	})...))
 
-	return &models.User{
-		ID:        id,
-		Email:     email,
-		Name:      name,
-		CreatedAt: time.Now(),
-	}, nil
+	return s.GetUser(ctx, id)
+}
+
+// normalizeUser trims and validates user fields. Emails are lower-cased so
+// the unique index treats differently-cased addresses as one.
+func normalizeUser(email, name, region string) (string, string, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	name = strings.TrimSpace(name)
+	region = strings.TrimSpace(region)
+
+	// Bare addresses only; ParseAddress also accepts "Name <addr>"
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmailLength {
+		return "", "", "", fmt.Errorf("invalid email")
+	}
+	if name == "" {
+		return "", "", "", fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", "", "", fmt.Errorf("name must be at most %d characters", maxNameLength)
+	}
+	if utf8.RuneCountInString(region) > maxRegionLength {
+		return "", "", "", fmt.Errorf("region must be at most %d characters", maxRegionLength)
+	}
+	return email, name, region, nil
 }
 
 // GetUser returns a user by ID
 func (s *UserService) GetUser(ctx context.Context, id int64) (*models.User, error) {
 	start := time.Now()
 
-	query := "SELECT id, email, name, created_at FROM users WHERE id = ?"
+	query := "SELECT id, email, name, region, role, created_at, updated_at FROM users WHERE id = ? AND deleted_at IS NULL"
 	var user models.User
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.CreatedAt,
+		&user.ID, &user.Email, &user.Name, &user.Region, &user.Role, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	s.metrics.RecordDBQuery(ctx, "SELECT", "users", query, start, err == nil)
@@ -83,12 +136,13 @@ func (s *UserService) GetUser(ctx context.Context, id int64) (*models.User, erro
 
 // GetUserByEmail returns a user by email
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
 	start := time.Now()
 
-	query := "SELECT id, email, name, created_at FROM users WHERE email = ?"
+	query := "SELECT id, email, name, region, role, created_at, updated_at FROM users WHERE email = ? AND deleted_at IS NULL"
 	var user models.User
 	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Name, &user.CreatedAt,
+		&user.ID, &user.Email, &user.Name, &user.Region, &user.Role, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	s.metrics.RecordDBQuery(ctx, "SELECT", "users", query, start, err == nil)
@@ -103,3 +157,206 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models
 
 	return &user, nil
 }
+
+// UpdateUser changes a user's email, name or region
+func (s *UserService) UpdateUser(ctx context.Context, id int64, req models.UpdateUserRequest) (*models.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Region != nil {
+		user.Region = *req.Region
+	}
+	user.Email, user.Name, user.Region, err = normalizeUser(user.Email, user.Name, user.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	query := "UPDATE users SET email = ?, name = ?, region = ? WHERE id = ? AND deleted_at IS NULL"
+	_, err = s.db.ExecContext(ctx, query, user.Email, user.Name, user.Region, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "users", query, start, err == nil)
+	if err != nil {
+		if db.IsDuplicateEntry(err) {
+			return nil, fmt.Errorf("email already in use")
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return s.GetUser(ctx, id)
+}
+
+// DeleteUser erases a user's personal data while keeping their orders for
+// accounting. The user row is anonymized rather than removed, sessions,
+// addresses and cart are deleted, and the shipping addresses on past orders are reduced to
+// the country. A user.deleted event lets consumers erase their copies.
+func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
+	return s.db.Retry(ctx, "DeleteUser", func(ctx context.Context) error {
+		return s.deleteUser(ctx, id)
+	})
+}
+
+// deleteUser is one attempt of DeleteUser
+func (s *UserService) deleteUser(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	selectQuery := "SELECT id FROM users WHERE id = ? AND deleted_at IS NULL FOR UPDATE"
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&id)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "users", selectQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// The placeholder email keeps the unique index satisfied and frees the
+	// real address for a new registration
+	start = time.Now()
+	userQuery := "UPDATE users SET email = ?, name = 'Deleted user', region = '', role = 'customer', password_hash = NULL, deleted_at = CURRENT_TIMESTAMP WHERE id = ?"
+	_, err = tx.ExecContext(ctx, userQuery, fmt.Sprintf("deleted-%d@anonymized.invalid", id), id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "users", userQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	start = time.Now()
+	sessionQuery := "DELETE FROM sessions WHERE user_id = ?"
+	_, err = tx.ExecContext(ctx, sessionQuery, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "sessions", sessionQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	start = time.Now()
+	apiKeyQuery := "DELETE FROM api_keys WHERE owner_user_id = ?"
+	_, err = tx.ExecContext(ctx, apiKeyQuery, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "api_keys", apiKeyQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete api keys: %w", err)
+	}
+
+	start = time.Now()
+	resetQuery := "DELETE FROM password_reset_tokens WHERE user_id = ?"
+	_, err = tx.ExecContext(ctx, resetQuery, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "password_reset_tokens", resetQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	start = time.Now()
+	addressQuery := "DELETE FROM addresses WHERE user_id = ?"
+	_, err = tx.ExecContext(ctx, addressQuery, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "addresses", addressQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete addresses: %w", err)
+	}
+
+	start = time.Now()
+	cartQuery := "DELETE FROM carts WHERE user_id = ?"
+	_, err = tx.ExecContext(ctx, cartQuery, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "carts", cartQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	start = time.Now()
+	orderQuery := "UPDATE orders SET shipping_address = JSON_OBJECT('country', shipping_address->>'$.country') WHERE user_id = ? AND shipping_address IS NOT NULL"
+	_, err = tx.ExecContext(ctx, orderQuery, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "orders", orderQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize orders: %w", err)
+	}
+
+	if err := s.outbox.Enqueue(ctx, tx, events.TypeUserDeleted, events.AggregateUser, id, events.UserDeleted{UserID: id}); err != nil {
+		return err
+	}
+
+	start = time.Now()
+	err = tx.Commit()
+	s.metrics.RecordDBQuery(ctx, "COMMIT", "users", "COMMIT", start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("[USER] User deleted and anonymized: user_id=%d", id)
+	return nil
+}
+
+// SetRole changes a user's role. Sessions stay open and pick up the new role
+// on their next request.
+func (s *UserService) SetRole(ctx context.Context, id int64, role string) (*models.User, error) {
+	if !auth.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	start := time.Now()
+	query := "UPDATE users SET role = ? WHERE id = ? AND deleted_at IS NULL"
+	result, err := s.db.ExecContext(ctx, query, role, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "users", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	// RowsAffected is 0 both for a missing user and an unchanged role
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := s.GetUser(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("[USER] Role changed: user_id=%d, role=%s", id, role)
+	return s.GetUser(ctx, id)
+}
+
+// EnsureAdmin makes sure an admin account with the given email exists,
+// creating it with password. Registration is open, so an existing user is
+// only promoted when password is already theirs; otherwise anyone who
+// signed up with the email first would become admin.
+func (s *UserService) EnsureAdmin(ctx context.Context, email, password string) error {
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil && err.Error() != "user not found" {
+		return err
+	}
+	if user == nil {
+		if user, err = s.CreateUser(ctx, email, "Administrator", "", password); err != nil {
+			return err
+		}
+		log.Printf("[USER] Admin account created: user_id=%d", user.ID)
+	}
+	if user.Role == auth.RoleAdmin {
+		return nil
+	}
+
+	start := time.Now()
+	query := "SELECT password_hash FROM users WHERE id = ?"
+	var passwordHash sql.NullString
+	err = s.db.QueryRowContext(ctx, query, user.ID).Scan(&passwordHash)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "users", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	ok := false
+	if passwordHash.Valid && password != "" {
+		if ok, err = auth.VerifyPassword(password, passwordHash.String); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return fmt.Errorf("user %s already exists and its password is not the admin password; promote it with the admin API instead", email)
+	}
+
+	if _, err := s.SetRole(ctx, user.ID, auth.RoleAdmin); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/auth"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestEnsureAdmin(t *testing.T) {
+	ctx := context.Background()
+	database := testutil.NewDB(t)
+	s := newTestServices(t, database, testutil.NewMetrics(t))
+	role := func(email string) string {
+		t.Helper()
+		user, err := s.users.GetUserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.Role
+	}
+
+	// A missing account is created as admin, and a second start leaves it be
+	for i := 0; i < 2; i++ {
+		if err := s.users.EnsureAdmin(ctx, "admin@example.com", "admin password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := role("admin@example.com"); got != auth.RoleAdmin {
+		t.Errorf("new account has role %q, want admin", got)
+	}
+
+	// Someone who registered the email first is not promoted without the
+	// admin password
+	s.testUser(t, "squatter@example.com")
+	for _, password := range []string{"admin password", ""} {
+		if err := s.users.EnsureAdmin(ctx, "squatter@example.com", password); err == nil {
+			t.Errorf("EnsureAdmin with password %q promoted an existing user", password)
+		}
+	}
+	if got := role("squatter@example.com"); got != auth.RoleCustomer {
+		t.Errorf("existing account has role %q, want customer", got)
+	}
+
+	// The owner of the admin password is
+	if err := s.users.EnsureAdmin(ctx, "squatter@example.com", "correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if got := role("squatter@example.com"); got != auth.RoleAdmin {
+		t.Errorf("account with the admin password has role %q, want admin", got)
+	}
+}
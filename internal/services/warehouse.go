@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+)
+
+// defaultWarehousePriority is used when a new warehouse has no priority
+const defaultWarehousePriority = 100
+
+// WarehouseService handles warehouse-related operations
+type WarehouseService struct {
+	db      *db.DB
+	metrics *metrics.AppMetrics
+}
+
+// NewWarehouseService creates a new warehouse service
+func NewWarehouseService(db *db.DB, metrics *metrics.AppMetrics) *WarehouseService {
+	return &WarehouseService{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+// CreateWarehouse creates a new warehouse
+func (s *WarehouseService) CreateWarehouse(ctx context.Context, req models.WarehouseRequest) (*models.Warehouse, error) {
+	wh := models.Warehouse{
+		Code:     strings.TrimSpace(req.Code),
+		Priority: defaultWarehousePriority,
+		Active:   true,
+	}
+	if req.Name != nil {
+		wh.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Region != nil {
+		wh.Region = strings.TrimSpace(*req.Region)
+	}
+	if req.Priority != nil {
+		wh.Priority = *req.Priority
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	if wh.Code == "" || wh.Name == "" || wh.Region == "" {
+		return nil, fmt.Errorf("code, name and region are required")
+	}
+
+	start := time.Now()
+	query := "INSERT INTO warehouses (code, name, region, priority, active) VALUES (?, ?, ?, ?, ?)"
+	result, err := s.db.ExecContext(ctx, query, wh.Code, wh.Name, wh.Region, wh.Priority, wh.Active)
+	s.metrics.RecordDBQuery(ctx, "INSERT", "warehouses", query, start, err == nil)
+	if err != nil {
+		if db.IsDuplicateEntry(err) {
+			return nil, fmt.Errorf("warehouse already exists")
+		}
+		return nil, fmt.Errorf("failed to create warehouse: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse ID: %w", err)
+	}
+	return s.GetWarehouse(ctx, id)
+}
+
+// GetWarehouse returns a warehouse by ID
+func (s *WarehouseService) GetWarehouse(ctx context.Context, id int64) (*models.Warehouse, error) {
+	start := time.Now()
+	query := "SELECT id, code, name, region, priority, active, created_at, updated_at FROM warehouses WHERE id = ?"
+	var wh models.Warehouse
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&wh.ID, &wh.Code, &wh.Name, &wh.Region, &wh.Priority, &wh.Active, &wh.CreatedAt, &wh.UpdatedAt,
+	)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "warehouses", query, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("warehouse not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+	return &wh, nil
+}
+
+// ListWarehouses returns all warehouses in routing order
+func (s *WarehouseService) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	start := time.Now()
+	query := "SELECT id, code, name, region, priority, active, created_at, updated_at FROM warehouses ORDER BY priority, code"
+	rows, err := s.db.QueryContext(ctx, query)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "warehouses", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	warehouses := []models.Warehouse{}
+	for rows.Next() {
+		var wh models.Warehouse
+		if err := rows.Scan(&wh.ID, &wh.Code, &wh.Name, &wh.Region, &wh.Priority, &wh.Active, &wh.CreatedAt, &wh.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, wh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list warehouses: %w", err)
+	}
+	return warehouses, nil
+}
+
+// UpdateWarehouse changes a warehouse's name, region, priority or active flag.
+// The code cannot change because inventory and order items refer to it.
+func (s *WarehouseService) UpdateWarehouse(ctx context.Context, id int64, req models.WarehouseRequest) (*models.Warehouse, error) {
+	wh, err := s.GetWarehouse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Code != "" && req.Code != wh.Code {
+		return nil, fmt.Errorf("warehouse code cannot be changed")
+	}
+	if req.Name != nil {
+		wh.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Region != nil {
+		wh.Region = strings.TrimSpace(*req.Region)
+	}
+	if req.Priority != nil {
+		wh.Priority = *req.Priority
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	if wh.Name == "" || wh.Region == "" {
+		return nil, fmt.Errorf("code, name and region are required")
+	}
+
+	start := time.Now()
+	query := "UPDATE warehouses SET name = ?, region = ?, priority = ?, active = ? WHERE id = ?"
+	_, err = s.db.ExecContext(ctx, query, wh.Name, wh.Region, wh.Priority, wh.Active, id)
+	s.metrics.RecordDBQuery(ctx, "UPDATE", "warehouses", query, start, err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update warehouse: %w", err)
+	}
+	return s.GetWarehouse(ctx, id)
+}
+
+// DeleteWarehouse removes a warehouse that holds no stock.
+// Warehouses with stock must be emptied (or deactivated) instead.
+func (s *WarehouseService) DeleteWarehouse(ctx context.Context, id int64) error {
+	return s.db.Retry(ctx, "DeleteWarehouse", func(ctx context.Context) error {
+		return s.deleteWarehouse(ctx, id)
+	})
+}
+
+// deleteWarehouse is one attempt of DeleteWarehouse
+func (s *WarehouseService) deleteWarehouse(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	selectQuery := "SELECT code FROM warehouses WHERE id = ? FOR UPDATE"
+	var code string
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&code)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "warehouses", selectQuery, start, err == nil || err == sql.ErrNoRows)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("warehouse not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get warehouse: %w", err)
+	}
+
+	// Lock the rows and add them up here, since PostgreSQL does not lock
+	// rows for an aggregate
+	start = time.Now()
+	stockQuery := "SELECT quantity FROM inventory WHERE warehouse_id = ? FOR UPDATE"
+	rows, err := tx.QueryContext(ctx, stockQuery, code)
+	s.metrics.RecordDBQuery(ctx, "SELECT", "inventory", stockQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to get warehouse stock: %w", err)
+	}
+	stock := 0
+	for rows.Next() {
+		var quantity int
+		if err := rows.Scan(&quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to get warehouse stock: %w", err)
+		}
+		stock += quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get warehouse stock: %w", err)
+	}
+	if stock > 0 {
+		return fmt.Errorf("warehouse has stock")
+	}
+
+	start = time.Now()
+	inventoryQuery := "DELETE FROM inventory WHERE warehouse_id = ?"
+	_, err = tx.ExecContext(ctx, inventoryQuery, code)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "inventory", inventoryQuery, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete warehouse inventory: %w", err)
+	}
+
+	start = time.Now()
+	query := "DELETE FROM warehouses WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, id)
+	s.metrics.RecordDBQuery(ctx, "DELETE", "warehouses", query, start, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete warehouse: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
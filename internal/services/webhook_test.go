@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/models"
+	"github.com/SigNoz/ecommerce-go-app/internal/testutil"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":1,"type":"order.created"}`)
+	now := time.Now().Unix()
+	ts := strconv.FormatInt(now, 10)
+	sig := SignWebhookPayload("s3cret", now, body)
+
+	if !VerifyWebhookSignature("s3cret", sig, ts, body, 5*time.Minute) {
+		t.Fatal("valid signature rejected")
+	}
+
+	old := time.Now().Add(-10 * time.Minute).Unix()
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+	}{
+		{"wrong secret", "other", sig, ts, body},
+		{"tampered body", "s3cret", sig, ts, []byte(`{"id":2,"type":"order.created"}`)},
+		{"other timestamp", "s3cret", sig, strconv.FormatInt(now+1, 10), body},
+		{"stale timestamp", "s3cret", SignWebhookPayload("s3cret", old, body), strconv.FormatInt(old, 10), body},
+		{"invalid timestamp", "s3cret", sig, "yesterday", body},
+		{"missing signature", "s3cret", "", ts, body},
+	}
+	for _, tt := range tests {
+		if VerifyWebhookSignature(tt.secret, tt.signature, tt.timestamp, tt.body, 5*time.Minute) {
+			t.Errorf("%s: signature accepted", tt.name)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{7, 640 * time.Second},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempt); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// webhookReceiver is an httptest endpoint that verifies signatures and
+// answers with a configurable status
+type webhookReceiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	received []webhookEnvelope
+	headers  []http.Header
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("read webhook body: %v", err)
+	}
+	if !VerifyWebhookSignature(r.secret, req.Header.Get(WebhookHeaderSignature), req.Header.Get(WebhookHeaderTimestamp), body, time.Minute) {
+		r.t.Errorf("webhook signature %q did not verify", req.Header.Get(WebhookHeaderSignature))
+	}
+	var envelope webhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		r.t.Errorf("webhook body %s: %v", body, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, envelope)
+	r.headers = append(r.headers, req.Header.Clone())
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+// newWebhookTest starts a receiver and subscribes it to order events
+func newWebhookTest(t *testing.T) (*WebhookService, *webhookReceiver, *models.WebhookSubscription) {
+	t.Helper()
+	database := testutil.NewDB(t)
+	webhooks := NewWebhookService(database, testutil.NewMetrics(t), nil)
+
+	receiver := &webhookReceiver{t: t, secret: "test-secret", status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	sub, err := webhooks.CreateSubscription(context.Background(), server.URL, []string{"order.*"}, receiver.secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhooks, receiver, sub
+}
+
+// publishOrderCreated queues deliveries of an order.created event
+func publishOrderCreated(t *testing.T, webhooks *WebhookService, eventID int64) {
+	t.Helper()
+	event := events.Event{
+		ID:            eventID,
+		Type:          events.TypeOrderCreated,
+		AggregateType: events.AggregateOrder,
+		AggregateID:   "42",
+		Payload:       json.RawMessage(`{"order_id":42}`),
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := webhooks.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deliverDue makes every queued delivery due and runs one delivery round
+func deliverDue(t *testing.T, webhooks *WebhookService) int {
+	t.Helper()
+	testExec(t, webhooks.db, "UPDATE webhook_deliveries SET next_attempt_at = ?", time.Now().UTC().Add(-time.Second))
+	n, err := webhooks.ProcessDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// onlyDelivery returns the single delivery of a subscription
+func onlyDelivery(t *testing.T, webhooks *WebhookService, subscriptionID int64) models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := webhooks.ListDeliveries(context.Background(), subscriptionID, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	webhooks, receiver, sub := newWebhookTest(t)
+	publishOrderCreated(t, webhooks, 1)
+	// Publishing the same event again must not queue a second delivery
+	publishOrderCreated(t, webhooks, 1)
+
+	receiver.setStatus(http.StatusInternalServerError)
+	if n := deliverDue(t, webhooks); n != 1 {
+		t.Fatalf("attempted %d deliveries, want 1", n)
+	}
+	d := onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusRetrying || d.Attempts != 1 || d.LastStatusCode == nil || *d.LastStatusCode != 500 || d.DeliveredAt != nil {
+		t.Fatalf("after a 500: %+v, want retrying after 1 attempt with status 500", d)
+	}
+	if wait := time.Until(d.NextAttemptAt); wait < webhookBaseBackoff-5*time.Second || wait > webhookBaseBackoff+5*time.Second {
+		t.Errorf("next attempt in %s, want about %s", wait, webhookBaseBackoff)
+	}
+
+	// Not due yet: nothing is sent
+	if n, err := webhooks.ProcessDue(context.Background()); err != nil || n != 0 {
+		t.Errorf("ProcessDue before the backoff = %d, %v; want 0", n, err)
+	}
+
+	receiver.setStatus(http.StatusNoContent)
+	deliverDue(t, webhooks)
+	d = onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusSucceeded || d.Attempts != 2 || *d.LastStatusCode != 204 || d.LastError != "" || d.DeliveredAt == nil {
+		t.Errorf("after a 204: %+v, want succeeded after 2 attempts", d)
+	}
+
+	if receiver.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", receiver.count())
+	}
+	h := receiver.headers[1]
+	if h.Get(WebhookHeaderEvent) != events.TypeOrderCreated || h.Get(WebhookHeaderEventID) != "1" ||
+		h.Get(WebhookHeaderDelivery) != strconv.FormatInt(d.ID, 10) {
+		t.Errorf("unexpected webhook headers %v", h)
+	}
+	if got := receiver.received[1]; got.ID != 1 || got.Type != events.TypeOrderCreated || string(got.Data) != `{"order_id":42}` {
+		t.Errorf("unexpected webhook body %+v", got)
+	}
+}
+
+func TestWebhookDeliveryDeadLetters(t *testing.T) {
+	webhooks, receiver, sub := newWebhookTest(t)
+	publishOrderCreated(t, webhooks, 1)
+
+	receiver.setStatus(http.StatusServiceUnavailable)
+	for i := 0; i < webhookMaxAttempts; i++ {
+		if n := deliverDue(t, webhooks); n != 1 {
+			t.Fatalf("round %d attempted %d deliveries, want 1", i+1, n)
+		}
+	}
+	d := onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusDead || d.Attempts != webhookMaxAttempts || *d.LastStatusCode != 503 || d.LastError == "" {
+		t.Fatalf("after %d failures: %+v, want dead", webhookMaxAttempts, d)
+	}
+
+	// Dead deliveries are not attempted again
+	if n := deliverDue(t, webhooks); n != 0 {
+		t.Errorf("attempted %d dead deliveries", n)
+	}
+	if receiver.count() != webhookMaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", receiver.count(), webhookMaxAttempts)
+	}
+}
+
+func TestWebhookReplay(t *testing.T) {
+	webhooks, receiver, sub := newWebhookTest(t)
+	ctx := context.Background()
+	publishOrderCreated(t, webhooks, 1)
+
+	deliverDue(t, webhooks)
+	d := onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusSucceeded {
+		t.Fatalf("delivery %+v, want succeeded", d)
+	}
+
+	// Replaying clears the earlier outcome
+	if err := webhooks.ReplayDelivery(ctx, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	d = onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusPending || d.Attempts != 0 || d.LastStatusCode != nil || d.LastError != "" || d.DeliveredAt != nil {
+		t.Errorf("replayed delivery %+v, want pending with no outcome", d)
+	}
+
+	if n, err := webhooks.ProcessDue(ctx); err != nil || n != 1 {
+		t.Fatalf("ProcessDue after replay = %d, %v; want 1", n, err)
+	}
+	d = onlyDelivery(t, webhooks, sub.ID)
+	if d.Status != WebhookStatusSucceeded || d.Attempts != 1 || d.DeliveredAt == nil {
+		t.Errorf("after replay: %+v, want succeeded after 1 attempt", d)
+	}
+	if receiver.count() != 2 {
+		t.Errorf("receiver got %d requests, want 2", receiver.count())
+	}
+
+	if err := webhooks.ReplayDelivery(ctx, d.ID+100); err == nil {
+		t.Error("replaying an unknown delivery succeeded")
+	}
+}
@@ -0,0 +1,56 @@
+// Package testutil holds the fixtures the tests of the internal packages
+// share: a migrated SQLite database, metrics on a no-op meter and quiet logs.
+package testutil
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Main runs the tests of a package, keeping its logs out of the test output
+// unless -v is set. Call it from TestMain.
+func Main(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// NewDB opens a migrated SQLite database, with the seed data, in a
+// temporary directory
+func NewDB(tb testing.TB) *db.DB {
+	tb.Helper()
+	ctx := context.Background()
+	database, err := db.NewDB(ctx, filepath.Join(tb.TempDir(), "test.db"), db.Options{
+		Driver:     "sqlite",
+		MaxRetries: 3,
+	}, noop.NewMeterProvider().Meter("test"), "test")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { database.Close() })
+	if err := database.Migrate(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return database
+}
+
+// NewMetrics creates the application metrics on a no-op meter
+func NewMetrics(tb testing.TB) *metrics.AppMetrics {
+	tb.Helper()
+	m, err := metrics.NewAppMetrics(noop.NewMeterProvider().Meter("test"), "test", "USD")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return m
+}
@@ -4,65 +4,208 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 
 	"github.com/SigNoz/ecommerce-go-app/internal/api"
+	"github.com/SigNoz/ecommerce-go-app/internal/currency"
 	"github.com/SigNoz/ecommerce-go-app/internal/db"
+	"github.com/SigNoz/ecommerce-go-app/internal/events"
+	"github.com/SigNoz/ecommerce-go-app/internal/grpcapi"
+	"github.com/SigNoz/ecommerce-go-app/internal/health"
+	"github.com/SigNoz/ecommerce-go-app/internal/lifecycle"
 	"github.com/SigNoz/ecommerce-go-app/internal/metrics"
+	"github.com/SigNoz/ecommerce-go-app/internal/middleware"
+	"github.com/SigNoz/ecommerce-go-app/internal/notify"
 	"github.com/SigNoz/ecommerce-go-app/internal/services"
 	"github.com/SigNoz/ecommerce-go-app/pkg/config"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	if err := run(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Println("Server exited")
+}
+
+// runCommand runs a subcommand instead of the server. `config print` writes
+// the effective configuration with secrets redacted, then fails if it is
+// invalid.
+func runCommand(args []string) error {
+	if len(args) != 2 || args[0] != "config" || args[1] != "print" {
+		return fmt.Errorf("unknown command %q (usage: %s [config print])", strings.Join(args, " "), os.Args[0])
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// run starts the components in order: telemetry, database, services,
+// background workers and servers. They are stopped in reverse order on
+// SIGINT or SIGTERM, and when startup fails part way, so telemetry is
+// flushed last and sees the shutdown of everything else.
+func run() error {
 	// Load configuration
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	lc := lifecycle.New()
+	defer lc.Stop()
+	ctx := lc.Context()
 
 	// Initialize OpenTelemetry metrics
-	ctx := context.Background()
 	appMetrics, meterProvider, err := metrics.InitMetrics(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize metrics: %v", err)
+		return fmt.Errorf("failed to initialize metrics: %w", err)
 	}
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := meterProvider.Shutdown(shutdownCtx); err != nil {
-			log.Printf("Error shutting down meter provider: %v", err)
-		}
-	}()
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	// Shutdown collects and exports a final round of metrics
+	lc.OnStop("telemetry", 10*time.Second, meterProvider.Shutdown)
 
 	// Initialize database
-	database, err := db.NewDB(cfg.GetDSN(), meterProvider.Meter(cfg.OTELServiceName), cfg.OTELServiceName)
+	database, err := db.NewDB(ctx, cfg.GetDSN(), db.Options{
+		Driver:          cfg.DBDriver,
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+		ConnectTimeout:  cfg.DBConnectTimeout,
+		QueryTimeout:    cfg.DBQueryTimeout,
+		MaxRetries:      cfg.DBMaxRetries,
+		ReplicaDSNs:     cfg.GetReplicaDSNs(),
+	}, meterProvider.Meter(cfg.OTELServiceName), cfg.OTELServiceName)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer database.Close()
+	lc.OnStop("database", 5*time.Second, func(context.Context) error { return database.Close() })
+	appMetrics.SetDBSystem(database.System())
+	appMetrics.SetDBInstance(database.Instance)
 
-	// Initialize schema
-	schemaSQL, err := os.ReadFile("schema.sql")
-	if err != nil {
-		log.Printf("Warning: Could not read schema.sql: %v", err)
-		log.Println("Assuming database schema already exists")
+	// Apply the schema migrations of the backend
+	if err := database.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if err := database.CheckSchema(ctx); err != nil {
+		return err
+	}
+
+	// Initialize exchange rates (prices are stored in the base currency)
+	var rates currency.RateProvider
+	if cfg.ExchangeRatesFile != "" {
+		fileRates, err := currency.NewFileRateProvider(cfg.ExchangeRatesFile, cfg.BaseCurrency)
+		if err != nil {
+			return fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+		rates = fileRates
 	} else {
-		if err := database.InitSchema(ctx, string(schemaSQL)); err != nil {
-			log.Printf("Warning: Could not initialize schema: %v", err)
-			log.Println("Assuming database schema already exists")
+		defaultRates, err := currency.NewDefaultRateProvider(cfg.BaseCurrency)
+		if err != nil {
+			return fmt.Errorf("failed to load exchange rates: %w", err)
 		}
+		rates = defaultRates
+		log.Println("Warning: Using the built-in exchange rates; set EXCHANGE_RATES_FILE for current ones")
+	}
+	log.Printf("Base currency: %s", rates.BaseCurrency())
+
+	// Initialize domain events (written to the outbox, delivered by the dispatcher)
+	outbox := events.NewOutbox(appMetrics)
+	dispatcher := events.NewDispatcher(database, appMetrics, events.NewLogSink())
+	publisher, err := events.NewEventPublisher(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create event publisher: %w", err)
+	}
+	if publisher != nil {
+		lc.OnStop("event publisher", 5*time.Second, func(context.Context) error { return publisher.Close() })
+		dispatcher.AddSink(events.NewPublisherSink(cfg.EventPublisher, publisher, cfg.EventTopicPrefix))
+		log.Printf("Publishing events to %s (topic prefix %q)", cfg.EventPublisher, cfg.EventTopicPrefix)
 	}
 
 	// Initialize services
-	productService := services.NewProductService(database, appMetrics)
-	cartService := services.NewCartService(database, appMetrics)
-	orderService := services.NewOrderService(database, appMetrics)
-	userService := services.NewUserService(database, appMetrics)
+	inventoryService, err := services.NewInventoryService(database, appMetrics, outbox)
+	if err != nil {
+		return fmt.Errorf("failed to create inventory service: %w", err)
+	}
+	fulfilmentRouter := services.NewFulfilmentRouter(appMetrics, inventoryService)
+	productService := services.NewProductService(database, appMetrics, rates.BaseCurrency())
+	cartService := services.NewCartService(database, appMetrics, rates.BaseCurrency(), outbox)
+	addressService := services.NewAddressService(database, appMetrics)
+	orderService := services.NewOrderService(database, appMetrics, rates, outbox, fulfilmentRouter, addressService)
+	userService := services.NewUserService(database, appMetrics, outbox)
+	notifier, err := notify.New(cfg.Notifier, outbox)
+	if err != nil {
+		return fmt.Errorf("failed to create notifier: %w", err)
+	}
+	authService := services.NewAuthService(database, appMetrics, notifier, cfg.SessionTTL, cfg.PasswordResetTTL)
+	auditService := services.NewAuditService(database, appMetrics)
+	apiKeyService := services.NewAPIKeyService(database, appMetrics)
+	warehouseService := services.NewWarehouseService(database, appMetrics)
+	shipmentService := services.NewShipmentService(database, appMetrics, outbox)
+	webhookService := services.NewWebhookService(database, appMetrics, nil)
+	dispatcher.AddSink(webhookService)
+
+	// Make sure there is an admin to hand out staff roles
+	if cfg.BootstrapAdminEmail != "" {
+		if err := userService.EnsureAdmin(ctx, cfg.BootstrapAdminEmail, cfg.BootstrapAdminPassword); err != nil {
+			return fmt.Errorf("failed to bootstrap admin account: %w", err)
+		}
+	}
+
+	// Initialize rate limiting, most specific prefix first
+	var rateLimitGroups []middleware.RateLimitGroup
+	for _, g := range []struct{ name, prefix, limit string }{
+		{"auth", "/api/v1/auth", cfg.RateLimitAuth},
+		{"admin", "/api/v1/admin", cfg.RateLimitAdmin},
+		{"api", "/api/v1", cfg.RateLimitAPI},
+	} {
+		limit, err := middleware.ParseRateLimit(g.limit)
+		if err != nil {
+			return fmt.Errorf("failed to configure %s rate limit: %w", g.name, err)
+		}
+		rateLimitGroups = append(rateLimitGroups, middleware.RateLimitGroup{Name: g.name, PathPrefix: g.prefix, Limit: limit})
+	}
+	rateLimiter, err := middleware.NewRateLimiter(appMetrics, rateLimitGroups, cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("failed to create rate limiter: %w", err)
+	}
+
+	// Initialize health checks; only the database gates readiness
+	healthChecker, err := health.NewChecker(appMetrics, cfg.HealthTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to create health checker: %w", err)
+	}
+	healthChecker.Add(health.Check{Name: "database", Ready: true, Func: database.PingContext})
+	healthChecker.Add(health.Check{Name: "db_pool", Ready: true, Func: database.CheckPool})
+	healthChecker.Add(health.Check{Name: "schema", Ready: true, Func: database.CheckSchema})
+	healthChecker.Add(health.Check{Name: "db_replicas", Func: database.CheckReplicas})
+	healthChecker.Add(health.Check{Name: "otlp_exporter", Func: appMetrics.CheckExport})
 
 	// Initialize app
-	app := api.NewApp(cfg, database, appMetrics, productService, cartService, orderService, userService)
+	app := api.NewApp(cfg, database, appMetrics, productService, cartService, orderService, userService, webhookService, inventoryService, warehouseService, shipmentService, addressService, authService, auditService, apiKeyService, rateLimiter, healthChecker)
 
 	// Setup router
 	router := mux.NewRouter()
@@ -81,29 +224,64 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Start server in goroutine
-	go func() {
-		log.Printf("Server starting on port %s", cfg.AppPort)
-		log.Printf("OTLP endpoint: %s", cfg.OTELExporterOTLPEndpoint)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
-		}
-	}()
-
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Create gRPC server for internal services
+	grpcServer := grpcapi.NewServer(productService, cartService, orderService, userService,
+		authService.Authenticate, apiKeyService.Authenticate, auditService.Record, rates.BaseCurrency())
 
-	log.Println("Shutting down server...")
+	// Start background workers. Each gets its own context, cancelled once
+	// the servers have stopped, so requests still in flight can enqueue
+	// events and deliveries.
+	lc.Go("event dispatcher", 10*time.Second, dispatcher.Run)
+	lc.Go("webhook worker", 10*time.Second, webhookService.Run)
+	lc.Go("inventory worker", 10*time.Second, inventoryService.Run)
+	lc.Go("cart monitor", 10*time.Second, cartService.Run)
+	lc.Go("health checker", 10*time.Second, healthChecker.Run)
+	lc.Go("database monitor", 10*time.Second, database.Run)
 
-	// Graceful shutdown
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Start servers
+	log.Printf("Server starting on port %s", cfg.AppPort)
+	log.Printf("OTLP endpoint: %s", cfg.OTELExporterOTLPEndpoint)
+	lc.Serve("http server", cfg.ShutdownTimeout, server.ListenAndServe, server.Shutdown)
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
+		lc.Serve("grpc server", cfg.ShutdownTimeout, func() error { return grpcServer.Serve(listener) }, func(ctx context.Context) error {
+			// GracefulStop waits for in-flight RPCs; cut them off at the timeout
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				grpcServer.Stop()
+				return ctx.Err()
+			}
+		})
 	}
 
-	log.Println("Server exited")
+	// Fail readiness first so load balancers stop routing to us. Registered
+	// last, so it runs before the servers stop.
+	lc.OnStop("readiness drain", cfg.ShutdownDrainDelay+time.Second, func(ctx context.Context) error {
+		healthChecker.SetDraining()
+		log.Printf("Draining for %s...", cfg.ShutdownDrainDelay)
+		select {
+		case <-time.After(cfg.ShutdownDrainDelay):
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	// Wait for a signal or a server to fail
+	if err := lc.Wait(); err != nil {
+		return err
+	}
+	log.Println("Shutting down server...")
+	return lc.Stop()
 }
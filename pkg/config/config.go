@@ -2,24 +2,81 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
-// Config holds application configuration from environment variables
+// Config holds application configuration from environment variables and
+// the config file
 type Config struct {
 	// Application
-	AppPort string
+	AppPort  string
+	GRPCPort string // gRPC API for internal services; empty disables it
 
 	// Database
+	DBDriver   string // mysql, postgres or sqlite
+	DBPath     string // SQLite database file
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
 
+	// Database connection pool and resilience
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	DBConnectTimeout  time.Duration // How long startup keeps retrying the first connection
+	DBQueryTimeout    time.Duration // Per-statement timeout; 0 disables it
+	DBMaxRetries      int           // Retries of deadlocks, lock wait timeouts and lost connections
+	DBReplicaHosts    []string      // host:port of read replicas, with the primary's credentials and database
+
+	// Currency
+	BaseCurrency      string // Currency product prices are stored in
+	ExchangeRatesFile string // Optional JSON file with exchange rates from the base currency
+
+	// Event publishing
+	EventPublisher   string // none, kafka, nats or memory
+	EventTopicPrefix string // Prepended to the event type to form the topic/subject
+	KafkaBrokers     string // Comma-separated host:port list
+	NATSURL          string
+
+	// Shipping
+	CarrierWebhookSecret string // Shared secret carriers sign tracking updates with; updates are rejected when empty
+
+	// Authentication
+	SessionTTL       time.Duration // Lifetime of a login session
+	PasswordResetTTL time.Duration // Lifetime of a password reset token
+	Notifier         string        // log or outbox; delivers password reset tokens
+
+	// Admin account created at startup when the email is set. An existing user
+	// is only promoted when its password is BootstrapAdminPassword.
+	BootstrapAdminEmail    string
+	BootstrapAdminPassword string
+
+	// Rate limiting, as "rate:burst" in requests per second per client; "0" disables
+	RateLimitAPI   string   // Every /api/v1 route not covered below
+	RateLimitAuth  string   // /api/v1/auth (login and password reset)
+	RateLimitAdmin string   // /api/v1/admin
+	TrustedProxies []string // IPs or CIDRs whose X-Forwarded-For is believed
+
+	// Reject request bodies that do not match the OpenAPI document
+	ValidateRequests bool
+
+	// Health probes
+	HealthTimeout      time.Duration // Per-check timeout of /readyz and /healthz
+	ShutdownDrainDelay time.Duration // How long /readyz reports draining before the server stops
+	ShutdownTimeout    time.Duration // How long the servers get to finish in-flight requests on shutdown
+
+	// GraphQL query limits; 0 disables a limit
+	GraphQLMaxDepth      int // Field nesting depth
+	GraphQLMaxComplexity int // Estimated fields resolved per request
+
 	// OpenTelemetry
 	OTELExporterOTLPEndpoint  string
 	OTELExporterOTLPProtocol  string
@@ -29,10 +86,20 @@ type Config struct {
 	OTELServiceVersion        string
 	OTELDeploymentEnvironment string
 	OTELResourceAttributes    string
+
+	// settings are the variables as loaded, for Print
+	settings []setting
+	// problems are the values LoadConfig could not parse, for Validate
+	problems []error
 }
 
-// LoadConfig loads configuration from .env file and environment variables with defaults
-func LoadConfig() *Config {
+// LoadConfig loads configuration from environment variables, the .env
+// file and the YAML or TOML file named by CONFIG_FILE, in that order of
+// precedence, with defaults. Passwords and keys can be read from the file
+// named by the variable with a _FILE suffix instead. It fails only when
+// the config file cannot be read; values that cannot be parsed are
+// reported by Validate.
+func LoadConfig() (*Config, error) {
 	// Load .env file if it exists (ignore error if file doesn't exist)
 	if err := godotenv.Load(); err != nil {
 		// .env file is optional, so we only log if there's an actual error (not just file not found)
@@ -41,56 +108,134 @@ func LoadConfig() *Config {
 		}
 	}
 
-	return &Config{
+	var file map[string]string
+	path := os.Getenv("CONFIG_FILE")
+	if path != "" {
+		var err error
+		if file, err = readConfigFile(path); err != nil {
+			return nil, err
+		}
+	}
+	l := newLoader(file)
+	l.get("CONFIG_FILE", "")
+
+	dbDriver := l.get("DB_DRIVER", "mysql")
+
+	cfg := &Config{
 		// Application
-		AppPort: getEnv("APP_PORT", "8080"),
+		AppPort:  l.get("APP_PORT", "8080"),
+		GRPCPort: l.get("GRPC_PORT", "9090"),
 
 		// Database
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "3306"),
-		DBUser:     getEnv("DB_USER", "root"),
-		DBPassword: getEnv("DB_PASSWORD", "password"),
-		DBName:     getEnv("DB_NAME", "ecommerce"),
+		DBDriver:   dbDriver,
+		DBPath:     l.get("DB_PATH", "ecommerce.db"),
+		DBHost:     l.get("DB_HOST", "localhost"),
+		DBPort:     l.get("DB_PORT", defaultDBPorts[dbDriver]),
+		DBUser:     l.get("DB_USER", "root"),
+		DBPassword: l.getSecret("DB_PASSWORD", ""),
+		DBName:     l.get("DB_NAME", "ecommerce"),
+
+		DBMaxOpenConns:    l.getInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:    l.getInt("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetime: l.getDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		DBConnMaxIdleTime: l.getDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+		DBConnectTimeout:  l.getDuration("DB_CONNECT_TIMEOUT", time.Minute),
+		DBQueryTimeout:    l.getDuration("DB_QUERY_TIMEOUT", 10*time.Second),
+		DBMaxRetries:      l.getInt("DB_MAX_RETRIES", 3),
+		DBReplicaHosts:    l.getList("DB_REPLICA_HOSTS"),
+
+		// Currency
+		BaseCurrency:      l.get("BASE_CURRENCY", "USD"),
+		ExchangeRatesFile: l.get("EXCHANGE_RATES_FILE", ""),
+
+		// Event publishing
+		EventPublisher:   l.get("EVENT_PUBLISHER", "none"),
+		EventTopicPrefix: l.get("EVENT_TOPIC_PREFIX", "ecommerce."),
+		KafkaBrokers:     l.get("KAFKA_BROKERS", "localhost:9092"),
+		NATSURL:          l.get("NATS_URL", "nats://localhost:4222"),
+
+		// Shipping
+		CarrierWebhookSecret: l.getSecret("CARRIER_WEBHOOK_SECRET", ""),
+
+		// Authentication
+		SessionTTL:       l.getDuration("SESSION_TTL", 30*24*time.Hour),
+		PasswordResetTTL: l.getDuration("PASSWORD_RESET_TTL", time.Hour),
+		Notifier:         l.get("NOTIFIER", "log"),
+
+		BootstrapAdminEmail:    l.get("BOOTSTRAP_ADMIN_EMAIL", ""),
+		BootstrapAdminPassword: l.getSecret("BOOTSTRAP_ADMIN_PASSWORD", ""),
+
+		// Rate limiting
+		RateLimitAPI:   l.get("RATE_LIMIT_API", "20:40"),
+		RateLimitAuth:  l.get("RATE_LIMIT_AUTH", "1:10"),
+		RateLimitAdmin: l.get("RATE_LIMIT_ADMIN", "5:20"),
+		TrustedProxies: l.getList("TRUSTED_PROXIES"),
+
+		ValidateRequests: l.getBool("VALIDATE_REQUESTS", false),
+
+		HealthTimeout:      l.getDuration("HEALTH_TIMEOUT", 2*time.Second),
+		ShutdownDrainDelay: l.getDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		ShutdownTimeout:    l.getDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		GraphQLMaxDepth:      l.getInt("GRAPHQL_MAX_DEPTH", 15),
+		GraphQLMaxComplexity: l.getInt("GRAPHQL_MAX_COMPLEXITY", 1000),
 
 		// OpenTelemetry
-		OTELExporterOTLPEndpoint:  getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
-		OTELExporterOTLPProtocol:  getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf"),
-		OTELExporterOTLPHeaders:   getEnv("OTEL_EXPORTER_OTLP_HEADERS", ""),        // For SigNoz Cloud: signoz-ingestion-key=<key>
-		OTELExporterOTLPInsecure:  getEnvBool("OTEL_EXPORTER_OTLP_INSECURE", true), // Default true for local dev
-		OTELServiceName:           getEnv("OTEL_SERVICE_NAME", "ecommerce-go-app"),
-		OTELServiceVersion:        getEnv("OTEL_SERVICE_VERSION", "1.0.0"),
-		OTELDeploymentEnvironment: getEnv("OTEL_DEPLOYMENT_ENVIRONMENT", "development"),
-		OTELResourceAttributes:    getEnv("OTEL_RESOURCE_ATTRIBUTES", ""),
+		OTELExporterOTLPEndpoint:  l.get("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+		OTELExporterOTLPProtocol:  l.get("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf"),
+		OTELExporterOTLPHeaders:   l.getSecret("OTEL_EXPORTER_OTLP_HEADERS", ""),  // For SigNoz Cloud: signoz-ingestion-key=<key>
+		OTELExporterOTLPInsecure:  l.getBool("OTEL_EXPORTER_OTLP_INSECURE", true), // Default true for local dev
+		OTELServiceName:           l.get("OTEL_SERVICE_NAME", "ecommerce-go-app"),
+		OTELServiceVersion:        l.get("OTEL_SERVICE_VERSION", "1.0.0"),
+		OTELDeploymentEnvironment: l.get("OTEL_DEPLOYMENT_ENVIRONMENT", "development"),
+		OTELResourceAttributes:    l.get("OTEL_RESOURCE_ATTRIBUTES", ""),
 	}
+	l.checkUnused()
+	cfg.settings = l.settings
+	cfg.problems = l.problems
+	return cfg, nil
 }
 
-// GetDSN returns the MySQL DSN string
-func (c *Config) GetDSN() string {
-	return c.DBUser + ":" + c.DBPassword + "@tcp(" + c.DBHost + ":" + c.DBPort + ")/" + c.DBName + "?parseTime=true&charset=utf8mb4"
+// defaultDBPorts are the default DB_PORT of each DB_DRIVER
+var defaultDBPorts = map[string]string{
+	"mysql":    "3306",
+	"postgres": "5432",
 }
 
-// GetAppPortInt returns the application port as an integer
-func (c *Config) GetAppPortInt() int {
-	port, err := strconv.Atoi(c.AppPort)
-	if err != nil {
-		return 8080
+// GetDSN returns the DSN of DB_DRIVER: a MySQL DSN, a postgres:// URL or
+// the SQLite file
+func (c *Config) GetDSN() string {
+	if c.DBDriver == "sqlite" {
+		return c.DBPath
 	}
-	return port
+	return c.dsn(c.DBHost + ":" + c.DBPort)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// GetReplicaDSNs returns the DSNs of the read replicas
+func (c *Config) GetReplicaDSNs() []string {
+	var dsns []string
+	for _, addr := range c.DBReplicaHosts {
+		dsns = append(dsns, c.dsn(addr))
 	}
-	return defaultValue
+	return dsns
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if value == "true" || value == "1" || value == "yes" {
-			return true
+func (c *Config) dsn(addr string) string {
+	if c.DBDriver == "postgres" {
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.DBUser, c.DBPassword),
+			Host:   addr,
+			Path:   "/" + c.DBName,
 		}
-		return false
+		return u.String()
 	}
-	return defaultValue
+	return c.DBUser + ":" + c.DBPassword + "@tcp(" + addr + ")/" + c.DBName + "?parseTime=true&charset=utf8mb4"
+}
+
+// GetAppPortInt returns the application port as an integer, or 0 when it
+// is not one, which Validate reports
+func (c *Config) GetAppPortInt() int {
+	port, _ := strconv.Atoi(c.AppPort)
+	return port
 }
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadTestConfig runs LoadConfig in a temporary directory holding the
+// given .env and config.yaml, with env set in the environment. Empty
+// contents leave the file out.
+func loadTestConfig(t *testing.T, dotEnv, yaml string, env map[string]string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if dotEnv != "" {
+		writeFile(t, filepath.Join(dir, ".env"), dotEnv)
+	}
+	// godotenv sets what .env holds in the process environment; t.Setenv
+	// restores each variable afterwards
+	for _, key := range []string{"APP_PORT", "DB_HOST", "DB_NAME", "DB_USER", "DB_PORT", "DB_PASSWORD", "DB_PASSWORD_FILE",
+		"CARRIER_WEBHOOK_SECRET", "BOOTSTRAP_ADMIN_PASSWORD", "OTEL_EXPORTER_OTLP_HEADERS", "CONFIG_FILE"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	if yaml != "" {
+		path := filepath.Join(dir, "config.yaml")
+		writeFile(t, path, yaml)
+		t.Setenv("CONFIG_FILE", path)
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// find returns the setting of key as loaded
+func (c *Config) find(key string) setting {
+	for _, s := range c.settings {
+		if s.key == key {
+			return s
+		}
+	}
+	return setting{}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	cfg := loadTestConfig(t,
+		"APP_PORT=8081\nDB_HOST=dotenv-host\n",
+		"app_port: 8082\ndb:\n  host: file-host\n  name: shop\n",
+		map[string]string{"APP_PORT": "8083"})
+
+	tests := []struct {
+		key, got, want, source string
+	}{
+		{"APP_PORT", cfg.AppPort, "8083", sourceEnv},
+		{"DB_HOST", cfg.DBHost, "dotenv-host", sourceEnv},
+		{"DB_NAME", cfg.DBName, "shop", sourceFile},
+		{"DB_USER", cfg.DBUser, "root", sourceDefault},
+		{"DB_PORT", cfg.DBPort, "3306", sourceDefault},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, tt.got, tt.want)
+		}
+		if s := cfg.find(tt.key); s.source != tt.source {
+			t.Errorf("%s comes from %q, want %q", tt.key, s.source, tt.source)
+		}
+	}
+
+	// An empty value in the file clears the default
+	cfg = loadTestConfig(t, "", "grpc_port: \"\"\n", nil)
+	if cfg.GRPCPort != "" {
+		t.Errorf("GRPC_PORT = %q, want it cleared by the file", cfg.GRPCPort)
+	}
+}
+
+func TestLoadConfigSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "db_password")
+	writeFile(t, password, "s3cret\n")
+	secret := filepath.Join(dir, "carrier_secret")
+	writeFile(t, secret, "carrier-key\r\n")
+
+	cfg := loadTestConfig(t, "",
+		"carrier_webhook_secret_file: "+secret+"\n",
+		map[string]string{"DB_PASSWORD_FILE": password})
+	if cfg.DBPassword != "s3cret" {
+		t.Errorf("DB_PASSWORD = %q, want the file without its newline", cfg.DBPassword)
+	}
+	if s := cfg.find("DB_PASSWORD"); s.source != "env DB_PASSWORD_FILE" {
+		t.Errorf("DB_PASSWORD comes from %q", s.source)
+	}
+	if cfg.CarrierWebhookSecret != "carrier-key" {
+		t.Errorf("CARRIER_WEBHOOK_SECRET = %q, want the file without its newline", cfg.CarrierWebhookSecret)
+	}
+	if s := cfg.find("CARRIER_WEBHOOK_SECRET"); s.source != "file CARRIER_WEBHOOK_SECRET_FILE" {
+		t.Errorf("CARRIER_WEBHOOK_SECRET comes from %q", s.source)
+	}
+	if len(cfg.problems) != 0 {
+		t.Errorf("problems: %v", cfg.problems)
+	}
+
+	// Setting both is ambiguous, and a missing file is reported
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"DB_PASSWORD": "plain", "DB_PASSWORD_FILE": password}, "DB_PASSWORD: set either DB_PASSWORD or DB_PASSWORD_FILE, not both"},
+		{map[string]string{"BOOTSTRAP_ADMIN_PASSWORD_FILE": filepath.Join(dir, "missing")}, "BOOTSTRAP_ADMIN_PASSWORD: open "},
+	}
+	for _, tt := range tests {
+		cfg := loadTestConfig(t, "", "", tt.env)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate = %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestCheckUnused(t *testing.T) {
+	cfg := loadTestConfig(t, "", strings.Join([]string{
+		"db_hots: typo",
+		"db_password_file: /dev/null",
+		"otel:",
+		"  service_name: shop",
+		"  service_nmae: typo",
+	}, "\n"), nil)
+
+	var unknown []string
+	for _, err := range cfg.problems {
+		unknown = append(unknown, err.Error())
+	}
+	want := []string{
+		"DB_HOTS: unknown setting in config file",
+		"OTEL_SERVICE_NMAE: unknown setting in config file",
+	}
+	if strings.Join(unknown, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems = %q, want %q", unknown, want)
+	}
+	if cfg.OTELServiceName != "shop" {
+		t.Errorf("OTEL_SERVICE_NAME = %q, want the nested setting", cfg.OTELServiceName)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "db_password")
+	writeFile(t, password, "file-password-1\n")
+
+	secrets := map[string]string{
+		"CARRIER_WEBHOOK_SECRET":     "carrier-secret-2",
+		"BOOTSTRAP_ADMIN_PASSWORD":   "admin-password-3",
+		"OTEL_EXPORTER_OTLP_HEADERS": "signoz-ingestion-key=ingestion-key-4",
+	}
+	env := map[string]string{"DB_PASSWORD_FILE": password}
+	for key, value := range secrets {
+		env[key] = value
+	}
+	cfg := loadTestConfig(t, "", "db_user: shop\n", env)
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	printed := out.String()
+	for _, value := range []string{"file-password-1", "carrier-secret-2", "admin-password-3", "ingestion-key-4"} {
+		if strings.Contains(printed, value) {
+			t.Errorf("Print shows the secret %q:\n%s", value, printed)
+		}
+	}
+	for _, key := range []string{"DB_PASSWORD", "CARRIER_WEBHOOK_SECRET", "BOOTSTRAP_ADMIN_PASSWORD", "OTEL_EXPORTER_OTLP_HEADERS"} {
+		if !strings.Contains(printed, key+"="+redacted) {
+			t.Errorf("Print does not redact %s:\n%s", key, printed)
+		}
+	}
+	// Other values, and where every value came from, are shown
+	for _, line := range []string{"DB_USER=shop", "# file", "DB_PASSWORD=" + redacted, "# env DB_PASSWORD_FILE"} {
+		if !strings.Contains(printed, line) {
+			t.Errorf("Print is missing %q:\n%s", line, printed)
+		}
+	}
+}
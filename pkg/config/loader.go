@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a setting, from the highest precedence to the lowest
+const (
+	sourceEnv     = "env"
+	sourceFile    = "file"
+	sourceDefault = "default"
+)
+
+// setting is the effective value of one variable and where it came from
+type setting struct {
+	key    string
+	value  string
+	source string
+	secret bool
+}
+
+// loader resolves each variable from the environment (including .env),
+// then the config file, then its default. It keeps the settings it
+// resolved for Print, and the values it could not parse for Validate.
+type loader struct {
+	file     map[string]string
+	used     map[string]bool
+	settings []setting
+	problems []error
+}
+
+func newLoader(file map[string]string) *loader {
+	return &loader{file: file, used: make(map[string]bool)}
+}
+
+// lookup returns the value of key and its source. Empty environment
+// variables are treated as unset; an empty value in the file is kept, so
+// the file can clear a setting such as GRPC_PORT.
+func (l *loader) lookup(key string) (string, string, bool) {
+	l.used[key] = true
+	if value := os.Getenv(key); value != "" {
+		return value, sourceEnv, true
+	}
+	if value, ok := l.file[key]; ok {
+		return value, sourceFile, true
+	}
+	return "", sourceDefault, false
+}
+
+func (l *loader) record(key, value, source string) {
+	l.settings = append(l.settings, setting{key: key, value: value, source: source})
+}
+
+func (l *loader) problem(key, format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (l *loader) get(key, defaultValue string) string {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+	l.record(key, value, source)
+	return value
+}
+
+// getSecret is get for passwords and keys, which can also be read from the
+// file named by <key>_FILE, as mounted by Docker and Kubernetes secrets
+func (l *loader) getSecret(key, defaultValue string) string {
+	fileKey := key + "_FILE"
+	l.used[fileKey] = true
+
+	value, source := defaultValue, sourceDefault
+	switch {
+	case os.Getenv(key) != "" && os.Getenv(fileKey) != "":
+		l.problem(key, "set either %s or %s, not both", key, fileKey)
+	case os.Getenv(key) != "":
+		value, source = os.Getenv(key), sourceEnv
+	case os.Getenv(fileKey) != "":
+		value, source = l.readSecret(key, os.Getenv(fileKey)), sourceEnv+" "+fileKey
+	default:
+		if v, ok := l.file[key]; ok {
+			value, source = v, sourceFile
+		} else if path, ok := l.file[fileKey]; ok {
+			value, source = l.readSecret(key, path), sourceFile+" "+fileKey
+		}
+	}
+	l.used[key] = true
+	l.settings = append(l.settings, setting{key: key, value: value, source: source, secret: true})
+	return value
+}
+
+func (l *loader) readSecret(key, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.problem(key, "%v", err)
+		return ""
+	}
+	// Secret files usually end with a newline that is not part of the secret
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+func (l *loader) getBool(key string, defaultValue bool) bool {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatBool(defaultValue), source)
+		return defaultValue
+	}
+	l.record(key, value, source)
+	switch strings.ToLower(value) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no", "":
+		return false
+	}
+	l.problem(key, "invalid boolean %q", value)
+	return defaultValue
+}
+
+// getList splits a comma-separated variable, dropping empty entries
+func (l *loader) getList(key string) []string {
+	value, source, _ := l.lookup(key)
+	l.record(key, value, source)
+
+	var values []string
+	for _, value := range strings.Split(value, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (l *loader) getInt(key string, defaultValue int) int {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.Itoa(defaultValue), source)
+		return defaultValue
+	}
+	l.record(key, value, source)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		l.problem(key, "invalid integer %q", value)
+		return defaultValue
+	}
+	return n
+}
+
+func (l *loader) getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, defaultValue.String(), source)
+		return defaultValue
+	}
+	l.record(key, value, source)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.problem(key, "invalid duration %q", value)
+		return defaultValue
+	}
+	return d
+}
+
+// checkUnused reports the settings in the file that no variable read, which
+// are most likely misspelt
+func (l *loader) checkUnused() {
+	var unknown []string
+	for key := range l.file {
+		if !l.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		l.problem(key, "unknown setting in config file")
+	}
+}
+
+// readConfigFile reads a YAML (.yaml, .yml) or TOML (.toml) config file.
+// Its keys are the environment variable names in any case, either flat
+// (db_host) or nested (db: {host: ...}); lists become comma-separated.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: want .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(prefix string, doc map[string]interface{}, values map[string]string) error {
+	for name, value := range doc {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := flatten(key, nested, values); err != nil {
+				return err
+			}
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		strs := make([]string, len(items))
+		for i, item := range items {
+			s, err := scalar(key, item)
+			if err != nil {
+				return err
+			}
+			strs[i] = s
+		}
+		if _, dup := values[key]; dup {
+			return fmt.Errorf("%s is set more than once", key)
+		}
+		values[key] = strings.Join(strs, ",")
+	}
+	return nil
+}
+
+func scalar(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("%s: unsupported value %v", key, value)
+}
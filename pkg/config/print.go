@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// redacted replaces the value of a secret in Print
+const redacted = "<redacted>"
+
+// Print writes the effective configuration as KEY=value lines, each with
+// where its value came from. Passwords and keys are redacted.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range c.settings {
+		value := s.value
+		if s.secret && value != "" {
+			value = redacted
+		} else if strings.ContainsAny(value, " \t\r\n#\"'") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(tw, "%s=%s\t# %s\n", s.key, value, s.source)
+	}
+	return tw.Flush()
+}
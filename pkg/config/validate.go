@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate reports every value that LoadConfig could not parse or that
+// the app cannot start with, one per line, so a typo fails startup
+// instead of falling back to a default
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.problems...)
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(validPort(c.AppPort), "APP_PORT", "%q is not a port", c.AppPort)
+	check(c.GRPCPort == "" || validPort(c.GRPCPort), "GRPC_PORT", "%q is not a port", c.GRPCPort)
+
+	// Database
+	switch c.DBDriver {
+	case "sqlite":
+		check(c.DBPath != "", "DB_PATH", "required with sqlite")
+		check(len(c.DBReplicaHosts) == 0, "DB_REPLICA_HOSTS", "read replicas are not supported with sqlite")
+	case "mysql", "postgres":
+		check(c.DBHost != "", "DB_HOST", "required")
+		check(validPort(c.DBPort), "DB_PORT", "%q is not a port", c.DBPort)
+		check(c.DBUser != "", "DB_USER", "required")
+		check(c.DBPassword != "", "DB_PASSWORD", "required (or DB_PASSWORD_FILE)")
+		check(c.DBName != "", "DB_NAME", "required")
+		for _, host := range c.DBReplicaHosts {
+			_, port, err := net.SplitHostPort(host)
+			check(err == nil && validPort(port), "DB_REPLICA_HOSTS", "%q is not host:port", host)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: unsupported driver %q (want mysql, postgres or sqlite)", c.DBDriver))
+	}
+	check(c.DBMaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	check(c.DBMaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	check(c.DBMaxRetries >= 0, "DB_MAX_RETRIES", "must not be negative")
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", c.DBConnMaxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", c.DBConnMaxIdleTime},
+		{"DB_CONNECT_TIMEOUT", c.DBConnectTimeout},
+		{"DB_QUERY_TIMEOUT", c.DBQueryTimeout},
+	} {
+		check(d.value >= 0, d.key, "must not be negative")
+	}
+
+	check(validCurrency(c.BaseCurrency), "BASE_CURRENCY", "%q is not an ISO 4217 code", c.BaseCurrency)
+
+	switch strings.ToLower(c.EventPublisher) {
+	case "", "none", "memory":
+	case "kafka":
+		check(strings.TrimSpace(c.KafkaBrokers) != "", "KAFKA_BROKERS", "required with kafka")
+	case "nats":
+		check(c.NATSURL != "", "NATS_URL", "required with nats")
+	default:
+		errs = append(errs, fmt.Errorf("EVENT_PUBLISHER: unknown publisher %q (want none, kafka, nats or memory)", c.EventPublisher))
+	}
+
+	check(c.SessionTTL > 0, "SESSION_TTL", "must be positive")
+	check(c.PasswordResetTTL > 0, "PASSWORD_RESET_TTL", "must be positive")
+	check(c.Notifier == "" || c.Notifier == "log" || c.Notifier == "outbox", "NOTIFIER", "unknown notifier %q (want log or outbox)", c.Notifier)
+	if c.BootstrapAdminEmail != "" {
+		_, err := mail.ParseAddress(c.BootstrapAdminEmail)
+		check(err == nil, "BOOTSTRAP_ADMIN_EMAIL", "%q is not an email address", c.BootstrapAdminEmail)
+		check(c.BootstrapAdminPassword != "", "BOOTSTRAP_ADMIN_PASSWORD", "required with BOOTSTRAP_ADMIN_EMAIL")
+	}
+
+	check(c.HealthTimeout > 0, "HEALTH_TIMEOUT", "must be positive")
+	check(c.ShutdownDrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY", "must not be negative")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT", "must be positive")
+	check(c.GraphQLMaxDepth >= 0, "GRAPHQL_MAX_DEPTH", "must not be negative")
+	check(c.GraphQLMaxComplexity >= 0, "GRAPHQL_MAX_COMPLEXITY", "must not be negative")
+
+	check(c.OTELExporterOTLPEndpoint != "", "OTEL_EXPORTER_OTLP_ENDPOINT", "required")
+	check(c.OTELServiceName != "", "OTEL_SERVICE_NAME", "required")
+
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func validCurrency(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range strings.ToUpper(code) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -13,7 +13,7 @@
 # Options:
 #   -u, --url URL          Base URL (default: http://localhost:8080)
 #   -d, --duration SEC     Duration in seconds (default: infinite)
-#   -i, --id USER_ID       User ID to simulate (default: random 1000-9999)
+#   -i, --id USER_ID       User number used in the email (default: random 1000-9999)
 #   -h, --help             Show this help message
 ###############################################################################
 
@@ -23,6 +23,9 @@ set -euo pipefail
 BASE_URL="${BASE_URL:-http://localhost:8080}"
 DURATION="${DURATION:-0}"
 USER_ID="${USER_ID:-$(($RANDOM % 9000 + 1000))}"
+# Staff account used to complete orders and restock (needs a staff role)
+STAFF_EMAIL="${STAFF_EMAIL:-}"
+STAFF_PASSWORD="${STAFF_PASSWORD:-}"
 
 # Internal state
 SCRIPT_START_TIME=$(date +%s)
@@ -30,6 +33,7 @@ REQUEST_STATUS_CODE=0
 REQUEST_RESPONSE_BODY=""
 PRODUCT_IDS=()
 DB_USER_ID=0
+STAFF_TOKEN=""
 
 # Colors
 RED='\033[0;31m'
@@ -56,11 +60,12 @@ random_delay() {
     sleep "$delay"
 }
 
-# Helper: Make HTTP request
+# Helper: Make HTTP request (pass a bearer token as the 4th argument)
 make_request() {
     local method=$1
     local endpoint=$2
     local data="${3:-}"
+    local token="${4:-}"
     
     local url="${BASE_URL}${endpoint}"
     # Use DB_USER_ID if available, otherwise fallback to USER_ID
@@ -77,17 +82,24 @@ make_request() {
         fi
     fi
     
+    local auth_args=()
+    if [[ -n "$token" ]]; then
+        auth_args=(-H "Authorization: Bearer ${token}")
+    fi
+
     local response
     if [[ "$method" == "GET" ]]; then
-        response=$(curl -s --max-time 10 -w "\n%{http_code}" "$url" 2>&1 || echo -e "\n000")
+        response=$(curl -s --max-time 10 -w "\n%{http_code}" "${auth_args[@]}" "$url" 2>&1 || echo -e "\n000")
     elif [[ "$method" == "POST" ]]; then
         response=$(curl -s --max-time 10 -w "\n%{http_code}" -X POST \
             -H "Content-Type: application/json" \
+            "${auth_args[@]}" \
             -d "$data" \
             "$url" 2>&1 || echo -e "\n000")
     elif [[ "$method" == "PUT" ]]; then
         response=$(curl -s --max-time 10 -w "\n%{http_code}" -X PUT \
             -H "Content-Type: application/json" \
+            "${auth_args[@]}" \
             -d "$data" \
             "$url" 2>&1 || echo -e "\n000")
     fi
@@ -120,21 +132,26 @@ fetch_product_ids() {
     fi
 }
 
-# Register/Verify user and get DB ID
+# Register user and get DB ID
 register_user() {
-    echo -e "${YELLOW}Registering/Verifying user user${USER_ID}@example.com...${NC}"
-    make_request "POST" "/api/v1/users" "{\"id\": ${USER_ID}, \"email\": \"user${USER_ID}@example.com\", \"name\": \"User ${USER_ID}\"}"
-    
-    if [[ "$REQUEST_STATUS_CODE" == "201" ]] || [[ "$REQUEST_STATUS_CODE" == "409" ]]; then
-        # Extract ID from response (if 201) or fetch by email (if 409)
-        if [[ "$REQUEST_STATUS_CODE" == "201" ]]; then
-            DB_USER_ID=$(echo "$REQUEST_RESPONSE_BODY" | grep -oE '"id":[0-9]+' | cut -d: -f2)
-        else
-            DB_USER_ID=$(echo "$REQUEST_RESPONSE_BODY" | grep -oE '"id":[0-9]+' | cut -d: -f2 || echo "0")
-        fi
+    local regions=("us-east" "us-west" "eu-central")
+    local region=$(random_element "${regions[@]}")
+    local email="user${USER_ID}@example.com"
+    echo -e "${YELLOW}Registering user ${email}...${NC}"
+    make_request "POST" "/api/v1/users" "{\"email\": \"${email}\", \"name\": \"User ${USER_ID}\", \"region\": \"${region}\"}"
+
+    # The server does not reveal existing accounts, so register a fresh
+    # address when this one is taken by an earlier run
+    if [[ "$REQUEST_STATUS_CODE" == "409" ]]; then
+        email="user${USER_ID}+$(date +%s)@example.com"
+        echo -e "${YELLOW}Email taken, registering ${email}...${NC}"
+        make_request "POST" "/api/v1/users" "{\"email\": \"${email}\", \"name\": \"User ${USER_ID}\", \"region\": \"${region}\"}"
+    fi
 
+    if [[ "$REQUEST_STATUS_CODE" == "201" ]]; then
+        DB_USER_ID=$(echo "$REQUEST_RESPONSE_BODY" | grep -oE '"id":[0-9]+' | head -n1 | cut -d: -f2)
         if [[ $DB_USER_ID -gt 0 ]]; then
-            echo -e "${GREEN}User verified with Database ID: ${DB_USER_ID}${NC}"
+            echo -e "${GREEN}User registered with Database ID: ${DB_USER_ID}${NC}"
             return 0
         fi
     fi
@@ -143,6 +160,21 @@ register_user() {
     return 1
 }
 
+# Log in as staff so orders can be completed and stock received
+login_staff() {
+    if [[ -z "$STAFF_EMAIL" || -z "$STAFF_PASSWORD" ]]; then
+        echo -e "${YELLOW}No staff credentials, orders will stay pending${NC}"
+        return 0
+    fi
+    make_request "POST" "/api/v1/auth/login" "{\"email\": \"${STAFF_EMAIL}\", \"password\": \"${STAFF_PASSWORD}\"}"
+    if [[ "$REQUEST_STATUS_CODE" == "200" ]]; then
+        STAFF_TOKEN=$(echo "$REQUEST_RESPONSE_BODY" | grep -oE '"token":"[0-9a-f]+"' | cut -d'"' -f4)
+        echo -e "${GREEN}Logged in as staff ${STAFF_EMAIL}${NC}"
+    else
+        echo -e "${RED}Staff login failed with status ${REQUEST_STATUS_CODE}, orders will stay pending${NC}"
+    fi
+}
+
 # Check duration
 check_duration() {
     if [[ $DURATION -gt 0 ]]; then
@@ -221,7 +253,7 @@ create_order() {
                 # 70% - Auto-complete the order (simulate successful payment)
                 sleep 0.5
                 local status_data='{"status": "completed"}'
-                if make_request "PUT" "/api/v1/orders/${order_id}/status" "$status_data"; then
+                if make_request "PUT" "/api/v1/orders/${order_id}/status" "$status_data" "$STAFF_TOKEN"; then
                     if [[ $REQUEST_STATUS_CODE -eq 200 ]]; then
                         echo -e "${GREEN}[SUCCESS] User ${USER_ID}: Order ${order_id} auto-completed (${payment_method})${NC}"
                     fi
@@ -231,6 +263,13 @@ create_order() {
                 echo -e "${YELLOW}[INFO] User ${USER_ID}: Order ${order_id} left as PENDING (${payment_method})${NC}"
             fi
         fi
+    elif [[ $REQUEST_STATUS_CODE -eq 409 ]]; then
+        # Out of stock: receive a delivery so later checkouts can succeed
+        local out_of_stock=$(echo "$REQUEST_RESPONSE_BODY" | grep -oE 'product [0-9]+' | cut -d' ' -f2)
+        if [[ -n "$out_of_stock" ]]; then
+            echo -e "${YELLOW}[INFO] User ${USER_ID}: Product ${out_of_stock} out of stock, restocking${NC}"
+            make_request "POST" "/api/v1/admin/inventory/adjustments" "{\"product_id\": ${out_of_stock}, \"warehouse_id\": \"WH-001\", \"type\": \"receive\", \"quantity\": 100, \"reason\": \"traffic generator restock\"}" "$STAFF_TOKEN"
+        fi
     fi
     random_delay
 }
@@ -245,6 +284,8 @@ if ! register_user; then
     exit 1
 fi
 
+login_staff
+
 # Fetch product IDs
 fetch_product_ids
 
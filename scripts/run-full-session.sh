@@ -123,7 +123,7 @@ check_app_healthy() {
     log_info "Waiting for E-commerce App to be healthy..."
     
     while [[ $attempt -le $max_attempts ]]; do
-        if curl -s -f --max-time 5 --connect-timeout 3 "http://localhost:8080/health" > /dev/null 2>&1; then
+        if curl -s -f --max-time 5 --connect-timeout 3 "http://localhost:8080/readyz" > /dev/null 2>&1; then
             log_success "E-commerce App is healthy"
             return 0
         fi